@@ -8,11 +8,20 @@ import (
 
 	_  "github.com/satrunjis/user-service/docs"
 	"github.com/satrunjis/user-service/internal/config"
+	"github.com/satrunjis/user-service/internal/domain"
+	"github.com/satrunjis/user-service/internal/events"
 	"github.com/satrunjis/user-service/internal/external/maptile"
+	"github.com/satrunjis/user-service/internal/external/webhook"
 	"github.com/satrunjis/user-service/internal/logger"
 	"github.com/satrunjis/user-service/internal/mapcache"
+	"github.com/satrunjis/user-service/internal/middleware"
+	"github.com/satrunjis/user-service/internal/notifier"
 	"github.com/satrunjis/user-service/internal/repository/elastic"
+	"github.com/satrunjis/user-service/internal/repository/redisstore"
+	"github.com/satrunjis/user-service/internal/secrets"
 	"github.com/satrunjis/user-service/internal/server"
+	"github.com/satrunjis/user-service/internal/service"
+	"github.com/satrunjis/user-service/internal/social"
 )
 
 // @title User Service API
@@ -22,6 +31,15 @@ import (
 // @host localhost:8080
 // @BasePath /
 // @schemes http
+
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Токен сессии в формате "Bearer <token>"
 func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
@@ -45,7 +63,277 @@ func main() {
 		return
 	}
 
-	serv := server.NewServer(&cfg.HTTPServerConfig, logger, esClient, cacheService, mapService)
+	masterKeys, err := secrets.LoadMasterKeys(string(cfg.Encryption.MasterKeys), cfg.Encryption.MasterKeysFile)
+	if err != nil {
+		logger.Error("Failed to load encryption master keys", "err", err)
+		return
+	}
+	if len(masterKeys) > 0 {
+		envelope, err := secrets.NewEnvelope(masterKeys)
+		if err != nil {
+			logger.Error("Failed to initialize field encryption", "err", err)
+			return
+		}
+		encryptedFields, err := elastic.ParseEncryptedFields(cfg.Encryption.Fields)
+		if err != nil {
+			logger.Error("Invalid encrypted fields", "err", err)
+			return
+		}
+		esClient.EnableFieldEncryption(envelope, encryptedFields)
+	} else {
+		logger.Warn("Encryption master keys are not set, profile fields are stored in plain text")
+	}
+
+	notifierService, err := notifier.New(&cfg.NotifierConfig, logger)
+	if err != nil {
+		logger.Error("Failed to initialize notifier", "err", err)
+		return
+	}
+
+	resetTokens := redisstore.NewResetTokens(cacheService.Client(), logger)
+	sessions := redisstore.NewSessions(cacheService.Client(), logger)
+	loginAttempts := redisstore.NewLoginAttempts(cacheService.Client(), logger)
+	mfaChallenges := redisstore.NewMFAChallenges(cacheService.Client(), logger)
+
+	apiKeys, err := elastic.NewAPIKeys(ctx, esClient)
+	if err != nil {
+		logger.Error("Failed to initialize API keys repository", "err", err)
+		return
+	}
+
+	if err := esClient.MigrateSocialNet(ctx); err != nil {
+		logger.Error("Failed to migrate social networks", "err", err)
+		return
+	}
+
+	networks := social.DefaultNetworks
+	if len(cfg.Social.Networks) > 0 {
+		networks = make([]domain.SocialNetwork, len(cfg.Social.Networks))
+		for i, n := range cfg.Social.Networks {
+			networks[i] = domain.SocialNetwork{ID: n.ID, Name: n.Name, HandlePattern: n.HandlePattern, ProfileURL: n.ProfileURL}
+		}
+	}
+	socialRegistry, err := social.NewRegistry(networks)
+	if err != nil {
+		logger.Error("Invalid social network registry", "err", err)
+		return
+	}
+
+	loginReservations, err := elastic.NewLoginReservations(ctx, esClient)
+	if err != nil {
+		logger.Error("Failed to initialize login reservations", "err", err)
+		return
+	}
+
+	loginBlocklist, err := elastic.NewLoginBlocklist(ctx, esClient, cfg.LoginBlocklist.Seed)
+	if err != nil {
+		logger.Error("Failed to initialize login blocklist", "err", err)
+		return
+	}
+
+	attributeSchemas, err := elastic.NewAttributeSchemas(ctx, esClient)
+	if err != nil {
+		logger.Error("Failed to initialize attribute schema storage", "err", err)
+		return
+	}
+
+	eventBus := events.NewBus(logger)
+
+	outbox, err := elastic.NewOutbox(ctx, esClient)
+	if err != nil {
+		logger.Error("Failed to initialize event outbox", "err", err)
+		return
+	}
+	leases := redisstore.NewLeases(cacheService.Client())
+	relay := events.NewRelay(outbox, leases, eventBus, events.RelayOptions{
+		PollInterval: cfg.Outbox.PollInterval,
+		BatchSize:    cfg.Outbox.BatchSize,
+		Retention:    cfg.Outbox.Retention,
+		LeaseTTL:     cfg.Outbox.LeaseTTL,
+		MaxAttempts:  cfg.Outbox.MaxAttempts,
+		BackoffMax:   cfg.Outbox.BackoffMax,
+	}, logger)
+
+	auditLog, err := elastic.NewAuditLog(ctx, esClient)
+	if err != nil {
+		logger.Error("Failed to initialize audit log", "err", err)
+		return
+	}
+
+	webhooks, err := elastic.NewWebhooks(ctx, esClient)
+	if err != nil {
+		logger.Error("Failed to initialize webhooks repository", "err", err)
+		return
+	}
+
+	webhookDeliveries, err := elastic.NewWebhookDeliveries(ctx, esClient)
+	if err != nil {
+		logger.Error("Failed to initialize webhook delivery history", "err", err)
+		return
+	}
+
+	var totpCipher service.SecretCipher
+	if cfg.AuthConfig.TOTP.EncryptionKey != "" {
+		totpCipher, err = secrets.NewCipherFromBase64(string(cfg.AuthConfig.TOTP.EncryptionKey))
+		if err != nil {
+			logger.Error("Failed to initialize TOTP cipher", "err", err)
+			return
+		}
+	} else {
+		logger.Warn("TOTP encryption key is not set, two-factor authentication is disabled")
+	}
+
+	userService := service.NewUserService(
+		esClient,
+		loginReservations,
+		loginBlocklist,
+		socialRegistry,
+		attributeSchemas,
+		auditLog,
+		relay,
+		cacheService,
+		mapService,
+		resetTokens,
+		sessions,
+		notifierService,
+		service.PasswordResetOptions{
+			TokenTTL: cfg.PasswordResetConfig.TokenTTL,
+			URL:      cfg.PasswordResetConfig.URL,
+		},
+		logger,
+	)
+
+	lockout := cfg.AuthConfig.Lockout
+	authService := service.NewAuthService(
+		esClient,
+		sessions,
+		loginAttempts,
+		mfaChallenges,
+		apiKeys,
+		totpCipher,
+		service.LockoutPolicy{
+			Window:       lockout.Window,
+			BackoffAfter: lockout.BackoffAfter,
+			BackoffBase:  lockout.BackoffBase,
+			BackoffMax:   lockout.BackoffMax,
+			LockAfter:    lockout.LockAfter,
+			LockDuration: lockout.LockDuration,
+			IPLockAfter:  lockout.IPLockAfter,
+		},
+		service.TwoFactorOptions{
+			Issuer:       cfg.AuthConfig.TOTP.Issuer,
+			ChallengeTTL: cfg.AuthConfig.TOTP.ChallengeTTL,
+		},
+		service.AccessOptions{
+			BootstrapKey:  string(cfg.AuthConfig.BootstrapAPIKey),
+			SessionScopes: cfg.AuthConfig.SessionScopes,
+		},
+		cfg.AuthConfig.SessionTTL,
+		logger,
+	)
+
+	webhookService := service.NewWebhookService(
+		webhooks,
+		webhookDeliveries,
+		redisstore.NewWebhookQueue(cacheService.Client()),
+		webhook.NewHTTPSender(cfg.Webhooks.Timeout, cfg.Webhooks.AllowPrivateNetworks, logger),
+		service.WebhookOptions{
+			MaxAttempts:          cfg.Webhooks.MaxAttempts,
+			BackoffBase:          cfg.Webhooks.BackoffBase,
+			BackoffMax:           cfg.Webhooks.BackoffMax,
+			Timeout:              cfg.Webhooks.Timeout,
+			PollInterval:         cfg.Webhooks.PollInterval,
+			Concurrency:          cfg.Webhooks.Concurrency,
+			AllowPrivateNetworks: cfg.Webhooks.AllowPrivateNetworks,
+		},
+		logger,
+	)
+
+	eventStream := redisstore.NewEventStream(cacheService.Client(), cfg.EventStream.MaxLen)
+	streamService := service.NewEventStreamService(
+		eventStream,
+		service.EventStreamOptions{
+			Buffer:    cfg.EventStream.Buffer,
+			Heartbeat: cfg.EventStream.Heartbeat,
+		},
+		logger,
+	)
+
+	changeStream := redisstore.NewChangeStream(cacheService.Client(), cfg.CDC.Stream, cfg.CDC.MaxLen)
+	cdcService := service.NewCDCService(
+		changeStream,
+		esClient,
+		socialRegistry,
+		logger,
+	)
+
+	erasureJobs, err := elastic.NewErasureJobs(ctx, esClient)
+	if err != nil {
+		logger.Error("Failed to initialize erasure job storage", "err", err)
+		return
+	}
+
+	var receiptSigner service.ReceiptSigner
+	if cfg.Erasure.SigningKey != "" {
+		receiptSigner, err = secrets.NewSignerFromBase64(string(cfg.Erasure.SigningKey))
+		if err != nil {
+			logger.Error("Failed to initialize erasure receipt signer", "err", err)
+			return
+		}
+	} else {
+		logger.Warn("Erasure signing key is not set, user erasure is disabled")
+	}
+
+	privacyService := service.NewPrivacyService(
+		esClient,
+		auditLog,
+		sessions,
+		cacheService,
+		webhookDeliveries,
+		outbox,
+		eventStream,
+		changeStream,
+		socialRegistry,
+		erasureJobs,
+		userService,
+		leases,
+		receiptSigner,
+		service.ErasureOptions{
+			PollInterval: cfg.Erasure.PollInterval,
+			LeaseTTL:     cfg.Erasure.LeaseTTL,
+		},
+		logger,
+	)
+
+	limiter, err := middleware.NewRateLimiter(redisstore.NewRateLimits(cacheService.Client()), &cfg.RateLimitConfig, logger)
+	if err != nil {
+		logger.Error("Failed to initialize rate limiter", "err", err)
+		return
+	}
+
+	// Журнал изменений не должен терять записи, поэтому публикация ждёт свободного места в очереди
+	eventBus.Subscribe("audit", userService.RecordAudit, events.Options{Buffer: 1024, Blocking: true})
+	eventBus.Subscribe("webhooks", webhookService.HandleEvent, events.Options{Buffer: 1024, Blocking: true})
+	eventBus.Subscribe("stream", streamService.Record, events.Options{Buffer: 1024, Blocking: true})
+	if cfg.CDC.Enabled {
+		eventBus.Subscribe("cdc", cdcService.Record, events.Options{Buffer: 1024, Blocking: true})
+	}
+
+	go relay.Run(ctx)
+	go webhookService.Run(ctx)
+	go streamService.Run(ctx)
+	go privacyService.RunErasures(ctx)
+
+	go userService.RunPurge(ctx, service.DeletionOptions{
+		Retention:     cfg.Deletion.Retention,
+		PurgeInterval: cfg.Deletion.PurgeInterval,
+	})
+
+	serv, err := server.NewServer(&cfg.HTTPServerConfig, logger, userService, authService, webhookService, streamService, privacyService, limiter)
+	if err != nil {
+		logger.Error("Failed to initialize HTTP server", "err", err)
+		return
+	}
 
 	go func() {
 		if err := serv.Run(); err != nil {
@@ -66,6 +354,10 @@ func main() {
 		logger.Error("Server forced to shutdown", "err", err)
 	}
 
+	if err := eventBus.Close(shutdownCtx); err != nil {
+		logger.Error("Failed to deliver pending events", "err", err)
+	}
+
 	if err := esClient.Close(); err != nil {
 		logger.Error("Failed to close Elasticsearch client", "err", err)
 	}
@@ -0,0 +1,70 @@
+// Команда reencrypt приводит документы пользователей к текущим настройкам шифрования полей:
+// шифрует значения, записанные до включения шифрования, перешифровывает данные текущим
+// мастер-ключом после его замены и расшифровывает поля, убранные из ENCRYPTION_FIELDS.
+// Прежний мастер-ключ можно убрать из ENCRYPTION_MASTER_KEYS только после успешного запуска.
+// Настройки те же, что у сервиса.
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/satrunjis/user-service/internal/config"
+	"github.com/satrunjis/user-service/internal/logger"
+	"github.com/satrunjis/user-service/internal/repository/elastic"
+	"github.com/satrunjis/user-service/internal/secrets"
+)
+
+func main() {
+	if err := run(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	cfg := config.Load()
+	logger := logger.New(cfg.Env, nil)
+
+	masterKeys, err := secrets.LoadMasterKeys(string(cfg.Encryption.MasterKeys), cfg.Encryption.MasterKeysFile)
+	if err != nil {
+		logger.Error("Failed to load encryption master keys", "err", err)
+		return err
+	}
+	if len(masterKeys) == 0 {
+		err := errors.New("encryption master keys are not set")
+		logger.Error("Nothing to re-encrypt with", "err", err)
+		return err
+	}
+	envelope, err := secrets.NewEnvelope(masterKeys)
+	if err != nil {
+		logger.Error("Failed to initialize field encryption", "err", err)
+		return err
+	}
+	encryptedFields, err := elastic.ParseEncryptedFields(cfg.Encryption.Fields)
+	if err != nil {
+		logger.Error("Invalid encrypted fields", "err", err)
+		return err
+	}
+
+	esClient, err := elastic.Init(ctx, cfg.ElasticConfig.URL, logger)
+	if err != nil {
+		logger.Error("Failed to initialize Elasticsearch client", "err", err)
+		return err
+	}
+	defer esClient.Close()
+	esClient.EnableFieldEncryption(envelope, encryptedFields)
+
+	rewritten, err := esClient.ReencryptUsers(ctx, cfg.Encryption.ReencryptBatch)
+	if err != nil {
+		logger.Error("Re-encryption failed", "err", err, "rewritten", rewritten)
+		return err
+	}
+	logger.Info("Re-encryption completed", "key_id", envelope.KeyID(), "rewritten", rewritten)
+	return nil
+}
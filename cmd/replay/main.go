@@ -0,0 +1,77 @@
+// Команда replay публикует текущее состояние всех пользователей в поток изменений (CDC_STREAM)
+// событиями user.snapshot. Нужна новому потребителю, чтобы начать с полного набора данных,
+// и после потери записей потока. Настройки те же, что у сервиса.
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/satrunjis/user-service/internal/config"
+	"github.com/satrunjis/user-service/internal/domain"
+	"github.com/satrunjis/user-service/internal/logger"
+	"github.com/satrunjis/user-service/internal/mapcache"
+	"github.com/satrunjis/user-service/internal/repository/elastic"
+	"github.com/satrunjis/user-service/internal/repository/redisstore"
+	"github.com/satrunjis/user-service/internal/service"
+	"github.com/satrunjis/user-service/internal/social"
+)
+
+func main() {
+	if err := run(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	cfg := config.Load()
+	logger := logger.New(cfg.Env, nil)
+
+	cacheService, err := mapcache.Init(ctx, &cfg.CacheConfig.URL, logger, cfg.CacheConfig.TTL)
+	if err != nil {
+		logger.Error("Failed to initialize cache service", "err", err)
+		return err
+	}
+	defer cacheService.Close()
+
+	esClient, err := elastic.Init(ctx, cfg.ElasticConfig.URL, logger)
+	if err != nil {
+		logger.Error("Failed to initialize Elasticsearch client", "err", err)
+		return err
+	}
+	defer esClient.Close()
+
+	networks := social.DefaultNetworks
+	if len(cfg.Social.Networks) > 0 {
+		networks = make([]domain.SocialNetwork, len(cfg.Social.Networks))
+		for i, n := range cfg.Social.Networks {
+			networks[i] = domain.SocialNetwork{ID: n.ID, Name: n.Name, HandlePattern: n.HandlePattern, ProfileURL: n.ProfileURL}
+		}
+	}
+	socialRegistry, err := social.NewRegistry(networks)
+	if err != nil {
+		logger.Error("Invalid social network registry", "err", err)
+		return err
+	}
+
+	cdcService := service.NewCDCService(
+		redisstore.NewChangeStream(cacheService.Client(), cfg.CDC.Stream, cfg.CDC.MaxLen),
+		esClient,
+		socialRegistry,
+		logger,
+	)
+
+	ctx = domain.WithPrincipal(ctx, &domain.Principal{Type: domain.PrincipalSystem, ID: "replay"})
+	published, err := cdcService.Replay(ctx, cfg.CDC.ReplayBatch)
+	if err != nil {
+		logger.Error("Replay failed", "err", err, "published", published)
+		return err
+	}
+	logger.Info("Replay completed", "stream", cfg.CDC.Stream, "published", published)
+	return nil
+}
@@ -17,6 +17,8 @@ type HTTPServerConfig struct {
 	ReadTimeout  time.Duration `yaml:"read_timeout" env:"HTTP_READ_TIMEOUT" env-default:"30s"`
 	WriteTimeout time.Duration `yaml:"write_timeout" env:"HTTP_WRITE_TIMEOUT" env-default:"30s"`
 	IdleTimeout  time.Duration `yaml:"idle_timeout" env:"HTTP_IDLE_TIMEOUT" env-default:"60s"`
+	// TrustedProxies адреса и подсети прокси, которым разрешено передавать адрес клиента в X-Forwarded-For
+	TrustedProxies []string `yaml:"trusted_proxies" env:"HTTP_TRUSTED_PROXIES" env-default:""`
 }
 type CacheConfig struct {
 	URL string        `yaml:"url" env:"REDIS_URL" env-default:"localhost:6379"`
@@ -28,12 +30,149 @@ type OpenStreetMapConfig struct {
 	UserAgent string        `yaml:"user_agent" env:"OSM_USER_AGENT" env-default:"UserService/1.0 (satrunjis@mail.ru)"`
 	ZoomLevel int           `yaml:"zoom_level" env:"OSM_ZOOM_LEVEL" env-default:"15"`
 }
+
+// Secret значение из конфигурации, которое не попадает в лог при выводе настроек
+type Secret string
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return "[redacted]"
+}
+
+type PasswordResetConfig struct {
+	TokenTTL time.Duration `yaml:"token_ttl" env:"PASSWORD_RESET_TTL" env-default:"30m"`
+	URL      string        `yaml:"url" env:"PASSWORD_RESET_URL" env-default:""`
+}
+type SMTPConfig struct {
+	Host     string        `yaml:"host" env:"SMTP_HOST" env-default:"localhost"`
+	Port     int           `yaml:"port" env:"SMTP_PORT" env-default:"1025"`
+	Username string        `yaml:"username" env:"SMTP_USERNAME" env-default:""`
+	Password Secret        `yaml:"password" env:"SMTP_PASSWORD" env-default:""`
+	From     string        `yaml:"from" env:"SMTP_FROM" env-default:"no-reply@user-service.local"`
+	Timeout  time.Duration `yaml:"timeout" env:"SMTP_TIMEOUT" env-default:"10s"`
+}
+type NotifierConfig struct {
+	Type     string     `yaml:"type" env:"NOTIFIER_TYPE" env-default:"log"`
+	FilePath string     `yaml:"file_path" env:"NOTIFIER_FILE_PATH" env-default:""`
+	SMTP     SMTPConfig `yaml:"smtp"`
+}
+type LockoutConfig struct {
+	Window       time.Duration `yaml:"window" env:"LOCKOUT_WINDOW" env-default:"15m"`
+	BackoffAfter int64         `yaml:"backoff_after" env:"LOCKOUT_BACKOFF_AFTER" env-default:"3"`
+	BackoffBase  time.Duration `yaml:"backoff_base" env:"LOCKOUT_BACKOFF_BASE" env-default:"1s"`
+	BackoffMax   time.Duration `yaml:"backoff_max" env:"LOCKOUT_BACKOFF_MAX" env-default:"5m"`
+	LockAfter    int64         `yaml:"lock_after" env:"LOCKOUT_LOCK_AFTER" env-default:"10"`
+	LockDuration time.Duration `yaml:"lock_duration" env:"LOCKOUT_LOCK_DURATION" env-default:"30m"`
+	IPLockAfter  int64         `yaml:"ip_lock_after" env:"LOCKOUT_IP_LOCK_AFTER" env-default:"100"`
+}
+type TOTPConfig struct {
+	Issuer        string        `yaml:"issuer" env:"TOTP_ISSUER" env-default:"UserService"`
+	EncryptionKey Secret        `yaml:"encryption_key" env:"TOTP_ENCRYPTION_KEY" env-default:""`
+	ChallengeTTL  time.Duration `yaml:"challenge_ttl" env:"TOTP_CHALLENGE_TTL" env-default:"5m"`
+}
+type AuthConfig struct {
+	SessionTTL      time.Duration `yaml:"session_ttl" env:"SESSION_TTL" env-default:"24h"`
+	SessionScopes   []string      `yaml:"session_scopes" env:"AUTH_SESSION_SCOPES" env-default:""`
+	BootstrapAPIKey Secret        `yaml:"bootstrap_api_key" env:"AUTH_BOOTSTRAP_API_KEY" env-default:""`
+	Lockout         LockoutConfig `yaml:"lockout"`
+	TOTP            TOTPConfig    `yaml:"totp"`
+}
+type LoginBlocklistConfig struct {
+	// Seed зарезервированные логины, которыми заполняется пустой список при первом запуске
+	Seed []string `yaml:"seed" env:"LOGIN_BLOCKLIST_SEED" env-default:"admin,administrator,root,support,system,moderator,security,help,info,userservice"`
+}
+type SocialNetworkConfig struct {
+	ID            string `yaml:"id"`
+	Name          string `yaml:"name"`
+	HandlePattern string `yaml:"handle_pattern"`
+	ProfileURL    string `yaml:"profile_url"`
+}
+type SocialConfig struct {
+	// Networks реестр соц. сетей, задаётся только в файле конфигурации.
+	// Если список пуст, используется встроенный набор.
+	Networks []SocialNetworkConfig `yaml:"networks"`
+}
+type DeletionConfig struct {
+	// Retention срок, в течение которого удалённого пользователя можно восстановить
+	Retention     time.Duration `yaml:"retention" env:"USER_DELETE_RETENTION" env-default:"720h"`
+	PurgeInterval time.Duration `yaml:"purge_interval" env:"USER_PURGE_INTERVAL" env-default:"1h"`
+}
+type OutboxConfig struct {
+	PollInterval time.Duration `yaml:"poll_interval" env:"OUTBOX_POLL_INTERVAL" env-default:"500ms"`
+	BatchSize    int           `yaml:"batch_size" env:"OUTBOX_BATCH_SIZE" env-default:"100"`
+	// Retention сколько хранятся опубликованные события
+	Retention time.Duration `yaml:"retention" env:"OUTBOX_RETENTION" env-default:"24h"`
+	LeaseTTL  time.Duration `yaml:"lease_ttl" env:"OUTBOX_LEASE_TTL" env-default:"15s"`
+	// MaxAttempts после стольких неудачных попыток публикации запись откладывается
+	MaxAttempts int           `yaml:"max_attempts" env:"OUTBOX_MAX_ATTEMPTS" env-default:"20"`
+	BackoffMax  time.Duration `yaml:"backoff_max" env:"OUTBOX_BACKOFF_MAX" env-default:"1m"`
+}
+type WebhookConfig struct {
+	// MaxAttempts после стольких неудачных попыток доставка переносится в очередь недоставленных
+	MaxAttempts  int           `yaml:"max_attempts" env:"WEBHOOK_MAX_ATTEMPTS" env-default:"8"`
+	BackoffBase  time.Duration `yaml:"backoff_base" env:"WEBHOOK_BACKOFF_BASE" env-default:"10s"`
+	BackoffMax   time.Duration `yaml:"backoff_max" env:"WEBHOOK_BACKOFF_MAX" env-default:"1h"`
+	Timeout      time.Duration `yaml:"timeout" env:"WEBHOOK_TIMEOUT" env-default:"10s"`
+	PollInterval time.Duration `yaml:"poll_interval" env:"WEBHOOK_POLL_INTERVAL" env-default:"1s"`
+	Concurrency  int           `yaml:"concurrency" env:"WEBHOOK_CONCURRENCY" env-default:"4"`
+	// AllowPrivateNetworks разрешает получателей в локальной и частных сетях, например при разработке
+	AllowPrivateNetworks bool `yaml:"allow_private_networks" env:"WEBHOOK_ALLOW_PRIVATE_NETWORKS" env-default:"false"`
+}
+type EventStreamConfig struct {
+	// MaxLen сколько последних событий хранится для возобновления потока по Last-Event-ID
+	MaxLen    int64         `yaml:"max_len" env:"EVENT_STREAM_MAX_LEN" env-default:"10000"`
+	Buffer    int           `yaml:"buffer" env:"EVENT_STREAM_BUFFER" env-default:"256"`
+	Heartbeat time.Duration `yaml:"heartbeat" env:"EVENT_STREAM_HEARTBEAT" env-default:"15s"`
+}
+type CDCConfig struct {
+	Enabled bool   `yaml:"enabled" env:"CDC_ENABLED" env-default:"true"`
+	Stream  string `yaml:"stream" env:"CDC_STREAM" env-default:"cdc:users"`
+	// MaxLen примерный предел длины потока, старые записи вытесняются
+	MaxLen      int64 `yaml:"max_len" env:"CDC_MAX_LEN" env-default:"1000000"`
+	ReplayBatch int   `yaml:"replay_batch" env:"CDC_REPLAY_BATCH" env-default:"500"`
+}
+type ErasureConfig struct {
+	// SigningKey ключ Ed25519 (32 байта в base64) для подписи квитанций, без него удаление по запросу выключено
+	SigningKey   Secret        `yaml:"signing_key" env:"ERASURE_SIGNING_KEY" env-default:""`
+	PollInterval time.Duration `yaml:"poll_interval" env:"ERASURE_POLL_INTERVAL" env-default:"5s"`
+	LeaseTTL     time.Duration `yaml:"lease_ttl" env:"ERASURE_LEASE_TTL" env-default:"5m"`
+}
+type EncryptionConfig struct {
+	// MasterKeys мастер-ключи "<id>:<32 байта в base64>" через запятую, первый текущий, остальные для чтения старых данных
+	MasterKeys Secret `yaml:"master_keys" env:"ENCRYPTION_MASTER_KEYS" env-default:""`
+	// MasterKeysFile файл с ключами в том же формате, по одному в строке. Читается, если MasterKeys пуст
+	MasterKeysFile string `yaml:"master_keys_file" env:"ENCRYPTION_MASTER_KEYS_FILE" env-default:""`
+	// Fields шифруемые поля "<поле>[:<режим поиска>]": comment (none, exact), location (none)
+	Fields         string `yaml:"fields" env:"ENCRYPTION_FIELDS" env-default:"comment:exact,location"`
+	ReencryptBatch int    `yaml:"reencrypt_batch" env:"ENCRYPTION_REENCRYPT_BATCH" env-default:"500"`
+}
+type RateLimitConfig struct {
+	Enabled bool   `yaml:"enabled" env:"RATE_LIMIT_ENABLED" env-default:"true"`
+	Default string `yaml:"default" env:"RATE_LIMIT_DEFAULT" env-default:"300/1m"`
+	// Routes лимиты отдельных маршрутов в формате <запросов>/<период>
+	Routes map[string]string `yaml:"routes" env:"RATE_LIMIT_ROUTES" env-default:"users_map:20/1m,users_availability:60/1m,auth_login:20/1m,password_reset:5/15m"`
+}
 type Config struct {
-	Env                 string              `yaml:"env" env:"ENV" env-default:"development"`
-	ElasticConfig       ElasticConfig       `yaml:"elastic"`
-	HTTPServerConfig    HTTPServerConfig    `yaml:"http_server"`
-	CacheConfig         CacheConfig         `yaml:"cache"`
-	OpenStreetMapConfig OpenStreetMapConfig `yaml:"openstreetmap"`
+	Env                 string               `yaml:"env" env:"ENV" env-default:"development"`
+	ElasticConfig       ElasticConfig        `yaml:"elastic"`
+	HTTPServerConfig    HTTPServerConfig     `yaml:"http_server"`
+	CacheConfig         CacheConfig          `yaml:"cache"`
+	OpenStreetMapConfig OpenStreetMapConfig  `yaml:"openstreetmap"`
+	PasswordResetConfig PasswordResetConfig  `yaml:"password_reset"`
+	NotifierConfig      NotifierConfig       `yaml:"notifier"`
+	AuthConfig          AuthConfig           `yaml:"auth"`
+	RateLimitConfig     RateLimitConfig      `yaml:"rate_limit"`
+	LoginBlocklist      LoginBlocklistConfig `yaml:"login_blocklist"`
+	Social              SocialConfig         `yaml:"social"`
+	Deletion            DeletionConfig       `yaml:"deletion"`
+	Outbox              OutboxConfig         `yaml:"outbox"`
+	Webhooks            WebhookConfig        `yaml:"webhooks"`
+	EventStream         EventStreamConfig    `yaml:"event_stream"`
+	CDC                 CDCConfig            `yaml:"cdc"`
+	Erasure             ErasureConfig        `yaml:"erasure"`
+	Encryption          EncryptionConfig     `yaml:"encryption"`
 }
 
 func Load() *Config {
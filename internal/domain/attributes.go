@@ -0,0 +1,40 @@
+package domain
+
+import "time"
+
+// Типы дополнительных атрибутов
+const (
+	AttributeString  = "string"
+	AttributeInteger = "integer"
+	AttributeNumber  = "number"
+	AttributeBoolean = "boolean"
+	// AttributeDate строка в формате RFC3339
+	AttributeDate = "date"
+)
+
+// AttributeProperty описание одного атрибута, подмножество JSON Schema
+type AttributeProperty struct {
+	Type        string   `json:"type" example:"string" enums:"string,integer,number,boolean,date"`
+	Description string   `json:"description,omitempty" example:"Отдел"`
+	Enum        []any    `json:"enum,omitempty"`
+	Minimum     *float64 `json:"minimum,omitempty"`
+	Maximum     *float64 `json:"maximum,omitempty"`
+	MinLength   *int     `json:"min_length,omitempty"`
+	MaxLength   *int     `json:"max_length,omitempty"`
+}
+
+// AttributeSchema схема атрибутов пользователя, общая для всего сервиса
+type AttributeSchema struct {
+	Properties map[string]AttributeProperty `json:"properties"`
+	Required   []string                     `json:"required,omitempty"`
+	Version    int64                        `json:"version" example:"3"`
+	UpdatedAt  *time.Time                   `json:"updated_at,omitempty" example:"2025-01-15T12:34:56Z"`
+	UpdatedBy  string                       `json:"updated_by,omitempty" example:"api_key:4f1e8f0a-6a44-4b43-9f0e-3a0e5c3f2b7d"`
+}
+
+// AttributeFilter условие поиска по атрибуту: eq, gte или lte
+type AttributeFilter struct {
+	Name  string
+	Op    string
+	Value string
+}
@@ -0,0 +1,47 @@
+package domain
+
+import "time"
+
+// Операции журнала изменений
+const (
+	AuditCreate   = "create"
+	AuditReplace  = "replace"
+	AuditUpdate   = "update"
+	AuditDelete   = "delete"
+	AuditRestore  = "restore"
+	AuditStatus   = "status"
+	AuditPassword = "password"
+	AuditPurge    = "purge"
+)
+
+// AuditRedacted подставляется вместо значений секретных полей
+const AuditRedacted = "[redacted]"
+
+// FieldChange изменение одного поля. Вложенные объекты раскладываются по путям через точку:
+// location.lat, attributes.department. Отсутствующее значение передаётся как null.
+type FieldChange struct {
+	Field  string `json:"field" example:"email"`
+	Before any    `json:"before"`
+	After  any    `json:"after"`
+}
+
+// AuditEntry запись журнала изменений пользователя. Записи только добавляются.
+// EventID событие, из которого создана запись: повторная доставка события запись не дублирует.
+type AuditEntry struct {
+	ID        string        `json:"id" example:"507f1f77bcf86cd799439011:3"`
+	UserID    string        `json:"user_id" example:"507f1f77bcf86cd799439011"`
+	Version   int64         `json:"version" example:"3"`
+	Operation string        `json:"operation" example:"update" enums:"create,replace,update,delete,restore,status,password,purge"`
+	Actor     string        `json:"actor" example:"api_key:4f1e8f0a-6a44-4b43-9f0e-3a0e5c3f2b7d"`
+	RequestID string        `json:"request_id,omitempty" example:"0b7c5f1e-7f0e-4c8a-9a43-0d3c1a8b2f11"`
+	EventID   string        `json:"event_id,omitempty" example:"9f1c2e4a-3b5d-4c6e-8f70-1a2b3c4d5e6f"`
+	At        time.Time     `json:"at" example:"2025-01-15T12:34:56Z"`
+	Changes   []FieldChange `json:"changes"`
+}
+
+type AuditPage struct {
+	Entries []*AuditEntry `json:"entries"`
+	Total   int64         `json:"total" example:"12"`
+	Page    int           `json:"page" example:"1"`
+	Size    int           `json:"size" example:"20"`
+}
@@ -0,0 +1,20 @@
+package domain
+
+import "time"
+
+const (
+	// BlockReserved зарезервированное имя, сравнивается с нормализованным значением целиком
+	BlockReserved = "reserved"
+	// BlockPattern регулярное выражение, ищется в любом месте нормализованного значения
+	BlockPattern = "pattern"
+)
+
+// LoginBlockRule запрещает использовать значение в логине и имени пользователя
+type LoginBlockRule struct {
+	ID        string    `json:"id" example:"4f1e8f0a-6a44-4b43-9f0e-3a0e5c3f2b7d"`
+	Kind      string    `json:"kind" example:"reserved" enums:"reserved,pattern"`
+	Value     string    `json:"value" example:"admln"`
+	Comment   *string   `json:"comment,omitempty" example:"системная учётная запись"`
+	CreatedAt time.Time `json:"created_at" example:"2025-01-15T12:34:56Z"`
+	CreatedBy string    `json:"created_by" example:"api_key:4f1e8f0a-6a44-4b43-9f0e-3a0e5c3f2b7d"`
+}
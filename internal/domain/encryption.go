@@ -0,0 +1,15 @@
+package domain
+
+// Режимы поиска по зашифрованному полю
+const (
+	// EncryptedSearchNone поле не участвует в поиске и фильтрах
+	EncryptedSearchNone = "none"
+	// EncryptedSearchExact поле находится только по точному совпадению значения
+	EncryptedSearchExact = "exact"
+)
+
+// EncryptedField поле пользователя, которое хранится в Elasticsearch зашифрованным
+type EncryptedField struct {
+	Field  string `json:"field" example:"comment"`
+	Search string `json:"search" example:"exact" enums:"none,exact"`
+}
@@ -0,0 +1,96 @@
+package domain
+
+import (
+	"slices"
+	"time"
+)
+
+// Типы событий пользователя
+const (
+	EventUserCreated         = "user.created"
+	EventUserReplaced        = "user.replaced"
+	EventUserPatched         = "user.patched"
+	EventUserDeleted         = "user.deleted"
+	EventUserRestored        = "user.restored"
+	EventUserStatusChanged   = "user.status_changed"
+	EventUserPasswordChanged = "user.password_changed"
+	EventUserPurged          = "user.purged"
+	// EventUserErased пользователь удалён по запросу субъекта данных, получатели удаляют его данные у себя
+	EventUserErased = "user.erased"
+	// EventLocationChanged публикуется дополнительно к основному событию, если изменилась геолокация
+	EventLocationChanged = "user.location_changed"
+)
+
+var EventTypes = []string{
+	EventUserCreated,
+	EventUserReplaced,
+	EventUserPatched,
+	EventUserDeleted,
+	EventUserRestored,
+	EventUserStatusChanged,
+	EventUserPasswordChanged,
+	EventUserPurged,
+	EventUserErased,
+	EventLocationChanged,
+}
+
+// UserEvent событие об успешной записи пользователя
+type UserEvent struct {
+	ID         string        `json:"id" example:"9f1c2e4a-3b5d-4c6e-8f70-1a2b3c4d5e6f"`
+	Type       string        `json:"type" example:"user.patched"`
+	UserID     string        `json:"user_id" example:"507f1f77bcf86cd799439011"`
+	Actor      string        `json:"actor" example:"api_key:4f1e8f0a-6a44-4b43-9f0e-3a0e5c3f2b7d"`
+	RequestID  string        `json:"request_id,omitempty" example:"0b7c5f1e-7f0e-4c8a-9a43-0d3c1a8b2f11"`
+	OccurredAt time.Time     `json:"occurred_at" example:"2025-01-15T12:34:56Z"`
+	Changes    []FieldChange `json:"changes,omitempty"`
+	// User состояние после изменения без секретов, для удалённых окончательно отсутствует
+	User *User `json:"user,omitempty"`
+	// PreviousLocation заполняется для EventLocationChanged
+	PreviousLocation *Location `json:"previous_location,omitempty"`
+}
+
+// StreamedEvent событие из журнала потока, ID задаёт порядок и служит Last-Event-ID
+type StreamedEvent struct {
+	ID    string
+	Event UserEvent
+}
+
+// BoundingBox прямоугольная область на карте
+type BoundingBox struct {
+	MinLon float64
+	MinLat float64
+	MaxLon float64
+	MaxLat float64
+}
+
+func (b BoundingBox) Contains(loc *Location) bool {
+	return loc != nil &&
+		loc.Lon >= b.MinLon && loc.Lon <= b.MaxLon &&
+		loc.Lat >= b.MinLat && loc.Lat <= b.MaxLat
+}
+
+// EventFilter отбор событий для подписчика потока, пустые условия не ограничивают
+type EventFilter struct {
+	UserIDs []string
+	Types   []string
+	BBox    *BoundingBox
+}
+
+// Matches проверяет событие. Для области подходит и прежняя геолокация,
+// чтобы подписчик видел, как пользователь покидает область.
+func (f EventFilter) Matches(event UserEvent) bool {
+	if len(f.UserIDs) > 0 && !slices.Contains(f.UserIDs, event.UserID) {
+		return false
+	}
+	if len(f.Types) > 0 && !slices.Contains(f.Types, event.Type) {
+		return false
+	}
+	if f.BBox != nil {
+		var current *Location
+		if event.User != nil {
+			current = event.User.Location
+		}
+		return f.BBox.Contains(current) || f.BBox.Contains(event.PreviousLocation)
+	}
+	return true
+}
@@ -2,16 +2,199 @@ package domain
 
 import (
 	"context"
+	"time"
 )
 
+// UserRepository методы записи принимают события, которые сохраняются в outbox
+// тем же запросом, что и документ, и публикуются только если запись удалась
 type UserRepository interface {
-	Create(ctx context.Context, user *User) error
+	Create(ctx context.Context, user *User, events ...UserEvent) error
 
 	GetByID(ctx context.Context, id *string) (*User, error)
+	GetByLogin(ctx context.Context, login *string) (*User, error)
 	Search(ctx context.Context, filters *UserFilter) ([]*User, error)
 
-	Replace(ctx context.Context, user *User) error
-	UpdatePartial(ctx context.Context, user *User) error
+	Replace(ctx context.Context, user *User, events ...UserEvent) error
+	UpdatePartial(ctx context.Context, user *User, events ...UserEvent) error
 
-	Delete(ctx context.Context, id *string) error
+	Delete(ctx context.Context, id *string, events ...UserEvent) error
+	// ListDeleted возвращает помеченных удалёнными не позднее before
+	ListDeleted(ctx context.Context, before time.Time, size int) ([]*User, error)
+	// Scan возвращает всех пользователей, включая удалённых, постранично. Пустой cursor начинает обход,
+	// next передаётся в следующий вызов; пустой next означает, что пользователей больше нет.
+	Scan(ctx context.Context, cursor string, size int) (users []*User, next string, err error)
+	// EncryptedFields поля, которые хранятся зашифрованными и поэтому ограничены в поиске
+	EncryptedFields() []EncryptedField
+}
+
+type PasswordResetStore interface {
+	// Save сохраняет хеш токена сброса, предыдущий токен пользователя становится недействительным
+	Save(ctx context.Context, tokenHash, userID string, ttl time.Duration) error
+	// Consume атомарно удаляет токен и возвращает ID пользователя (одноразовое использование)
+	Consume(ctx context.Context, tokenHash string) (string, error)
+}
+
+type SessionStore interface {
+	Create(ctx context.Context, tokenHash string, session *Session) error
+	Get(ctx context.Context, tokenHash string) (*Session, error)
+	Delete(ctx context.Context, tokenHash string) error
+	DeleteAllForUser(ctx context.Context, userID string) error
+	// ListForUser возвращает действующие сессии пользователя
+	ListForUser(ctx context.Context, userID string) ([]*Session, error)
+}
+
+type MFAChallengeStore interface {
+	Save(ctx context.Context, tokenHash string, challenge *MFAChallenge) error
+	Get(ctx context.Context, tokenHash string) (*MFAChallenge, error)
+	Delete(ctx context.Context, tokenHash string) error
+}
+
+// LoginAttemptStore хранит счётчики неудачных входов и блокировки.
+// Ключ субъекта формирует сервис, например "login:john_doe" или "ip:10.0.0.1".
+type LoginAttemptStore interface {
+	RegisterFailure(ctx context.Context, subject string, window time.Duration) (int64, error)
+	ResetFailures(ctx context.Context, subject string) error
+
+	SetBackoff(ctx context.Context, subject string, d time.Duration) error
+	Backoff(ctx context.Context, subject string) (time.Duration, error)
+
+	Lock(ctx context.Context, lockout *Lockout) error
+	GetLock(ctx context.Context, subject string) (*Lockout, error)
+	Unlock(ctx context.Context, subject string) (bool, error)
+}
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *APIKey) error
+	GetByID(ctx context.Context, id string) (*APIKey, error)
+	List(ctx context.Context) ([]*APIKey, error)
+	Revoke(ctx context.Context, id string, at time.Time) error
+	TouchLastUsed(ctx context.Context, id string, at time.Time) error
+}
+
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, limit RateLimit) (*RateDecision, error)
+}
+
+// LoginReservationRepository гарантирует уникальность логина без учёта регистра и похожих символов.
+// Резерв создаётся атомарно до записи пользователя, поэтому параллельные запросы не могут занять один логин.
+type LoginReservationRepository interface {
+	// Reserve возвращает ALREADY_EXISTS, если логин закреплён за другим пользователем
+	Reserve(ctx context.Context, reservation *LoginReservation) error
+	Get(ctx context.Context, normalized string) (*LoginReservation, error)
+	// Release снимает резерв, только если он принадлежит userID
+	Release(ctx context.Context, normalized, userID string) error
+}
+
+type LoginBlocklistRepository interface {
+	Create(ctx context.Context, rule *LoginBlockRule) error
+	List(ctx context.Context) ([]*LoginBlockRule, error)
+	Delete(ctx context.Context, id string) error
+}
+
+type AttributeSchemaRepository interface {
+	// Get возвращает NOT_FOUND, если схема ещё не задана
+	Get(ctx context.Context) (*AttributeSchema, error)
+	// Save сохраняет схему и добавляет маппинг новых атрибутов в индекс пользователей
+	Save(ctx context.Context, schema *AttributeSchema) error
+}
+
+type AuditRepository interface {
+	// Append присваивает записи следующий номер версии пользователя и сохраняет её
+	Append(ctx context.Context, entry *AuditEntry) error
+	// History возвращает записи пользователя от новых к старым
+	History(ctx context.Context, userID string, page, size int) (*AuditPage, error)
+	// Replay возвращает записи от старых к новым, сделанные не позже until и с версией не выше maxVersion.
+	// Нулевые until и maxVersion не ограничивают выборку.
+	Replay(ctx context.Context, userID string, until time.Time, maxVersion int64) ([]*AuditEntry, error)
+	// Pseudonymize заменяет ID пользователя в записях на pseudonym и стирает значения изменений
+	Pseudonymize(ctx context.Context, userID, pseudonym string) (int, error)
+}
+
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook *Webhook) error
+	GetByID(ctx context.Context, id string) (*Webhook, error)
+	List(ctx context.Context) ([]*Webhook, error)
+	Update(ctx context.Context, webhook *Webhook) error
+	Delete(ctx context.Context, id string) error
+}
+
+type WebhookDeliveryRepository interface {
+	// Create возвращает ALREADY_EXISTS, если доставка с таким ID уже есть
+	Create(ctx context.Context, delivery *WebhookDelivery) error
+	// Save создаёт или перезаписывает доставку целиком
+	Save(ctx context.Context, delivery *WebhookDelivery) error
+	GetByID(ctx context.Context, id string) (*WebhookDelivery, error)
+	// List возвращает доставки вебхука от новых к старым, пустой status не ограничивает выборку
+	List(ctx context.Context, webhookID, status string, page, size int) (*WebhookDeliveryPage, error)
+	// ListByUser возвращает до limit доставок событий пользователя всех вебхуков, от новых к старым
+	ListByUser(ctx context.Context, userID string, limit int) ([]*WebhookDelivery, error)
+	DeleteByUser(ctx context.Context, userID string) (int64, error)
+}
+
+// WebhookQueue очередь доставок по времени следующей попытки. Задача остаётся в очереди,
+// пока не будет выполнена или перенесена в очередь недоставленных, поэтому перезапуск её не теряет.
+type WebhookQueue interface {
+	Enqueue(ctx context.Context, deliveryID string, at time.Time) error
+	// Claim забирает до limit задач со наступившим временем и откладывает их на lease,
+	// чтобы другой экземпляр сервиса не взял их одновременно. Если обработчик не завершит задачу, она вернётся после lease.
+	Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]string, error)
+	// Complete убирает задачу из очереди и из очереди недоставленных
+	Complete(ctx context.Context, deliveryID string) error
+	DeadLetter(ctx context.Context, deliveryID string, at time.Time) error
+}
+
+// OutboxRepository события, ожидающие публикации. Записи добавляет UserRepository.
+type OutboxRepository interface {
+	// Pending возвращает неопубликованные и не отложенные записи в порядке записи
+	Pending(ctx context.Context, limit int) ([]*OutboxEntry, error)
+	MarkPublished(ctx context.Context, ids []string, at time.Time) error
+	// RecordFailure сохраняет число попыток, ошибку, время повтора и отметку об откладывании записи
+	RecordFailure(ctx context.Context, entry *OutboxEntry) error
+	// DeletePublished удаляет записи, опубликованные раньше before
+	DeletePublished(ctx context.Context, before time.Time) (int64, error)
+	// PendingForUser число неопубликованных и не отложенных событий пользователя
+	PendingForUser(ctx context.Context, userID string) (int64, error)
+	// DeleteByUser удаляет опубликованные и отложенные события пользователя
+	DeleteByUser(ctx context.Context, userID string) (int64, error)
+}
+
+// LeaseStore выбирает один экземпляр сервиса для фоновой задачи
+type LeaseStore interface {
+	// Acquire захватывает или продлевает аренду name для owner. false означает, что аренда у другого владельца.
+	Acquire(ctx context.Context, name, owner string, ttl time.Duration) (bool, error)
+	Release(ctx context.Context, name, owner string) error
+}
+
+// EventStream ограниченный журнал последних событий, общий для экземпляров сервиса.
+// Из него читают подписчики потока событий, в том числе возобновляя чтение после разрыва.
+type EventStream interface {
+	// Append добавляет событие. Повторно добавленное событие с тем же ID пропускается, возвращается false.
+	Append(ctx context.Context, event UserEvent) (bool, error)
+	// Range возвращает до count событий после after
+	Range(ctx context.Context, after string, count int) ([]StreamedEvent, error)
+	// Read ждёт события после after не дольше block
+	Read(ctx context.Context, after string, count int, block time.Duration) ([]StreamedEvent, error)
+	// LastID ID последнего события, "0-0" для пустого журнала
+	LastID(ctx context.Context) (string, error)
+	// DeleteByUser удаляет события пользователя из журнала
+	DeleteByUser(ctx context.Context, userID string) (int64, error)
+}
+
+// ChangeStream поток изменений пользователей для внешних потребителей
+type ChangeStream interface {
+	// Publish добавляет событие в поток. Повторно опубликованное событие с тем же ID пропускается, возвращается false.
+	Publish(ctx context.Context, event UserEvent) (bool, error)
+	// DeleteByUser удаляет из потока изменения пользователя, ещё не вытесненные ограничением длины
+	DeleteByUser(ctx context.Context, userID string) (int64, error)
+}
+
+type ErasureJobRepository interface {
+	Create(ctx context.Context, job *ErasureJob) error
+	GetByID(ctx context.Context, id string) (*ErasureJob, error)
+	// Save перезаписывает задачу целиком
+	Save(ctx context.Context, job *ErasureJob) error
+	// Runnable возвращает ожидающие и выполняющиеся задачи, от старых к новым
+	Runnable(ctx context.Context, limit int) ([]*ErasureJob, error)
+	// ActiveForUser возвращает незавершённую задачу пользователя или NOT_FOUND
+	ActiveForUser(ctx context.Context, userID string) (*ErasureJob, error)
 }
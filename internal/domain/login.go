@@ -0,0 +1,19 @@
+package domain
+
+import "time"
+
+// LoginReservation закрепляет нормализованный логин за пользователем
+type LoginReservation struct {
+	Normalized string    `json:"normalized"`
+	Login      string    `json:"login"`
+	UserID     string    `json:"user_id"`
+	ReservedAt time.Time `json:"reserved_at"`
+}
+
+type LoginAvailability struct {
+	Login     string `json:"login" example:"john_doe"`
+	Available bool   `json:"available" example:"false"`
+	// Reason причина недоступности: invalid, reserved или taken
+	Reason  string `json:"reason,omitempty" example:"taken"`
+	Message string `json:"message,omitempty" example:"login is already taken"`
+}
@@ -0,0 +1,12 @@
+package domain
+
+import "time"
+
+type PasswordResetNotification struct {
+	UserID    string
+	Login     string
+	Email     *string
+	Token     string
+	ResetURL  *string
+	ExpiresAt time.Time
+}
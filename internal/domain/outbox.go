@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+// OutboxEntry событие, записанное в outbox тем же запросом, что и изменение пользователя.
+// ID совпадает с ID события: подписчики используют его, чтобы не обработать повторную доставку дважды.
+type OutboxEntry struct {
+	ID string `json:"id"`
+	// UserID копия Event.UserID: событие не индексируется, а удалению данных нужно найти записи пользователя
+	UserID string `json:"user_id"`
+	// Position порядок события среди записанных одним запросом
+	Position    int        `json:"position"`
+	Event       UserEvent  `json:"event"`
+	CreatedAt   time.Time  `json:"created_at"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	// Attempts число неудачных попыток публикации, LastError - ошибка последней из них
+	Attempts  int    `json:"attempts,omitempty"`
+	LastError string `json:"last_error,omitempty"`
+	// RetryAt раньше этого времени запись не публикуется повторно
+	RetryAt *time.Time `json:"retry_at,omitempty"`
+	// ParkedAt запись отложена после нескольких неудачных попыток и больше не публикуется
+	ParkedAt *time.Time `json:"parked_at,omitempty"`
+}
@@ -0,0 +1,72 @@
+package domain
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+const (
+	ScopeUsersRead  = "users:read"
+	ScopeUsersWrite = "users:write"
+	ScopeMapsRead   = "maps:read"
+	// ScopeWebhooks управление подписками на события и их доставками
+	ScopeWebhooks = "webhooks:manage"
+	ScopeAdmin    = "admin"
+)
+
+var KnownScopes = []string{ScopeUsersRead, ScopeUsersWrite, ScopeMapsRead, ScopeWebhooks, ScopeAdmin}
+
+const (
+	PrincipalAPIKey    = "api_key"
+	PrincipalSession   = "session"
+	PrincipalBootstrap = "bootstrap"
+	// PrincipalSystem фоновые задачи сервиса
+	PrincipalSystem = "system"
+)
+
+type APIKey struct {
+	ID         string     `json:"id" example:"0b7c5f1e-7f0e-4c8a-9a43-0d3c1a8b2f11"`
+	Name       string     `json:"name" example:"crm-sync"`
+	Hash       string     `json:"hash,omitempty" swaggerignore:"true"`
+	Scopes     []string   `json:"scopes" example:"users:read,maps:read"`
+	CreatedAt  time.Time  `json:"created_at" example:"2024-01-15T12:34:56Z"`
+	CreatedBy  string     `json:"created_by,omitempty" example:"api_key:bootstrap"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty" example:"2025-01-15T12:34:56Z"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" example:"2024-02-01T08:00:00Z"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" example:"2024-03-01T08:00:00Z"`
+}
+
+// Principal аутентифицированный вызывающий: сервис по API-ключу или пользователь по сессии
+type Principal struct {
+	Type   string
+	ID     string
+	UserID string
+	Scopes []string
+}
+
+func (p *Principal) HasScope(scope string) bool {
+	return p != nil && (slices.Contains(p.Scopes, scope) || slices.Contains(p.Scopes, ScopeAdmin))
+}
+
+// Actor строка для журналов: "api_key:<id>", "session:<user_id>"
+func (p *Principal) Actor() string {
+	if p == nil {
+		return "anonymous"
+	}
+	if p.Type == PrincipalSession {
+		return p.Type + ":" + p.UserID
+	}
+	return p.Type + ":" + p.ID
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+func PrincipalFrom(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
@@ -27,6 +27,29 @@ func geoStr(g *Location) string {
 	return fmt.Sprintf("Location{Lat: %f, Lon: %f}", g.Lat, g.Lon)
 }
 
+func profilesStr(profiles []SocialProfile) string {
+	parts := make([]string, len(profiles))
+	for i, p := range profiles {
+		parts[i] = p.Network + ":" + p.Handle
+	}
+	return strings.Join(parts, " ")
+}
+
+func mapStr(m map[string]any) string {
+	if len(m) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%v", m)
+}
+
+func filtersStr(filters []AttributeFilter) string {
+	parts := make([]string, len(filters))
+	for i, f := range filters {
+		parts[i] = f.Name + " " + f.Op + " " + f.Value
+	}
+	return strings.Join(parts, ", ")
+}
+
 func timeStr(t *time.Time) string {
 	if t == nil {
 		return "<nil>"
@@ -43,11 +66,17 @@ func (u *User) String() string {
 		{"Login", ptrStr(u.Login)},
 		{"Username", ptrStr(u.Username)},
 		{"Password", ifStr(u.Password != nil, "[hidden]")},
+		{"Email", ptrStr(u.Email)},
 		{"Description", ptrStr(u.Description)},
 		{"Comment", ptrStr(u.Comment)},
 		{"RegDate", timeStr(u.RegDate)},
 		{"Location", geoStr(u.Location)},
 		{"SocialNet", ptrStr(u.SocialNet)},
+		{"SocialProfiles", profilesStr(u.SocialProfiles)},
+		{"Attributes", mapStr(u.Attributes)},
+		{"Status", ptrStr(u.Status)},
+		{"DeletedAt", timeStr(u.DeletedAt)},
+		{"TwoFactor", ifStr(u.TwoFactor != nil && u.TwoFactor.Enabled, "enabled")},
 	})
 }
 
@@ -63,6 +92,9 @@ func (f *UserFilter) String() string {
 		{"Lon", ptrStr(f.Lon)},
 		{"Distance", ptrStr(f.Distance)},
 		{"SocialType", ptrStr(f.SocialType)},
+		{"SocialHandle", ptrStr(f.SocialHandle)},
+		{"Status", strings.Join(f.Status, ",")},
+		{"Attributes", filtersStr(f.Attributes)},
 		{"SortBy", ptrStr(f.SortBy)},
 		{"SortOrder", ptrStr(f.SortOrder)},
 		{"Page", ptrStr(f.Page)},
@@ -0,0 +1,114 @@
+package domain
+
+import "time"
+
+// CacheEntry ключ кеша с размером значения и сроком хранения
+type CacheEntry struct {
+	Key       string     `json:"key" example:"tile_59.934280_30.335098_13"`
+	Size      int64      `json:"size" example:"18231"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" example:"2025-01-22T12:34:56Z"`
+}
+
+// CachedTile закешированный тайл карты, построенный по геолокации пользователя
+type CachedTile struct {
+	CacheEntry
+	Location Location `json:"location"`
+	Zoom     int      `json:"zoom" example:"13"`
+}
+
+// UserExport все данные о пользователе, которые хранит сервис (ответ на запрос субъекта данных)
+type UserExport struct {
+	UserID      string    `json:"user_id"`
+	GeneratedAt time.Time `json:"generated_at"`
+	RequestedBy string    `json:"requested_by"`
+	RequestID   string    `json:"request_id,omitempty"`
+	// Profile профиль без хеша пароля и секрета 2FA, о них сообщается в Notes
+	Profile           *User              `json:"profile"`
+	Audit             []*AuditEntry      `json:"audit"`
+	Sessions          []*Session         `json:"sessions"`
+	MapTiles          []*CachedTile      `json:"map_tiles"`
+	WebhookDeliveries []*WebhookDelivery `json:"webhook_deliveries"`
+	Notes             []string           `json:"notes,omitempty"`
+}
+
+// Статусы задачи удаления и её шагов
+const (
+	ErasurePending   = "pending"
+	ErasureRunning   = "running"
+	ErasureFailed    = "failed"
+	ErasureCompleted = "completed"
+)
+
+// Шаги удаления в порядке выполнения. Тайлы ищутся по геолокациям из профиля и журнала,
+// поэтому идут до удаления профиля. Outbox очищается, когда опубликованы все события
+// пользователя, включая событие об удалении профиля; после этого события уже попали в доставки
+// вебхуков и потоки и больше не появятся. Журнал обезличивается последним, когда события уже записаны.
+const (
+	ErasureStepSessions          = "sessions"
+	ErasureStepMapTiles          = "map_tiles"
+	ErasureStepWebhookDeliveries = "webhook_deliveries"
+	ErasureStepProfile           = "profile"
+	ErasureStepOutbox            = "outbox"
+	ErasureStepEventStream       = "event_stream"
+	ErasureStepChangeStream      = "change_stream"
+	ErasureStepAudit             = "audit"
+)
+
+var ErasureSteps = []string{
+	ErasureStepSessions,
+	ErasureStepMapTiles,
+	ErasureStepProfile,
+	ErasureStepOutbox,
+	ErasureStepWebhookDeliveries,
+	ErasureStepEventStream,
+	ErasureStepChangeStream,
+	ErasureStepAudit,
+}
+
+type ErasureStep struct {
+	Name   string `json:"name" example:"map_tiles"`
+	Status string `json:"status" example:"completed" enums:"pending,running,failed,completed"`
+	// Count сколько записей удалено или обезличено
+	Count       int64      `json:"count" example:"3"`
+	Error       string     `json:"error,omitempty" example:"elasticsearch is unavailable"`
+	Attempts    int        `json:"attempts" example:"1"`
+	CompletedAt *time.Time `json:"completed_at,omitempty" example:"2025-01-15T12:34:56Z"`
+}
+
+// ErasureJob удаление пользователя по запросу субъекта данных. Выполненные шаги
+// при возобновлении после сбоя не повторяются.
+type ErasureJob struct {
+	ID     string `json:"id" example:"3e8b2c1d-4f5a-4b6c-9d7e-8f9a0b1c2d3e"`
+	UserID string `json:"user_id" example:"507f1f77bcf86cd799439011"`
+	// Pseudonym заменяет ID пользователя в журнале изменений
+	Pseudonym   string          `json:"pseudonym" example:"erased-5b0f7c2a-8d1e-4f3b-a6c9-2e7d4b1a0f58"`
+	Status      string          `json:"status" example:"running" enums:"pending,running,failed,completed"`
+	Steps       []ErasureStep   `json:"steps"`
+	RequestedBy string          `json:"requested_by" example:"api_key:4f1e8f0a-6a44-4b43-9f0e-3a0e5c3f2b7d"`
+	RequestID   string          `json:"request_id,omitempty" example:"0b7c5f1e-7f0e-4c8a-9a43-0d3c1a8b2f11"`
+	CreatedAt   time.Time       `json:"created_at" example:"2025-01-15T12:34:56Z"`
+	UpdatedAt   time.Time       `json:"updated_at" example:"2025-01-15T12:34:56Z"`
+	CompletedAt *time.Time      `json:"completed_at,omitempty" example:"2025-01-15T12:35:02Z"`
+	Receipt     *ErasureReceipt `json:"receipt,omitempty"`
+}
+
+// ErasureReceipt подтверждение удаления. Signature - Ed25519 от JSON квитанции без поля signature,
+// проверяется открытым ключом PublicKey.
+type ErasureReceipt struct {
+	JobID       string               `json:"job_id" example:"3e8b2c1d-4f5a-4b6c-9d7e-8f9a0b1c2d3e"`
+	UserID      string               `json:"user_id" example:"507f1f77bcf86cd799439011"`
+	RequestedBy string               `json:"requested_by" example:"api_key:4f1e8f0a-6a44-4b43-9f0e-3a0e5c3f2b7d"`
+	RequestedAt time.Time            `json:"requested_at" example:"2025-01-15T12:34:56Z"`
+	CompletedAt time.Time            `json:"completed_at" example:"2025-01-15T12:35:02Z"`
+	Steps       []ErasureReceiptStep `json:"steps"`
+	Algorithm   string               `json:"algorithm" example:"Ed25519"`
+	KeyID       string               `json:"key_id" example:"4f1e8f0a6a444b43"`
+	PublicKey   string               `json:"public_key" example:"Xy7lq0oZl1mHq6sHkT1dGmXxYlB7kW8mP0gq9b1tFzA="`
+	Signature   string               `json:"signature,omitempty" example:"k3Jp..."`
+}
+
+type ErasureReceiptStep struct {
+	Name        string    `json:"name" example:"audit"`
+	Count       int64     `json:"count" example:"12"`
+	CompletedAt time.Time `json:"completed_at" example:"2025-01-15T12:35:02Z"`
+}
@@ -0,0 +1,16 @@
+package domain
+
+import "time"
+
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+}
+
+type RateDecision struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration
+	// Reset время до полного восстановления лимита
+	Reset time.Duration
+}
@@ -0,0 +1,15 @@
+package domain
+
+import "context"
+
+type ifMatchKey struct{}
+
+// WithIfMatch передаёт версию из заголовка If-Match, которую клиент ожидает у изменяемого пользователя
+func WithIfMatch(ctx context.Context, revision string) context.Context {
+	return context.WithValue(ctx, ifMatchKey{}, revision)
+}
+
+func IfMatchFrom(ctx context.Context) string {
+	revision, _ := ctx.Value(ifMatchKey{}).(string)
+	return revision
+}
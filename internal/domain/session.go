@@ -0,0 +1,26 @@
+package domain
+
+import "time"
+
+type Session struct {
+	UserID    string    `json:"user_id"`
+	ClientIP  string    `json:"client_ip,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// MFAChallenge промежуточное состояние входа: пароль проверен, ожидается второй фактор
+type MFAChallenge struct {
+	UserID    string    `json:"user_id"`
+	Login     string    `json:"login"`
+	ClientIP  string    `json:"client_ip,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type Lockout struct {
+	Subject  string    `json:"subject" example:"login:john_doe"`
+	Failures int64     `json:"failures" example:"10"`
+	LockedAt time.Time `json:"locked_at" example:"2024-01-15T12:34:56Z"`
+	Until    time.Time `json:"until" example:"2024-01-15T13:04:56Z"`
+}
@@ -0,0 +1,19 @@
+package domain
+
+// SocialNetwork описание соц. сети из реестра
+type SocialNetwork struct {
+	ID   string `json:"id" example:"telegram"`
+	Name string `json:"name" example:"Telegram"`
+	// HandlePattern регулярное выражение для ника (без @)
+	HandlePattern string `json:"handle_pattern" example:"^[a-z0-9_]{5,32}$"`
+	// ProfileURL шаблон ссылки на профиль, ник подставляется вместо {handle}
+	ProfileURL string `json:"profile_url,omitempty" example:"https://t.me/{handle}"`
+}
+
+// SocialProfile профиль пользователя в соц. сети
+type SocialProfile struct {
+	Network string `json:"network" example:"telegram"`
+	Handle  string `json:"handle,omitempty" example:"john_doe"`
+	// URL вычисляется по шаблону сети и не хранится
+	URL string `json:"url,omitempty" example:"https://t.me/john_doe"`
+}
@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"slices"
+	"time"
+)
+
+// Статусы учётной записи
+const (
+	StatusPending   = "pending"
+	StatusActive    = "active"
+	StatusSuspended = "suspended"
+	StatusBanned    = "banned"
+	StatusDeleted   = "deleted"
+)
+
+var Statuses = []string{StatusPending, StatusActive, StatusSuspended, StatusBanned, StatusDeleted}
+
+// statusTransitions допустимые переходы между статусами
+var statusTransitions = map[string][]string{
+	StatusPending:   {StatusActive, StatusSuspended, StatusBanned, StatusDeleted},
+	StatusActive:    {StatusSuspended, StatusBanned, StatusDeleted},
+	StatusSuspended: {StatusActive, StatusBanned, StatusDeleted},
+	StatusBanned:    {StatusActive, StatusDeleted},
+	StatusDeleted:   {},
+}
+
+// HiddenStatuses по умолчанию не попадают в поиск
+var HiddenStatuses = []string{StatusBanned, StatusDeleted}
+
+// CanTransition проверяет, разрешён ли переход из from в to
+func CanTransition(from, to string) bool {
+	return slices.Contains(statusTransitions[from], to)
+}
+
+// StatusChange последняя смена статуса
+type StatusChange struct {
+	From   string    `json:"from" example:"active"`
+	Reason string    `json:"reason,omitempty" example:"Рассылка спама"`
+	Actor  string    `json:"actor" example:"api_key:4f1e8f0a-6a44-4b43-9f0e-3a0e5c3f2b7d"`
+	At     time.Time `json:"at" example:"2025-01-15T12:34:56Z"`
+}
+
+// CurrentStatus возвращает статус пользователя. Документы, созданные до появления статусов, считаются активными.
+func (u *User) CurrentStatus() string {
+	if u.Status == nil || *u.Status == "" {
+		return StatusActive
+	}
+	return *u.Status
+}
@@ -7,11 +7,33 @@ type User struct {
 	Login       *string    `form:"login" json:"login,omitempty" example:"john_doe" swagger:"description='Логин пользователя'"`
 	Username    *string    `form:"username" json:"username,omitempty" example:"John Doe" swagger:"description='Имя пользователя'"`
 	Password    *string    `form:"password" json:"password,omitempty" example:"secret123" swagger:"description='Пароль пользователя '"`
+	Email       *string    `form:"email" json:"email,omitempty" example:"john@example.com" swagger:"description='Электронная почта для уведомлений'"`
 	Description *string    `form:"description" json:"description,omitempty" example:"Программист из Санкт-Петербурга" swagger:"description='Описание пользователя'"`
 	Comment     *string    `form:"comment" json:"comment,omitempty" example:"Важный клиент" swagger:"description='Комментарии о пользователе (заметка админа)'"`
 	RegDate     *time.Time `form:"reg_date" json:"reg_date,omitempty" example:"2023-01-15T12:34:56Z" swagger:"description='Дата регистрации'"`
 	Location    *Location  `form:"location" json:"location,omitempty" swagger:"description='Геолокация пользователя'"`
-	SocialNet   *string    `form:"social_net" json:"social_net,omitempty" example:"MAX" swagger:"description='Название соц. сети, строго определенное'"`
+	// SocialNet устаревшее поле, принимается на запись и переносится в SocialProfiles
+	SocialNet      *string         `form:"social_net" json:"social_net,omitempty" example:"MAX" swagger:"description='Устарело, используйте social_profiles'"`
+	SocialProfiles []SocialProfile `form:"-" json:"social_profiles,omitempty" swagger:"description='Профили в соц. сетях'"`
+	Attributes     map[string]any  `form:"-" json:"attributes,omitempty" swagger:"description='Дополнительные атрибуты по схеме, которую задаёт администратор'"`
+	Status         *string         `form:"-" json:"status,omitempty" example:"active" enums:"pending,active,suspended,banned,deleted" swagger:"description='Статус учётной записи. При создании можно указать pending, дальше меняется через отдельные методы'"`
+	StatusChange   *StatusChange   `form:"-" json:"status_change,omitempty" swagger:"description='Последняя смена статуса (только чтение)'"`
+	DeletedAt      *time.Time      `form:"-" json:"deleted_at,omitempty" example:"2025-01-15T12:34:56Z" swagger:"description='Когда пользователь помечен удалённым (только чтение)'"`
+	TwoFactor      *TwoFactor      `form:"-" json:"two_factor,omitempty" swagger:"description='Состояние двухфакторной аутентификации (только чтение)'"`
+	// Revision версия документа в хранилище, отдаётся в ETag. Запись с непустой версией
+	// не выполнится, если документ успели изменить.
+	Revision string `form:"-" json:"-"`
+}
+
+// TwoFactor хранится в документе пользователя. Секреты зашифрованы, коды восстановления захешированы,
+// наружу отдаётся только флаг Enabled.
+type TwoFactor struct {
+	Enabled       bool       `json:"enabled" example:"true"`
+	Secret        string     `json:"secret,omitempty" swaggerignore:"true"`
+	PendingSecret string     `json:"pending_secret,omitempty" swaggerignore:"true"`
+	RecoveryCodes []string   `json:"recovery_codes,omitempty" swaggerignore:"true"`
+	LastUsedStep  int64      `json:"last_used_step,omitempty" swaggerignore:"true"`
+	EnabledAt     *time.Time `json:"enabled_at,omitempty" example:"2023-01-15T12:34:56Z"`
 }
 
 type UserFilter struct {
@@ -28,7 +50,14 @@ type UserFilter struct {
 	Distance *string  `form:"radius" json:"radius,omitempty" example:"1000" swagger:"description='Максимально расстояние между пользователем и заданной точки', default='1km', enum='100m,500m,1km,5km,10km'"`
 
 	// Социальные сети
-	SocialType *string `form:"social_net" json:"social_net,omitempty" example:"facebook" swagger:"description='Тип соц. сети'"`
+	SocialType   *string `form:"social_net" json:"social_net,omitempty" example:"facebook" swagger:"description='Тип соц. сети'"`
+	SocialHandle *string `form:"social_handle" json:"social_handle,omitempty" example:"john_doe" swagger:"description='Ник в соц. сети'"`
+
+	// Статусы через запятую. Без фильтра заблокированные и удалённые не возвращаются
+	Status []string `form:"status" json:"status,omitempty" example:"active,suspended" swagger:"description='Статусы через запятую (pending, active, suspended, banned, deleted)'"`
+
+	// Фильтры по атрибутам, в запросе attr.<name>=, attr.<name>.gte=, attr.<name>.lte=
+	Attributes []AttributeFilter `form:"-" json:"attributes,omitempty" swaggerignore:"true"`
 
 	// Сортировка
 	SortBy    *string `form:"sort_by" json:"sort_by,omitempty" example:"login" swagger:"description='Поле сортировки (login, reg_date)', enum='login,reg_date'"`
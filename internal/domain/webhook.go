@@ -0,0 +1,72 @@
+package domain
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+)
+
+// Статусы доставки вебхука
+const (
+	// DeliveryPending доставка ждёт первой попытки
+	DeliveryPending = "pending"
+	// DeliverySucceeded получатель ответил кодом 2xx
+	DeliverySucceeded = "succeeded"
+	// DeliveryFailed попытка не удалась, назначен повтор
+	DeliveryFailed = "failed"
+	// DeliveryDead попытки исчерпаны, доставка перенесена в очередь недоставленных
+	DeliveryDead = "dead"
+)
+
+var DeliveryStatuses = []string{DeliveryPending, DeliverySucceeded, DeliveryFailed, DeliveryDead}
+
+// Webhook подписка внешней системы на события пользователей
+type Webhook struct {
+	ID  string `json:"id" example:"6d2b1f0e-5c1a-4e8b-9a7d-2f3e4c5b6a70"`
+	URL string `json:"url" example:"https://crm.example.com/hooks/users"`
+	// Events типы событий подписки, пустой список означает все
+	Events []string `json:"events" example:"user.created,user.patched"`
+	// Secret ключ подписи, в ответах API показывается только при создании
+	Secret      string     `json:"secret,omitempty" example:"whsec_mJx2...Q"`
+	Description *string    `json:"description,omitempty" example:"синхронизация с CRM"`
+	Active      bool       `json:"active" example:"true"`
+	CreatedAt   time.Time  `json:"created_at" example:"2025-01-15T12:34:56Z"`
+	CreatedBy   string     `json:"created_by" example:"api_key:4f1e8f0a-6a44-4b43-9f0e-3a0e5c3f2b7d"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty" example:"2025-01-16T08:00:00Z"`
+}
+
+// Accepts проверяет, подписан ли вебхук на событие
+func (w *Webhook) Accepts(eventType string) bool {
+	if !w.Active {
+		return false
+	}
+	return len(w.Events) == 0 || slices.Contains(w.Events, eventType)
+}
+
+// WebhookDelivery доставка одного события одному вебхуку вместе с результатом последней попытки
+type WebhookDelivery struct {
+	ID        string `json:"id" example:"1c9e7a52-3f4d-4b8e-a1c2-7d6e5f4a3b21"`
+	WebhookID string `json:"webhook_id" example:"6d2b1f0e-5c1a-4e8b-9a7d-2f3e4c5b6a70"`
+	EventID   string `json:"event_id" example:"9f1c2e4a-3b5d-4c6e-8f70-1a2b3c4d5e6f"`
+	EventType string `json:"event_type" example:"user.patched"`
+	UserID    string `json:"user_id,omitempty" example:"507f1f77bcf86cd799439011"`
+	// Payload тело запроса, повторная отправка использует его без изменений
+	Payload        json.RawMessage `json:"payload" swaggertype:"object"`
+	Status         string          `json:"status" example:"failed" enums:"pending,succeeded,failed,dead"`
+	Attempts       int             `json:"attempts" example:"2"`
+	ResponseStatus int             `json:"response_status,omitempty" example:"503"`
+	Error          string          `json:"error,omitempty" example:"unexpected response status 503"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty" example:"2025-01-15T12:35:16Z"`
+	LastAttemptAt  *time.Time      `json:"last_attempt_at,omitempty" example:"2025-01-15T12:34:56Z"`
+	// ReplayOf ID доставки, повтором которой создана эта
+	ReplayOf  *string   `json:"replay_of,omitempty" example:"0a8d6c41-2e3c-4a7d-9b1f-6c5d4e3f2a10"`
+	CreatedAt time.Time `json:"created_at" example:"2025-01-15T12:34:56Z"`
+	UpdatedAt time.Time `json:"updated_at" example:"2025-01-15T12:34:56Z"`
+}
+
+type WebhookDeliveryPage struct {
+	Deliveries []*WebhookDelivery `json:"deliveries"`
+	Total      int64              `json:"total" example:"42"`
+	Page       int                `json:"page" example:"1"`
+	Size       int                `json:"size" example:"20"`
+}
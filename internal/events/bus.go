@@ -0,0 +1,221 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+
+	"github.com/satrunjis/user-service/internal/domain"
+)
+
+const defaultBuffer = 256
+
+var ErrClosed = errors.New("events: bus is closed")
+
+// Handler обрабатывает событие в отдельной горутине подписчика. Ошибка оставляет событие
+// неопубликованным, и relay повторит его, поэтому обработчик должен пропускать уже обработанные.
+type Handler func(ctx context.Context, event domain.UserEvent) error
+
+type Options struct {
+	// Buffer размер очереди подписчика
+	Buffer int
+	// Blocking при заполненной очереди задерживает публикацию вместо потери события.
+	// Нужен подписчикам, которым важна каждая запись, например журналу изменений.
+	Blocking bool
+	// Types типы событий подписчика, пустой список означает все
+	Types []string
+}
+
+type delivery struct {
+	ctx   context.Context
+	event domain.UserEvent
+	// done вызывается после обработки, если публикующий ждёт подписчиков
+	done func(err error)
+}
+
+type subscriber struct {
+	name    string
+	handler Handler
+	opts    Options
+	queue   chan delivery
+	done    chan struct{}
+}
+
+// Bus доставляет события подписчикам внутри процесса. У каждого подписчика своя очередь
+// и горутина, поэтому медленный или упавший подписчик не мешает остальным.
+type Bus struct {
+	mu     sync.RWMutex
+	subs   []*subscriber
+	closed bool
+	logger *slog.Logger
+}
+
+func NewBus(logger *slog.Logger) *Bus {
+	return &Bus{logger: logger}
+}
+
+// Subscribe регистрирует обработчик. Возвращаемая функция отписывает его,
+// дождавшись обработки уже поставленных в очередь событий.
+func (b *Bus) Subscribe(name string, handler Handler, opts Options) func() {
+	if opts.Buffer <= 0 {
+		opts.Buffer = defaultBuffer
+	}
+	sub := &subscriber{
+		name:    name,
+		handler: handler,
+		opts:    opts,
+		queue:   make(chan delivery, opts.Buffer),
+		done:    make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	b.subs = append(b.subs, sub)
+	b.mu.Unlock()
+
+	go b.run(sub)
+
+	return func() {
+		b.mu.Lock()
+		i := slices.Index(b.subs, sub)
+		if i >= 0 {
+			b.subs = slices.Delete(b.subs, i, i+1)
+			close(sub.queue)
+		}
+		b.mu.Unlock()
+		<-sub.done
+	}
+}
+
+// Publish ставит событие в очереди подписчиков. Контекст запроса не ограничивает
+// доставку, но его значения (инициатор, ID запроса) доступны обработчикам.
+func (b *Bus) Publish(ctx context.Context, event domain.UserEvent) error {
+	return b.publish(context.WithoutCancel(ctx), event, nil, nil)
+}
+
+// PublishAndWait публикует событие и ждёт, пока его обработают все подписчики.
+// Возвращает ошибки обработчиков; подписчик без блокировки с заполненной очередью тоже считается ошибкой.
+func (b *Bus) PublishAndWait(ctx context.Context, event domain.UserEvent) error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	report := func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}
+	if err := b.publish(context.WithoutCancel(ctx), event, &wg, report); err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		mu.Lock()
+		defer mu.Unlock()
+		return errors.Join(errs...)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// publish ставит событие в очереди. Если задан wg, каждая доставка отмечается в нём,
+// а ошибка обработки передаётся в report.
+func (b *Bus) publish(ctx context.Context, event domain.UserEvent, wg *sync.WaitGroup, report func(error)) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		b.logger.WarnContext(ctx, "event published after bus was closed", "event_type", event.Type, "event_id", event.ID)
+		return ErrClosed
+	}
+
+	for _, sub := range b.subs {
+		if len(sub.opts.Types) > 0 && !slices.Contains(sub.opts.Types, event.Type) {
+			continue
+		}
+		d := delivery{ctx: ctx, event: event}
+		if wg != nil {
+			wg.Add(1)
+			d.done = func(err error) {
+				if err != nil {
+					report(err)
+				}
+				wg.Done()
+			}
+		}
+		if sub.opts.Blocking {
+			sub.queue <- d
+			continue
+		}
+		select {
+		case sub.queue <- d:
+		default:
+			if d.done != nil {
+				d.done(fmt.Errorf("subscriber %s: queue is full", sub.name))
+			}
+			b.logger.WarnContext(ctx, "subscriber queue is full, event dropped",
+				"subscriber", sub.name, "event_type", event.Type, "event_id", event.ID)
+		}
+	}
+	return nil
+}
+
+// Close перестаёт принимать события и ждёт, пока подписчики обработают очереди
+func (b *Bus) Close(ctx context.Context) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	subs := b.subs
+	b.subs = nil
+	for _, sub := range subs {
+		close(sub.queue)
+	}
+	b.mu.Unlock()
+
+	for _, sub := range subs {
+		select {
+		case <-sub.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (b *Bus) run(sub *subscriber) {
+	defer close(sub.done)
+	for d := range sub.queue {
+		b.deliver(sub, d)
+	}
+}
+
+// deliver изолирует панику обработчика, чтобы подписчик продолжил получать события
+func (b *Bus) deliver(sub *subscriber, d delivery) {
+	var err error
+	defer func() {
+		if r := recover(); r != nil {
+			b.logger.ErrorContext(d.ctx, "event handler panicked",
+				"subscriber", sub.name, "event_type", d.event.Type, "event_id", d.event.ID, "panic", r)
+			err = fmt.Errorf("subscriber %s panicked: %v", sub.name, r)
+		}
+		if d.done != nil {
+			d.done(err)
+		}
+	}()
+	if err = sub.handler(d.ctx, d.event); err != nil {
+		b.logger.ErrorContext(d.ctx, "event handler failed",
+			"subscriber", sub.name, "event_type", d.event.Type, "event_id", d.event.ID, "error", err)
+		err = fmt.Errorf("subscriber %s: %w", sub.name, err)
+	}
+}
@@ -0,0 +1,207 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/satrunjis/user-service/internal/domain"
+)
+
+// Аренда, которой экземпляр сервиса закрепляет за собой чтение outbox
+const relayLease = "outbox-relay"
+
+type RelayOptions struct {
+	PollInterval time.Duration
+	BatchSize    int
+	// Retention сколько хранятся опубликованные записи, чтобы разбирать инциденты
+	Retention time.Duration
+	// LeaseTTL если экземпляр не продлил аренду за это время, outbox читает другой
+	LeaseTTL time.Duration
+	// MaxAttempts после стольких неудачных попыток запись откладывается, чтобы не задерживать
+	// следующие события. 0 - повторять без ограничений.
+	MaxAttempts int
+	// BackoffMax наибольшая пауза между попытками; первая пауза равна PollInterval и удваивается
+	BackoffMax time.Duration
+}
+
+// Relay читает события из outbox и публикует их в шину. Запись отмечается опубликованной
+// только после успешной обработки всеми подписчиками, поэтому доставка не реже одного раза:
+// после сбоя событие может прийти повторно с тем же ID, и подписчики пропускают уже обработанные.
+// Outbox читает один экземпляр сервиса, остальные ждут освобождения аренды.
+type Relay struct {
+	outbox domain.OutboxRepository
+	leases domain.LeaseStore
+	bus    *Bus
+	opts   RelayOptions
+	owner  string
+	wake   chan struct{}
+	logger *slog.Logger
+}
+
+func NewRelay(outbox domain.OutboxRepository, leases domain.LeaseStore, bus *Bus, opts RelayOptions, logger *slog.Logger) *Relay {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	if opts.BackoffMax < opts.PollInterval {
+		opts.BackoffMax = opts.PollInterval
+	}
+	return &Relay{
+		outbox: outbox,
+		leases: leases,
+		bus:    bus,
+		opts:   opts,
+		owner:  uuid.New().String(),
+		wake:   make(chan struct{}, 1),
+		logger: logger,
+	}
+}
+
+// Wake сообщает о новых записях, чтобы не ждать следующего опроса
+func (r *Relay) Wake() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run публикует события из outbox, пока не отменён ctx
+func (r *Relay) Run(ctx context.Context) {
+	const op = "Relay.Run"
+	log := r.logger.With("operation", op)
+
+	if r.opts.PollInterval <= 0 {
+		log.Warn("outbox relay is disabled, events are not published")
+		return
+	}
+
+	ticker := time.NewTicker(r.opts.PollInterval)
+	defer ticker.Stop()
+	var cleanedAt time.Time
+
+	defer func() {
+		if err := r.leases.Release(context.WithoutCancel(ctx), relayLease, r.owner); err != nil {
+			log.Warn("failed to release outbox lease", "error", err)
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-r.wake:
+		}
+
+		leader, err := r.leases.Acquire(ctx, relayLease, r.owner, r.opts.LeaseTTL)
+		if err != nil {
+			log.ErrorContext(ctx, "failed to acquire outbox lease", "error", err)
+			continue
+		}
+		if !leader {
+			continue
+		}
+
+		r.drain(ctx, log)
+
+		if r.opts.Retention > 0 && time.Since(cleanedAt) > time.Hour {
+			cleanedAt = time.Now()
+			deleted, err := r.outbox.DeletePublished(ctx, time.Now().Add(-r.opts.Retention))
+			if err != nil {
+				log.ErrorContext(ctx, "failed to clean up outbox", "error", err)
+			} else if deleted > 0 {
+				log.InfoContext(ctx, "published outbox entries removed", "deleted", deleted)
+			}
+		}
+	}
+}
+
+func (r *Relay) drain(ctx context.Context, log *slog.Logger) {
+	for ctx.Err() == nil {
+		entries, err := r.outbox.Pending(ctx, r.opts.BatchSize)
+		if err != nil {
+			log.ErrorContext(ctx, "failed to read outbox", "error", err)
+			return
+		}
+		if len(entries) == 0 {
+			return
+		}
+
+		// Пачка публикуется по порядку до первой ошибки: событие и следующие за ним
+		// остаются в outbox и повторяются не раньше RetryAt. Отложенная запись следующие не задерживает.
+		ids := make([]string, 0, len(entries))
+		failed := false
+		for _, entry := range entries {
+			if entry.RetryAt != nil && time.Now().Before(*entry.RetryAt) {
+				failed = true
+				break
+			}
+			if err := r.bus.PublishAndWait(ctx, entry.Event); err != nil {
+				if ctx.Err() != nil || errors.Is(err, ErrClosed) {
+					failed = true
+					break
+				}
+				if r.recordFailure(ctx, log, entry, err) {
+					continue
+				}
+				failed = true
+				break
+			}
+			ids = append(ids, entry.ID)
+		}
+		if len(ids) > 0 {
+			if err := r.outbox.MarkPublished(context.WithoutCancel(ctx), ids, time.Now().UTC()); err != nil {
+				log.ErrorContext(ctx, "failed to mark outbox entries as published", "error", err, "count", len(ids))
+				return
+			}
+			log.DebugContext(ctx, "outbox entries published", "count", len(ids))
+		}
+
+		if failed || len(entries) < r.opts.BatchSize {
+			return
+		}
+		// Пачка могла обрабатываться долго, аренда продлевается перед следующей
+		if leader, err := r.leases.Acquire(ctx, relayLease, r.owner, r.opts.LeaseTTL); err != nil || !leader {
+			return
+		}
+	}
+}
+
+// recordFailure сохраняет неудачную попытку публикации и назначает следующую. После MaxAttempts
+// попыток запись откладывается: она остаётся в outbox для разбора, но больше не публикуется.
+// Возвращает true, если запись отложена и можно публиковать следующие.
+func (r *Relay) recordFailure(ctx context.Context, log *slog.Logger, entry *domain.OutboxEntry, cause error) bool {
+	now := time.Now().UTC()
+	entry.Attempts++
+	entry.LastError = cause.Error()
+	parked := r.opts.MaxAttempts > 0 && entry.Attempts >= r.opts.MaxAttempts
+	if parked {
+		entry.ParkedAt = &now
+	} else {
+		retryAt := now.Add(r.backoff(entry.Attempts))
+		entry.RetryAt = &retryAt
+	}
+
+	log = log.With("entry_id", entry.ID, "event_id", entry.Event.ID, "event_type", entry.Event.Type, "attempts", entry.Attempts)
+	if err := r.outbox.RecordFailure(context.WithoutCancel(ctx), entry); err != nil {
+		log.ErrorContext(ctx, "failed to publish outbox entry, will retry", "error", cause, "record_error", err)
+		return false
+	}
+	if parked {
+		log.ErrorContext(ctx, "outbox entry parked after repeated publish failures, it will not be published", "user_id", entry.UserID, "error", cause)
+		return true
+	}
+	log.ErrorContext(ctx, "failed to publish outbox entry, will retry", "retry_at", entry.RetryAt, "error", cause)
+	return false
+}
+
+// backoff пауза перед следующей попыткой: PollInterval, удвоенный за каждую неудачную попытку
+func (r *Relay) backoff(attempt int) time.Duration {
+	d := r.opts.PollInterval
+	for i := 1; i < attempt && d < r.opts.BackoffMax; i++ {
+		d *= 2
+	}
+	return min(d, r.opts.BackoffMax)
+}
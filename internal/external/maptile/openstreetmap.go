@@ -8,6 +8,8 @@ import (
 	"math"
 	"net/http"
 	"time"
+
+	"github.com/satrunjis/user-service/internal/service"
 )
 
 type OpenStreetMapService struct {
@@ -54,8 +56,13 @@ func (s *OpenStreetMapService) GetMapTile(ctx context.Context, lat, lon float64,
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		log.ErrorContext(ctx, "failed to fetch tile", "error", err)
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		log.ErrorContext(ctx, "failed to fetch tile", "status", resp.StatusCode)
+		err := fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		// Перегрузка и сбои тайл-сервера временные, клиент может повторить запрос
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			return nil, service.WrapError(service.ErrCodeUnavailable, err)
+		}
+		return nil, err
 	}
 	tileData, err := io.ReadAll(resp.Body)
 	if err != nil {
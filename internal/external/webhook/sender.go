@@ -0,0 +1,74 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/satrunjis/user-service/internal/netguard"
+)
+
+// Тело ответа получателя не используется, но дочитывается, чтобы соединение вернулось в пул
+const maxResponseBody = 64 << 10
+
+type HTTPSender struct {
+	client    *http.Client
+	userAgent string
+	logger    *slog.Logger
+}
+
+// NewHTTPSender создаёт отправителя. Без allowPrivate подключения к loopback, частным и link-local
+// адресам запрещены: адрес получателя задаёт клиент API.
+func NewHTTPSender(timeout time.Duration, allowPrivate bool, logger *slog.Logger) *HTTPSender {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = netguard.Control
+	}
+	return &HTTPSender{
+		client: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				// Прокси из окружения не используется, иначе проверялся бы адрес прокси, а не получателя
+				DialContext:     dialer.DialContext,
+				MaxIdleConns:    50,
+				IdleConnTimeout: 90 * time.Second,
+			},
+			// Перенаправления не выполняются: подпись привязана к адресу подписки
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		userAgent: "user-service-webhooks/1.0",
+		logger:    logger,
+	}
+}
+
+// Send отправляет POST и возвращает код ответа. Ошибка означает, что ответ не получен.
+func (s *HTTPSender) Send(ctx context.Context, url string, header http.Header, body []byte) (int, error) {
+	const op = "webhook.Send"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("%s: failed to create request: %w", op, err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", s.userAgent)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBody))
+
+	s.logger.DebugContext(ctx, "webhook request sent", "operation", op, "status", resp.StatusCode)
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,77 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/satrunjis/user-service/internal/netguard"
+)
+
+var discard = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func TestSendPostsSignedRequest(t *testing.T) {
+	var got *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	sender := NewHTTPSender(time.Second, true, discard)
+	header := http.Header{"X-Webhook-Signature": {"sha256=abc"}}
+	status, err := sender.Send(context.Background(), server.URL+"/hook", header, []byte(`{"id":"1"}`))
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if status != http.StatusAccepted {
+		t.Errorf("status = %d, want %d", status, http.StatusAccepted)
+	}
+	if got.Method != http.MethodPost || got.URL.Path != "/hook" {
+		t.Errorf("request = %s %s, want POST /hook", got.Method, got.URL.Path)
+	}
+	if got.Header.Get("X-Webhook-Signature") != "sha256=abc" || got.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("headers = %v", got.Header)
+	}
+	if string(body) != `{"id":"1"}` {
+		t.Errorf("body = %s", body)
+	}
+}
+
+func TestSendDoesNotFollowRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://169.254.169.254/latest/meta-data", http.StatusFound)
+	}))
+	defer server.Close()
+
+	status, err := NewHTTPSender(time.Second, true, discard).Send(context.Background(), server.URL, nil, nil)
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if status != http.StatusFound {
+		t.Errorf("status = %d, want %d", status, http.StatusFound)
+	}
+}
+
+func TestSendRejectsPrivateAddresses(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	_, err := NewHTTPSender(time.Second, false, discard).Send(context.Background(), server.URL, nil, nil)
+	if !errors.Is(err, netguard.ErrForbiddenAddress) {
+		t.Fatalf("Send() error = %v, want ErrForbiddenAddress", err)
+	}
+	if called {
+		t.Error("request reached a loopback address")
+	}
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/satrunjis/user-service/internal/domain"
+)
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required" example:"crm-sync" swagger:"description='Название ключа (какой сервис использует)'"`
+	Scopes    []string   `json:"scopes" binding:"required" example:"users:read,maps:read" swagger:"description='Права: users:read, users:write, maps:read, webhooks:manage, admin'"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" example:"2025-01-15T12:34:56Z" swagger:"description='Срок действия (необязательно)'"`
+}
+
+type CreateAPIKeyResponse struct {
+	Key    string         `json:"key" example:"usk_0b7c5f1e-7f0e-4c8a-9a43-0d3c1a8b2f11_mJx2...Q"`
+	APIKey *domain.APIKey `json:"api_key"`
+}
+
+type APIKeyListResponse struct {
+	APIKeys []*domain.APIKey `json:"api_keys"`
+	Total   int              `json:"total"`
+}
+
+// CreateAPIKey godoc
+// @Summary      Создать API-ключ
+// @Description  Создаёт ключ для межсервисного доступа. Значение ключа показывается один раз, хранится только его хеш
+// @Tags         api-keys
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        body  body      CreateAPIKeyRequest  true  "Название и права"
+// @Success      201   {object}  CreateAPIKeyResponse
+// @Failure      400   {object}  ErrorResponse
+// @Failure      401   {object}  ErrorResponse
+// @Failure      403   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Router       /api/v1/admin/api-keys [post]
+func (h *AuthHandler) CreateAPIKey(c *gin.Context) {
+	const op = "AuthHandler.CreateAPIKey"
+	log := h.logger.With("operation", op)
+	ctx := c.Request.Context()
+
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.ErrorContext(ctx, "failed to bind JSON", "error", err)
+		c.Error(bindError(err))
+		return
+	}
+
+	created, err := h.authService.CreateAPIKey(ctx, req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to create api key", "error", err)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, CreateAPIKeyResponse{Key: created.Key, APIKey: created.APIKey})
+}
+
+// ListAPIKeys godoc
+// @Summary      Список API-ключей
+// @Description  Возвращает все ключи, включая отозванные, без секретов
+// @Tags         api-keys
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Success      200  {object}  APIKeyListResponse
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /api/v1/admin/api-keys [get]
+func (h *AuthHandler) ListAPIKeys(c *gin.Context) {
+	keys, err := h.authService.ListAPIKeys(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to list api keys", "err", err)
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, APIKeyListResponse{APIKeys: keys, Total: len(keys)})
+}
+
+// RevokeAPIKey godoc
+// @Summary      Отозвать API-ключ
+// @Description  Ключ перестаёт приниматься сразу после отзыва
+// @Tags         api-keys
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id   path  string  true  "API key ID"
+// @Success      204
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Router       /api/v1/admin/api-keys/{id} [delete]
+func (h *AuthHandler) RevokeAPIKey(c *gin.Context) {
+	if err := h.authService.RevokeAPIKey(c.Request.Context(), c.Param("id")); err != nil {
+		h.logger.Error("Failed to revoke api key", "err", err)
+		c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/satrunjis/user-service/internal/domain"
+)
+
+const attributeFilterPrefix = "attr."
+
+// GetAttributeSchema godoc
+// @Summary      Схема дополнительных атрибутов
+// @Description  Возвращает типы, допустимые значения и обязательные атрибуты пользователей
+// @Tags         attributes
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Success      200  {object}  domain.AttributeSchema
+// @Failure      401  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /api/v1/users/attributes/schema [get]
+func (h *UserHandler) GetAttributeSchema(c *gin.Context) {
+	schema, err := h.userService.GetAttributeSchema(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to get attribute schema", "err", err)
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, schema)
+}
+
+// UpdateAttributeSchema godoc
+// @Summary      Изменить схему атрибутов
+// @Description  Заменяет схему целиком. Тип существующего атрибута изменить нельзя, удалённые атрибуты перестают приниматься при записи
+// @Tags         attributes
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        schema  body      domain.AttributeSchema  true  "Схема"
+// @Success      200     {object}  domain.AttributeSchema
+// @Failure      400     {object}  ErrorResponse
+// @Failure      401     {object}  ErrorResponse
+// @Failure      403     {object}  ErrorResponse
+// @Failure      409     {object}  ErrorResponse
+// @Failure      500     {object}  ErrorResponse
+// @Router       /api/v1/admin/attributes/schema [put]
+func (h *UserHandler) UpdateAttributeSchema(c *gin.Context) {
+	const op = "UserHandler.UpdateAttributeSchema"
+	log := h.logger.With("operation", op)
+	ctx := c.Request.Context()
+
+	var schema domain.AttributeSchema
+	if err := c.ShouldBindJSON(&schema); err != nil {
+		log.ErrorContext(ctx, "failed to bind JSON", "error", err)
+		c.Error(bindError(err))
+		return
+	}
+
+	updated, err := h.userService.UpdateAttributeSchema(ctx, &schema)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to update attribute schema", "error", err)
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+// parseAttributeFilters разбирает параметры attr.<name>, attr.<name>.gte и attr.<name>.lte
+func parseAttributeFilters(c *gin.Context) []domain.AttributeFilter {
+	query := c.Request.URL.Query()
+	var keys []string
+	for key := range query {
+		if strings.HasPrefix(key, attributeFilterPrefix) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	var filters []domain.AttributeFilter
+	for _, key := range keys {
+		name := strings.TrimPrefix(key, attributeFilterPrefix)
+		op := "eq"
+		if base, suffix, ok := strings.Cut(name, "."); ok && (suffix == "gte" || suffix == "lte") {
+			name, op = base, suffix
+		}
+		value := query.Get(key)
+		if name == "" || value == "" {
+			continue
+		}
+		filters = append(filters, domain.AttributeFilter{Name: name, Op: op, Value: value})
+	}
+	return filters
+}
@@ -0,0 +1,341 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/satrunjis/user-service/internal/service"
+)
+
+type AuthHandler struct {
+	authService *service.AuthService
+	logger      *slog.Logger
+}
+
+func NewAuthHandler(authService *service.AuthService, logger *slog.Logger) *AuthHandler {
+	return &AuthHandler{
+		authService: authService,
+		logger:      logger,
+	}
+}
+
+type LoginRequest struct {
+	Login    string `json:"login" binding:"required" example:"john_doe" swagger:"description='Логин пользователя'"`
+	Password string `json:"password" binding:"required" example:"secret123" swagger:"description='Пароль пользователя'"`
+}
+
+type LoginResponse struct {
+	Token       string    `json:"token,omitempty" example:"mJx2...Q"`
+	TokenType   string    `json:"token_type,omitempty" example:"Bearer"`
+	UserID      string    `json:"user_id,omitempty" example:"507f1f77bcf86cd799439011"`
+	MFARequired bool      `json:"mfa_required" example:"false"`
+	MFAToken    string    `json:"mfa_token,omitempty" example:"Vb8k...w"`
+	ExpiresAt   time.Time `json:"expires_at" example:"2024-01-16T12:34:56Z"`
+}
+
+type SecondFactorRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required" example:"Vb8k...w" swagger:"description='Токен первого шага входа'"`
+	Code     string `json:"code" binding:"required" example:"123456" swagger:"description='TOTP-код или код восстановления'"`
+}
+
+type TOTPCodeRequest struct {
+	Code string `json:"code" binding:"required" example:"123456" swagger:"description='TOTP-код или код восстановления'"`
+}
+
+type TOTPEnrollmentResponse struct {
+	Secret string `json:"secret" example:"JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+	URI    string `json:"otpauth_uri" example:"otpauth://totp/UserService:john_doe?secret=JBSWY3DPEHPK3PXP&issuer=UserService"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes" example:"ABCD-EFGH,IJKL-MNOP"`
+}
+
+type UnlockRequest struct {
+	Login string `json:"login,omitempty" example:"john_doe" swagger:"description='Логин для разблокировки'"`
+	IP    string `json:"ip,omitempty" example:"10.0.0.1" swagger:"description='IP-адрес для разблокировки'"`
+}
+
+type UnlockResponse struct {
+	Unlocked bool `json:"unlocked" example:"true"`
+}
+
+// Login godoc
+// @Summary      Вход в систему
+// @Description  Проверка логина и пароля, выдача токена сессии. Если включён второй фактор, вместо сессии возвращается mfa_token для /auth/login/2fa. После серии неудачных попыток включается экспоненциальная задержка, затем временная блокировка
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        body  body      LoginRequest  true  "Учётные данные"
+// @Success      200   {object}  LoginResponse
+// @Failure      400   {object}  ErrorResponse
+// @Failure      401   {object}  ErrorResponse
+// @Failure      429   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Router       /api/v1/auth/login [post]
+func (h *AuthHandler) Login(c *gin.Context) {
+	const op = "AuthHandler.Login"
+	log := h.logger.With("operation", op)
+	ctx := c.Request.Context()
+
+	var req LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.ErrorContext(ctx, "failed to bind JSON", "error", err)
+		c.Error(bindError(err))
+		return
+	}
+
+	result, err := h.authService.Login(ctx, req.Login, req.Password, clientInfo(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, loginResponse(result))
+}
+
+// LoginSecondFactor godoc
+// @Summary      Второй шаг входа
+// @Description  Проверка TOTP-кода или кода восстановления для пользователей с включённой двухфакторной аутентификацией
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        body  body      SecondFactorRequest  true  "Токен первого шага и код"
+// @Success      200   {object}  LoginResponse
+// @Failure      400   {object}  ErrorResponse
+// @Failure      401   {object}  ErrorResponse
+// @Failure      429   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Router       /api/v1/auth/login/2fa [post]
+func (h *AuthHandler) LoginSecondFactor(c *gin.Context) {
+	const op = "AuthHandler.LoginSecondFactor"
+	log := h.logger.With("operation", op)
+	ctx := c.Request.Context()
+
+	var req SecondFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.ErrorContext(ctx, "failed to bind JSON", "error", err)
+		c.Error(bindError(err))
+		return
+	}
+
+	result, err := h.authService.CompleteLogin(ctx, req.MFAToken, req.Code, clientInfo(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, loginResponse(result))
+}
+
+// BeginTOTP godoc
+// @Summary      Начать подключение TOTP
+// @Description  Генерирует секрет и otpauth-ссылку для приложения-аутентификатора. Второй фактор включается после подтверждения кодом
+// @Tags         two-factor
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Param        id   path      string  true  "User ID"
+// @Success      200  {object}  TOTPEnrollmentResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /api/v1/users/{id}/2fa/totp [post]
+func (h *AuthHandler) BeginTOTP(c *gin.Context) {
+	id := c.Param("id")
+	enrollment, err := h.authService.BeginTOTPEnrollment(c.Request.Context(), &id)
+	if err != nil {
+		h.logger.Error("Failed to begin TOTP enrollment", "err", err)
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, TOTPEnrollmentResponse{Secret: enrollment.Secret, URI: enrollment.URI})
+}
+
+// ConfirmTOTP godoc
+// @Summary      Подтвердить подключение TOTP
+// @Description  Проверяет первый код из приложения, включает второй фактор и возвращает коды восстановления (показываются один раз)
+// @Tags         two-factor
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Param        id    path      string           true  "User ID"
+// @Param        body  body      TOTPCodeRequest  true  "Код из приложения"
+// @Success      200   {object}  RecoveryCodesResponse
+// @Failure      400   {object}  ErrorResponse
+// @Failure      404   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Router       /api/v1/users/{id}/2fa/totp/confirm [post]
+func (h *AuthHandler) ConfirmTOTP(c *gin.Context) {
+	id := c.Param("id")
+	req, ok := h.bindCode(c)
+	if !ok {
+		return
+	}
+
+	codes, err := h.authService.ConfirmTOTPEnrollment(c.Request.Context(), &id, req.Code)
+	if err != nil {
+		h.logger.Error("Failed to confirm TOTP enrollment", "err", err)
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableTOTP godoc
+// @Summary      Отключить TOTP
+// @Description  Отключает двухфакторную аутентификацию после проверки текущего кода или кода восстановления
+// @Tags         two-factor
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Param        id    path  string           true  "User ID"
+// @Param        body  body  TOTPCodeRequest  true  "Текущий код"
+// @Success      204
+// @Failure      400   {object}  ErrorResponse
+// @Failure      404   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Router       /api/v1/users/{id}/2fa/totp [delete]
+func (h *AuthHandler) DisableTOTP(c *gin.Context) {
+	id := c.Param("id")
+	req, ok := h.bindCode(c)
+	if !ok {
+		return
+	}
+
+	if err := h.authService.DisableTOTP(c.Request.Context(), &id, req.Code); err != nil {
+		h.logger.Error("Failed to disable TOTP", "err", err)
+		c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary      Перевыпустить коды восстановления
+// @Description  Заменяет все коды восстановления новыми, старые перестают действовать
+// @Tags         two-factor
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Param        id    path      string           true  "User ID"
+// @Param        body  body      TOTPCodeRequest  true  "Текущий код"
+// @Success      200   {object}  RecoveryCodesResponse
+// @Failure      400   {object}  ErrorResponse
+// @Failure      404   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Router       /api/v1/users/{id}/2fa/recovery-codes [post]
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	id := c.Param("id")
+	req, ok := h.bindCode(c)
+	if !ok {
+		return
+	}
+
+	codes, err := h.authService.RegenerateRecoveryCodes(c.Request.Context(), &id, req.Code)
+	if err != nil {
+		h.logger.Error("Failed to regenerate recovery codes", "err", err)
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+func (h *AuthHandler) bindCode(c *gin.Context) (*TOTPCodeRequest, bool) {
+	var req TOTPCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Failed to bind JSON", "err", err)
+		c.Error(bindError(err))
+		return nil, false
+	}
+	return &req, true
+}
+
+func loginResponse(result *service.LoginResult) LoginResponse {
+	if result.MFARequired {
+		return LoginResponse{
+			MFARequired: true,
+			MFAToken:    result.MFAToken,
+			ExpiresAt:   result.MFAExpiresAt,
+		}
+	}
+	return LoginResponse{
+		Token:     result.Token,
+		TokenType: "Bearer",
+		UserID:    result.Session.UserID,
+		ExpiresAt: result.Session.ExpiresAt,
+	}
+}
+
+// Logout godoc
+// @Summary      Выход из системы
+// @Description  Завершает текущую сессию
+// @Tags         auth
+// @Produce      json
+// @Param        Authorization  header  string  true  "Bearer <token>"
+// @Success      204
+// @Failure      401  {object}  ErrorResponse
+// @Router       /api/v1/auth/logout [post]
+func (h *AuthHandler) Logout(c *gin.Context) {
+	if err := h.authService.Logout(c.Request.Context(), bearerToken(c)); err != nil {
+		c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Unlock godoc
+// @Summary      Снять блокировку входа
+// @Description  Снимает блокировку и сбрасывает счётчики неудачных попыток для логина и/или IP-адреса
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Param        body  body      UnlockRequest  true  "Логин и/или IP"
+// @Success      200   {object}  UnlockResponse
+// @Failure      400   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Router       /api/v1/admin/lockouts/unlock [post]
+func (h *AuthHandler) Unlock(c *gin.Context) {
+	const op = "AuthHandler.Unlock"
+	log := h.logger.With("operation", op)
+	ctx := c.Request.Context()
+
+	var req UnlockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.ErrorContext(ctx, "failed to bind JSON", "error", err)
+		c.Error(bindError(err))
+		return
+	}
+
+	unlocked, err := h.authService.Unlock(ctx, req.Login, req.IP)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	log.InfoContext(ctx, "unlock requested", "login", req.Login, "ip", req.IP, "unlocked", unlocked)
+	c.JSON(http.StatusOK, UnlockResponse{Unlocked: unlocked})
+}
+
+func clientInfo(c *gin.Context) service.ClientInfo {
+	return service.ClientInfo{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
+
+func bearerToken(c *gin.Context) string {
+	header := c.GetHeader("Authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/satrunjis/user-service/internal/service"
+)
+
+// CheckLoginAvailability godoc
+// @Summary      Проверить доступность логина
+// @Description  Проверяет формат логина и то, что он не занят. Логины сравниваются без учёта регистра и визуально похожих символов
+// @Tags         users
+// @Produce      json
+// @Param        login  query     string  true  "Логин"
+// @Success      200    {object}  domain.LoginAvailability
+// @Failure      400    {object}  ErrorResponse
+// @Failure      429    {object}  ErrorResponse
+// @Failure      500    {object}  ErrorResponse
+// @Router       /api/v1/users/availability [get]
+func (h *UserHandler) CheckLoginAvailability(c *gin.Context) {
+	login := c.Query("login")
+	if login == "" {
+		c.Error(service.NewValidationError(service.FieldError{
+			Field:   "login",
+			Code:    service.ValidationRequired,
+			Message: "login query parameter is required",
+		}))
+		return
+	}
+
+	result, err := h.userService.CheckLoginAvailability(c.Request.Context(), login)
+	if err != nil {
+		h.logger.ErrorContext(c.Request.Context(), "failed to check login availability", "error", err)
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
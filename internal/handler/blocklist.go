@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/satrunjis/user-service/internal/domain"
+)
+
+type CreateLoginBlockRuleRequest struct {
+	Kind    string  `json:"kind" binding:"required" example:"reserved" swagger:"description='reserved - имя целиком, pattern - регулярное выражение'"`
+	Value   string  `json:"value" binding:"required" example:"admin" swagger:"description='Имя или шаблон'"`
+	Comment *string `json:"comment,omitempty" example:"системная учётная запись" swagger:"description='Комментарий'"`
+}
+
+type LoginBlockRuleListResponse struct {
+	Rules []*domain.LoginBlockRule `json:"rules"`
+	Total int                      `json:"total"`
+}
+
+// CreateLoginBlockRule godoc
+// @Summary      Добавить запрет логина
+// @Description  Добавляет зарезервированное имя или шаблон запрещённых слов. Проверяются логин и имя пользователя после нормализации (регистр, похожие символы)
+// @Tags         login-blocklist
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        body  body      CreateLoginBlockRuleRequest  true  "Правило"
+// @Success      201   {object}  domain.LoginBlockRule
+// @Failure      400   {object}  ErrorResponse
+// @Failure      401   {object}  ErrorResponse
+// @Failure      403   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Router       /api/v1/admin/logins/blocklist [post]
+func (h *UserHandler) CreateLoginBlockRule(c *gin.Context) {
+	const op = "UserHandler.CreateLoginBlockRule"
+	log := h.logger.With("operation", op)
+	ctx := c.Request.Context()
+
+	var req CreateLoginBlockRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.ErrorContext(ctx, "failed to bind JSON", "error", err)
+		c.Error(bindError(err))
+		return
+	}
+
+	rule, err := h.userService.AddLoginBlockRule(ctx, req.Kind, req.Value, req.Comment)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to add login block rule", "error", err)
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusCreated, rule)
+}
+
+// ListLoginBlockRules godoc
+// @Summary      Список запретов логинов
+// @Tags         login-blocklist
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Success      200  {object}  LoginBlockRuleListResponse
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /api/v1/admin/logins/blocklist [get]
+func (h *UserHandler) ListLoginBlockRules(c *gin.Context) {
+	rules, err := h.userService.ListLoginBlockRules(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to list login block rules", "err", err)
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, LoginBlockRuleListResponse{Rules: rules, Total: len(rules)})
+}
+
+// DeleteLoginBlockRule godoc
+// @Summary      Удалить запрет логина
+// @Description  Уже созданных пользователей удаление не затрагивает
+// @Tags         login-blocklist
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id   path  string  true  "Rule ID"
+// @Success      204
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Router       /api/v1/admin/logins/blocklist/{id} [delete]
+func (h *UserHandler) DeleteLoginBlockRule(c *gin.Context) {
+	if err := h.userService.DeleteLoginBlockRule(c.Request.Context(), c.Param("id")); err != nil {
+		h.logger.Error("Failed to delete login block rule", "err", err)
+		c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
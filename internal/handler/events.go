@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/satrunjis/user-service/internal/service"
+)
+
+// Клиент EventSource переподключается через это время, если соединение оборвалось
+const sseRetry = 3000
+
+type EventStreamHandler struct {
+	streamService *service.EventStreamService
+	logger        *slog.Logger
+}
+
+func NewEventStreamHandler(streamService *service.EventStreamService, logger *slog.Logger) *EventStreamHandler {
+	return &EventStreamHandler{
+		streamService: streamService,
+		logger:        logger,
+	}
+}
+
+// StreamUserEvents godoc
+// @Summary      Поток событий пользователей
+// @Description  Server-Sent Events: каждое событие приходит с id, типом в поле event и domain.UserEvent в data. Для возобновления после разрыва передайте id последнего события в заголовке Last-Event-ID (EventSource делает это сам) или параметре last_event_id. Хранятся только последние события, более ранние пропускаются. Раз в несколько секунд отправляется комментарий для поддержания соединения
+// @Tags         users
+// @Produce      text/event-stream
+// @Security     ApiKeyAuth
+// @Param        user_id        query     string  false  "ID пользователей через запятую"
+// @Param        type           query     string  false  "Типы событий через запятую"  example(user.created,user.location_changed)
+// @Param        bbox           query     string  false  "Область minLon,minLat,maxLon,maxLat: текущая или прежняя геолокация пользователя в ней"  example(30.1,59.8,30.6,60.1)
+// @Param        last_event_id  query     string  false  "ID последнего полученного события"
+// @Param        Last-Event-ID  header    string  false  "ID последнего полученного события"
+// @Success      200            {object}  domain.UserEvent
+// @Failure      400            {object}  ErrorResponse
+// @Failure      401            {object}  ErrorResponse
+// @Failure      403            {object}  ErrorResponse
+// @Failure      503            {object}  ErrorResponse
+// @Router       /api/v1/users/events [get]
+func (h *EventStreamHandler) StreamUserEvents(c *gin.Context) {
+	const op = "EventStreamHandler.StreamUserEvents"
+	log := h.logger.With("operation", op)
+	ctx := c.Request.Context()
+
+	filter, err := service.NewEventFilter(splitList(c.Query("user_id")), splitList(c.Query("type")), c.Query("bbox"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+
+	events, err := h.streamService.Subscribe(ctx, lastEventID, filter)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to subscribe to event stream", "error", err)
+		c.Error(err)
+		return
+	}
+
+	// Поток живёт дольше WriteTimeout сервера
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		log.WarnContext(ctx, "failed to clear write deadline", "error", err)
+	}
+
+	c.Header("Content-Type", sse.ContentType)
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// Запрещает буферизацию ответа в nginx
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.WriteString("retry: " + strconv.Itoa(sseRetry) + "\n\n")
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(h.streamService.Heartbeat())
+	defer heartbeat.Stop()
+
+	sent := 0
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				log.DebugContext(ctx, "event stream closed", "sent", sent)
+				return
+			}
+			c.Render(-1, sse.Event{Id: event.ID, Event: event.Event.Type, Data: event.Event})
+			c.Writer.Flush()
+			sent++
+		case <-heartbeat.C:
+			if _, err := c.Writer.WriteString(": ping\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case <-ctx.Done():
+			log.DebugContext(ctx, "event stream client disconnected", "sent", sent)
+			return
+		}
+	}
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -24,27 +24,35 @@ func NewUserHandler(userService *service.UserService, logger *slog.Logger) *User
 
 // GetUsers godoc
 // @Summary      Поиск и фильтрация пользователей
-// @Description  Полнотекстовый поиск с фильтрацией и сортировкой
+// @Description  Полнотекстовый поиск с фильтрацией и сортировкой.
+// @Description  Без фильтра status пользователи со статусом banned и deleted не возвращаются.
+// @Description  По атрибутам: attr.<name>=значение, для чисел и дат также attr.<name>.gte и attr.<name>.lte
+// @Description  Зашифрованные поля (см. /api/v1/users/encrypted-fields) в полнотекстовом поиске не участвуют, comment в режиме exact находится только по точному совпадению q, при зашифрованной location поиск по радиусу недоступен
 // @Tags         users
 // @Accept       json
 // @Produce      json
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
 // @Param   filters query domain.UserFilter false "Фильтры"
 // @Success      200         {object}  UserListResponse
 // @Failure      500         {object}  ErrorResponse
 // @Router       /api/v1/users [get]
 func (h *UserHandler) GetUsers(c *gin.Context) {
 	filters := domain.UserFilter{
-		Search:     strPtr(c.Query("q")),
-		DateFrom:   parseTimePtr(c.Query("date_from")),
-		DateTo:     parseTimePtr(c.Query("date_to")),
-		Distance:   strPtr(c.Query("radius")),
-		Lat:        parsefloatPtr(c.Query("lat")),
-		Lon:        parsefloatPtr(c.Query("lon")),
-		SocialType: strPtr(c.Query("social_net")),
-		SortBy:     strPtr(c.Query("sort_by")),
-		SortOrder:  strPtr(c.Query("sort_order")),
-		Page:       parseIntPtr(c.Query("page")),
-		Size:       parseIntPtr(c.Query("size")),
+		Search:       strPtr(c.Query("q")),
+		DateFrom:     parseTimePtr(c.Query("date_from")),
+		DateTo:       parseTimePtr(c.Query("date_to")),
+		Distance:     strPtr(c.Query("radius")),
+		Lat:          parsefloatPtr(c.Query("lat")),
+		Lon:          parsefloatPtr(c.Query("lon")),
+		SocialType:   strPtr(c.Query("social_net")),
+		SocialHandle: strPtr(c.Query("social_handle")),
+		SortBy:       strPtr(c.Query("sort_by")),
+		SortOrder:    strPtr(c.Query("sort_order")),
+		Page:         parseIntPtr(c.Query("page")),
+		Size:         parseIntPtr(c.Query("size")),
+		Status:       c.QueryArray("status"),
+		Attributes:   parseAttributeFilters(c),
 	}
 
 	users, err := h.userService.SearchUsers(c.Request.Context(), &filters)
@@ -71,6 +79,8 @@ func (h *UserHandler) GetUsers(c *gin.Context) {
 // @Tags         users
 // @Accept       json
 // @Produce      json
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
 // @Param        user  body  domain.User  false  "Данные пользователя"
 // @Success      201   {object}  UserID
 // @Failure      400   {object}  ErrorResponse
@@ -87,10 +97,7 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 	var user domain.User
 	if err := c.ShouldBindJSON(&user); err != nil {
 		log.ErrorContext(ctx, "failed to bind JSON", "error", err)
-		c.Error(&service.ServiceError{
-			Code:    service.ErrCodeInvalidInput,
-			Message: "Invalid request payload: " + err.Error(),
-		})
+		c.Error(bindError(err))
 		return
 	}
 
@@ -111,22 +118,45 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 
 // GetUser godoc
 // @Summary      Получить пользователя
-// @Description  Получить пользователя по ID
+// @Description  Получить пользователя по ID. С параметром as_of возвращает состояние на указанный момент, восстановленное по журналу изменений
 // @Tags         users
 // @Accept       json
 // @Produce      json
-// @Param        id   path      string  true  "User ID"
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Param        id     path      string  true   "User ID"
+// @Param        as_of  query     string  false  "Момент времени (RFC3339)"  format(date-time)
 // @Success      200  {object}  domain.User
+// @Header       200  {string}  ETag  "Версия пользователя для заголовка If-Match"
+// @Failure      400  {object}  ErrorResponse
 // @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
 // @Router       /api/v1/users/{id} [get]
 func (h *UserHandler) GetUser(c *gin.Context) {
 	id := c.Param("id")
-	user, err := h.userService.GetUserByID(c.Request.Context(), &id)
+
+	var user *domain.User
+	var err error
+	if asOf := c.Query("as_of"); asOf != "" {
+		at, parseErr := time.Parse(time.RFC3339, asOf)
+		if parseErr != nil {
+			c.Error(service.NewValidationError(service.FieldError{
+				Field:   "as_of",
+				Code:    service.ValidationFormat,
+				Message: "as_of must be an RFC3339 timestamp",
+			}))
+			return
+		}
+		user, err = h.userService.GetUserAsOf(c.Request.Context(), &id, at)
+	} else {
+		user, err = h.userService.GetUserByID(c.Request.Context(), &id)
+	}
 	if err != nil {
 		h.logger.Error("Failed to get user", "err", err)
 		c.Error(err)
 		return
 	}
+	setETag(c, user)
 	c.JSON(http.StatusOK, user)
 }
 
@@ -136,17 +166,21 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 // @Tags         users
 // @Accept       json
 // @Produce      json
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
 // @Param        id   path      string        true  "User ID"
 // @Param        user body      UserWithoutID  false  "Обновлённые данные"
+// @Param        If-Match  header  string  false  "ETag из GET: запись выполнится, только если пользователь не менялся"
 // @Success      200  {object}  domain.User
 // @Failure      400  {object}  ErrorResponse
+// @Failure      412  {object}  ErrorResponse
 // @Failure      500  {object}  ErrorResponse
 // @Router       /api/v1/users/{id} [put]
 func (h *UserHandler) UpdateUser(c *gin.Context) {
 	id := c.Param("id")
 	const op = "UserHandler.UpdateUser"
 	log := h.logger.With("operation", op)
-	ctx := c.Request.Context()
+	ctx := ifMatch(c)
 	start := time.Now()
 
 	log.DebugContext(ctx, "start user update")
@@ -154,10 +188,7 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 	var user domain.User
 	if err := c.ShouldBindJSON(&user); err != nil {
 		log.ErrorContext(ctx, "failed to bind JSON", "error", err)
-		c.Error(&service.ServiceError{
-			Code:    service.ErrCodeInvalidInput,
-			Message: "Invalid request payload: " + err.Error(),
-		})
+		c.Error(bindError(err))
 		return
 	}
 
@@ -184,10 +215,14 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 // @Tags         users
 // @Accept       json
 // @Produce      json
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
 // @Param        id   path      string  true  "User ID"
 // @Param        patch body     domain.User  true  "Поля для обновления"
+// @Param        If-Match  header  string  false  "ETag из GET: запись выполнится, только если пользователь не менялся"
 // @Success      200  {object}  UserWithoutID
 // @Failure      400  {object}  ErrorResponse
+// @Failure      412  {object}  ErrorResponse
 // @Failure      500  {object}  ErrorResponse
 // @Router       /api/v1/users/{id} [patch]
 func (h *UserHandler) UpdateUserPartial(c *gin.Context) {
@@ -195,14 +230,11 @@ func (h *UserHandler) UpdateUserPartial(c *gin.Context) {
 	var user domain.User
 	if err := c.ShouldBindJSON(&user); err != nil {
 		h.logger.Error("Failed to bind JSON", "err", err)
-		c.Error(&service.ServiceError{
-			Code:    service.ErrCodeInvalidInput,
-			Message: "Invalid request payload: " + err.Error(),
-		})
+		c.Error(bindError(err))
 		return
 	}
 	user.ID = &id
-	if err := h.userService.UpdatePartial(c.Request.Context(), &user); err != nil {
+	if err := h.userService.UpdatePartial(ifMatch(c), &user); err != nil {
 		h.logger.Error("Failed to update user", "err", err)
 		c.Error(err)
 		return
@@ -213,17 +245,21 @@ func (h *UserHandler) UpdateUserPartial(c *gin.Context) {
 
 // DeleteUser godoc
 // @Summary      Удалить пользователя
-// @Description  Удалить запись о пользователе
+// @Description  Помечает пользователя удалённым. Его можно восстановить, пока не истёк срок хранения, после чего запись удаляется окончательно
 // @Tags         users
 // @Accept       json
 // @Produce      json
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
 // @Param        id   path      string  true  "User ID"
+// @Param        If-Match  header  string  false  "ETag из GET: запись выполнится, только если пользователь не менялся"
 // @Success      204
 // @Failure      404  {object}  ErrorResponse
+// @Failure      412  {object}  ErrorResponse
 // @Router       /api/v1/users/{id} [delete]
 func (h *UserHandler) DeleteUser(c *gin.Context) {
 	id := c.Param("id")
-	err := h.userService.DeleteUser(c.Request.Context(), &id)
+	err := h.userService.DeleteUser(ifMatch(c), &id)
 	if err != nil {
 		h.logger.Error("Failed to delete user", "err", err)
 		c.Error(err)
@@ -232,12 +268,72 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+// RevertUser godoc
+// @Summary      Откатить пользователя к версии
+// @Description  Перезаписывает пользователя состоянием указанной версии из журнала изменений с обычной проверкой данных. Пароль, 2FA, статус и зашифрованные поля не откатываются
+// @Tags         users
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Param        id       path      string  true  "User ID"
+// @Param        version  query     int     true  "Версия из истории изменений"
+// @Success      200  {object}  domain.User
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Router       /api/v1/users/{id}/revert [post]
+func (h *UserHandler) RevertUser(c *gin.Context) {
+	id := c.Param("id")
+	version, err := strconv.ParseInt(c.Query("version"), 10, 64)
+	if err != nil {
+		c.Error(service.NewValidationError(service.FieldError{
+			Field:   "version",
+			Code:    service.ValidationRequired,
+			Message: "version must be a positive number",
+		}))
+		return
+	}
+
+	user, err := h.userService.RevertUser(c.Request.Context(), &id, version)
+	if err != nil {
+		h.logger.Error("Failed to revert user", "err", err)
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+// RestoreUser godoc
+// @Summary      Восстановить пользователя
+// @Description  Восстанавливает удалённого пользователя со статусом, который был до удаления
+// @Tags         users
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Param        id   path      string  true  "User ID"
+// @Success      200  {object}  domain.User
+// @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Router       /api/v1/users/{id}/restore [post]
+func (h *UserHandler) RestoreUser(c *gin.Context) {
+	id := c.Param("id")
+	user, err := h.userService.RestoreUser(c.Request.Context(), &id)
+	if err != nil {
+		h.logger.Error("Failed to restore user", "err", err)
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
 // GetUserMap godoc
 // @Summary      Получить карту пользователя
 // @Description  Получить PNG-карту местоположения пользователя
 // @Tags         users
 // @Accept       json
 // @Produce      image/png
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
 // @Param        id   path      string  true  "User ID"
 // @Success      200  {file}   body  "PNG изображение"
 // @Failure      404  {object}   ErrorResponse
@@ -253,10 +349,7 @@ func (h *UserHandler) GetUserMap(c *gin.Context) {
 		return
 	}
 	if maptile == nil {
-		c.Error(&service.ServiceError{
-			Code:    service.ErrCodeInternal,
-			Message: "Empty map data",
-		})
+		c.Error(service.NewMessageError(service.ErrCodeInternal, service.MsgMapEmpty))
 		return
 	}
 	c.Data(http.StatusOK, "image/png", *maptile)
@@ -273,7 +366,13 @@ type UserID struct {
 }
 
 type ErrorResponse struct {
-	Error string `json:"error"`
+	Error ErrorBody `json:"error"`
+}
+
+type ErrorBody struct {
+	Code    string               `json:"code" example:"INVALID_INPUT"`
+	Message string               `json:"message" example:"login must be 5-20 characters"`
+	Details []service.FieldError `json:"details,omitempty"`
 }
 
 func strPtr(s string) *string {
@@ -319,10 +418,12 @@ func parsefloatPtr(s string) *float64 {
 type UserWithoutID struct {
 	Login       *string    `form:"login" json:"login,omitempty" example:"john_doe" swagger:"description='Логин пользователя'"`
 	Username    *string    `form:"username" json:"username,omitempty" example:"John Doe" swagger:"description='Имя пользователя'"`
-	Password    *string    `form:"password" json:"password,omitempty" example:"secret123" swagger:"description='Пароль пользователя '"`
+	Email       *string    `form:"email" json:"email,omitempty" example:"john@example.com" swagger:"description='Электронная почта для уведомлений'"`
 	Description *string    `form:"description" json:"description,omitempty" example:"Программист из Санкт-Петербурга" swagger:"description='Описание пользователя'"`
 	Comment     *string    `form:"comment" json:"comment,omitempty" example:"Важный клиент" swagger:"description='Комментарии о пользователе (заметка админа)'"`
 	RegDate     *time.Time `form:"reg_date" json:"reg_date,omitempty" example:"2023-01-15T12:34:56Z" swagger:"description='Дата регистрации'"`
 	Location    *domain.Location  `form:"location" json:"location,omitempty" swagger:"description='Геолокация пользователя'"`
-	SocialNet   *string    `form:"social_net" json:"social_net,omitempty" example:"MAX" swagger:"description='Название соц. сети, строго определенное'"`
+	SocialNet   *string    `form:"social_net" json:"social_net,omitempty" example:"MAX" swagger:"description='Устарело, используйте social_profiles'"`
+	SocialProfiles []domain.SocialProfile `form:"-" json:"social_profiles,omitempty" swagger:"description='Профили в соц. сетях'"`
+	Attributes     map[string]any         `form:"-" json:"attributes,omitempty" swagger:"description='Дополнительные атрибуты по схеме'"`
 }
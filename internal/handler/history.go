@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetUserHistory godoc
+// @Summary      История изменений пользователя
+// @Description  Журнал изменений от новых записей к старым: кто, когда и какие поля изменил. Значения пароля и секретов 2FA скрыты
+// @Tags         users
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Param        id    path      string  true   "User ID"
+// @Param        page  query     int     false  "Номер страницы"  default(1)
+// @Param        size  query     int     false  "Записей на странице (до 100)"  default(20)
+// @Success      200   {object}  domain.AuditPage
+// @Failure      400   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Router       /api/v1/users/{id}/history [get]
+func (h *UserHandler) GetUserHistory(c *gin.Context) {
+	id := c.Param("id")
+	page, size := 0, 0
+	if p := parseIntPtr(c.Query("page")); p != nil {
+		page = *p
+	}
+	if s := parseIntPtr(c.Query("size")); s != nil {
+		size = *s
+	}
+
+	history, err := h.userService.UserHistory(c.Request.Context(), &id, page, size)
+	if err != nil {
+		h.logger.Error("Failed to get user history", "err", err)
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, history)
+}
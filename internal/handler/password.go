@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password" binding:"required" example:"secret123" swagger:"description='Текущий пароль'"`
+	NewPassword string `json:"new_password" binding:"required" example:"newSecret456" swagger:"description='Новый пароль'"`
+}
+
+type PasswordResetRequest struct {
+	Login string `json:"login" binding:"required" example:"john_doe" swagger:"description='Логин пользователя'"`
+}
+
+type PasswordResetConfirmRequest struct {
+	Token       string `json:"token" binding:"required" example:"mJx2...Q" swagger:"description='Токен из уведомления'"`
+	NewPassword string `json:"new_password" binding:"required" example:"newSecret456" swagger:"description='Новый пароль'"`
+}
+
+// ChangePassword godoc
+// @Summary      Сменить пароль
+// @Description  Смена пароля пользователя с проверкой текущего пароля
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Param        id    path  string                 true  "User ID"
+// @Param        body  body  ChangePasswordRequest  true  "Текущий и новый пароль"
+// @Success      204
+// @Failure      400   {object}  ErrorResponse
+// @Failure      404   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Router       /api/v1/users/{id}/password [post]
+func (h *UserHandler) ChangePassword(c *gin.Context) {
+	const op = "UserHandler.ChangePassword"
+	log := h.logger.With("operation", op)
+	ctx := c.Request.Context()
+	id := c.Param("id")
+
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.ErrorContext(ctx, "failed to bind JSON", "error", err)
+		c.Error(bindError(err))
+		return
+	}
+
+	if err := h.userService.ChangePassword(ctx, &id, req.OldPassword, req.NewPassword); err != nil {
+		log.ErrorContext(ctx, "failed to change password", "error", err, "user_id", id)
+		c.Error(err)
+		return
+	}
+
+	log.InfoContext(ctx, "password changed", "user_id", id)
+	c.Status(http.StatusNoContent)
+}
+
+// RequestPasswordReset godoc
+// @Summary      Запросить сброс пароля
+// @Description  Отправляет одноразовый токен сброса через настроенный канал уведомлений. Ответ не зависит от существования логина
+// @Tags         password
+// @Accept       json
+// @Produce      json
+// @Param        body  body  PasswordResetRequest  true  "Логин пользователя"
+// @Success      202
+// @Failure      400   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Router       /api/v1/password-reset [post]
+func (h *UserHandler) RequestPasswordReset(c *gin.Context) {
+	const op = "UserHandler.RequestPasswordReset"
+	log := h.logger.With("operation", op)
+	ctx := c.Request.Context()
+
+	var req PasswordResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.ErrorContext(ctx, "failed to bind JSON", "error", err)
+		c.Error(bindError(err))
+		return
+	}
+
+	if err := h.userService.RequestPasswordReset(ctx, req.Login); err != nil {
+		log.ErrorContext(ctx, "failed to request password reset", "error", err)
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+// ConfirmPasswordReset godoc
+// @Summary      Подтвердить сброс пароля
+// @Description  Устанавливает новый пароль по одноразовому токену сброса
+// @Tags         password
+// @Accept       json
+// @Produce      json
+// @Param        body  body  PasswordResetConfirmRequest  true  "Токен и новый пароль"
+// @Success      204
+// @Failure      400   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Router       /api/v1/password-reset/confirm [post]
+func (h *UserHandler) ConfirmPasswordReset(c *gin.Context) {
+	const op = "UserHandler.ConfirmPasswordReset"
+	log := h.logger.With("operation", op)
+	ctx := c.Request.Context()
+
+	var req PasswordResetConfirmRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.ErrorContext(ctx, "failed to bind JSON", "error", err)
+		c.Error(bindError(err))
+		return
+	}
+
+	if err := h.userService.ConfirmPasswordReset(ctx, req.Token, req.NewPassword); err != nil {
+		log.ErrorContext(ctx, "failed to confirm password reset", "error", err)
+		c.Error(err)
+		return
+	}
+
+	log.InfoContext(ctx, "password reset confirmed")
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/satrunjis/user-service/internal/domain"
+	"github.com/satrunjis/user-service/internal/service"
+)
+
+type PrivacyHandler struct {
+	privacyService *service.PrivacyService
+	logger         *slog.Logger
+}
+
+func NewPrivacyHandler(privacyService *service.PrivacyService, logger *slog.Logger) *PrivacyHandler {
+	return &PrivacyHandler{
+		privacyService: privacyService,
+		logger:         logger,
+	}
+}
+
+// ExportManifest описание архива выгрузки, файл manifest.json
+type ExportManifest struct {
+	UserID      string   `json:"user_id"`
+	GeneratedAt string   `json:"generated_at"`
+	RequestedBy string   `json:"requested_by"`
+	RequestID   string   `json:"request_id,omitempty"`
+	Files       []string `json:"files"`
+	Notes       []string `json:"notes,omitempty"`
+}
+
+// ExportUser godoc
+// @Summary      Выгрузка данных пользователя
+// @Description  ZIP-архив JSON-файлов со всеми данными о пользователе для ответа на запрос субъекта персональных данных: manifest.json, profile.json, audit.json (журнал изменений), sessions.json, map_tiles.json (закешированные тайлы по текущей и прежним геолокациям), webhook_deliveries.json. Доступна самому пользователю и с правом admin
+// @Tags         users
+// @Produce      application/zip
+// @Security     ApiKeyAuth
+// @Param        id   path      string  true  "User ID"
+// @Success      200  {file}    file
+// @Failure      400  {object}  ErrorResponse
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /api/v1/users/{id}/export [get]
+func (h *PrivacyHandler) ExportUser(c *gin.Context) {
+	const op = "PrivacyHandler.ExportUser"
+	log := h.logger.With("operation", op)
+	ctx := c.Request.Context()
+	id := c.Param("id")
+
+	export, err := h.privacyService.ExportUser(ctx, &id)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to export user", "error", err)
+		c.Error(err)
+		return
+	}
+
+	archive, err := exportArchive(export)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to build export archive", "error", err)
+		c.Error(service.WrapError(service.ErrCodeInternal, err, "Failed to build export archive"))
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="user-`+id+`-export.zip"`)
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "application/zip", archive)
+}
+
+// RequestErasure godoc
+// @Summary      Удаление данных пользователя
+// @Description  Ставит задачу удаления всех данных пользователя по запросу субъекта персональных данных: сессии, закешированные тайлы по его геолокациям, профиль, события пользователя в outbox, доставки вебхуков, журнале потока событий и потоке CDC. Журнал изменений обезличивается: ID заменяется псевдонимом, значения полей стираются. Задача выполняется в фоне, по завершении в ней появляется подписанная квитанция. Требует право admin
+// @Tags         users
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id   path      string  true  "User ID"
+// @Success      202  {object}  domain.ErasureJob
+// @Failure      400  {object}  ErrorResponse
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse  "Для пользователя уже есть незавершённая задача"
+// @Failure      500  {object}  ErrorResponse
+// @Router       /api/v1/users/{id}/erasure [post]
+func (h *PrivacyHandler) RequestErasure(c *gin.Context) {
+	id := c.Param("id")
+	job, err := h.privacyService.RequestErasure(c.Request.Context(), &id)
+	if err != nil {
+		h.logger.Error("Failed to request user erasure", "err", err)
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetErasure godoc
+// @Summary      Задача удаления данных пользователя
+// @Description  Состояние задачи и её шагов. У завершённой задачи есть квитанция, подпись проверяется открытым ключом из неё
+// @Tags         admin
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id   path      string  true  "Erasure job ID"
+// @Success      200  {object}  domain.ErasureJob
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Router       /api/v1/admin/erasures/{id} [get]
+func (h *PrivacyHandler) GetErasure(c *gin.Context) {
+	job, err := h.privacyService.GetErasure(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.logger.Error("Failed to get erasure job", "err", err)
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// ResumeErasure godoc
+// @Summary      Возобновить удаление данных пользователя
+// @Description  Повторяет задачу, остановленную ошибкой, начиная с невыполненного шага
+// @Tags         admin
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id   path      string  true  "Erasure job ID"
+// @Success      202  {object}  domain.ErasureJob
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse  "Задача не завершилась ошибкой"
+// @Router       /api/v1/admin/erasures/{id}/resume [post]
+func (h *PrivacyHandler) ResumeErasure(c *gin.Context) {
+	job, err := h.privacyService.ResumeErasure(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.logger.Error("Failed to resume erasure job", "err", err)
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusAccepted, job)
+}
+
+func exportArchive(export *domain.UserExport) ([]byte, error) {
+	files := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", export.Profile},
+		{"audit.json", export.Audit},
+		{"sessions.json", export.Sessions},
+		{"map_tiles.json", export.MapTiles},
+		{"webhook_deliveries.json", export.WebhookDeliveries},
+	}
+
+	manifest := ExportManifest{
+		UserID:      export.UserID,
+		GeneratedAt: export.GeneratedAt.Format(time.RFC3339),
+		RequestedBy: export.RequestedBy,
+		RequestID:   export.RequestID,
+		Notes:       export.Notes,
+	}
+	for _, f := range files {
+		manifest.Files = append(manifest.Files, f.name)
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	write := func(name string, data any) error {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: export.GeneratedAt})
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(data)
+	}
+
+	if err := write("manifest.json", manifest); err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		if err := write(f.name, f.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
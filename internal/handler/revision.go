@@ -0,0 +1,26 @@
+package handler
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/satrunjis/user-service/internal/domain"
+)
+
+// ifMatch добавляет в контекст версию из заголовка If-Match. "*" не ограничивает запись.
+func ifMatch(c *gin.Context) context.Context {
+	ctx := c.Request.Context()
+	value := strings.TrimSpace(c.GetHeader("If-Match"))
+	if value == "" || value == "*" {
+		return ctx
+	}
+	value = strings.TrimPrefix(value, "W/")
+	return domain.WithIfMatch(ctx, strings.Trim(value, `"`))
+}
+
+func setETag(c *gin.Context, user *domain.User) {
+	if user.Revision != "" {
+		c.Header("ETag", `"`+user.Revision+`"`)
+	}
+}
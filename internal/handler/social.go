@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListSocialNetworks godoc
+// @Summary      Список соц. сетей
+// @Description  Сети, которые можно указать в social_profiles, с шаблоном ника и ссылки на профиль
+// @Tags         users
+// @Produce      json
+// @Success      200  {array}  domain.SocialNetwork
+// @Router       /api/v1/social-networks [get]
+func (h *UserHandler) ListSocialNetworks(c *gin.Context) {
+	c.JSON(http.StatusOK, h.userService.SocialNetworks())
+}
+
+// ListEncryptedFields godoc
+// @Summary      Зашифрованные поля пользователя
+// @Description  Поля, которые хранятся зашифрованными, и как по ним можно искать: none - не участвуют в поиске и фильтрах, exact - только точное совпадение значения. Их значения не попадают в журнал изменений и события
+// @Tags         users
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Success      200  {array}   domain.EncryptedField
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Router       /api/v1/users/encrypted-fields [get]
+func (h *UserHandler) ListEncryptedFields(c *gin.Context) {
+	c.JSON(http.StatusOK, h.userService.EncryptedFields())
+}
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/satrunjis/user-service/internal/domain"
+)
+
+type StatusChangeRequest struct {
+	Reason string `json:"reason" example:"Рассылка спама" swagger:"description='Причина, обязательна для suspend и ban'"`
+}
+
+// ActivateUser godoc
+// @Summary      Активировать пользователя
+// @Description  Переводит пользователя из pending, suspended или banned в active
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Param        id    path      string               true   "User ID"
+// @Param        body  body      StatusChangeRequest  false  "Причина"
+// @Success      200   {object}  domain.User
+// @Failure      400   {object}  ErrorResponse
+// @Failure      404   {object}  ErrorResponse
+// @Failure      409   {object}  ErrorResponse
+// @Router       /api/v1/users/{id}/activate [post]
+func (h *UserHandler) ActivateUser(c *gin.Context) {
+	h.changeStatus(c, domain.StatusActive)
+}
+
+// SuspendUser godoc
+// @Summary      Приостановить пользователя
+// @Description  Временная блокировка: вход запрещён, активные сессии завершаются
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Param        id    path      string               true  "User ID"
+// @Param        body  body      StatusChangeRequest  true  "Причина"
+// @Success      200   {object}  domain.User
+// @Failure      400   {object}  ErrorResponse
+// @Failure      404   {object}  ErrorResponse
+// @Failure      409   {object}  ErrorResponse
+// @Router       /api/v1/users/{id}/suspend [post]
+func (h *UserHandler) SuspendUser(c *gin.Context) {
+	h.changeStatus(c, domain.StatusSuspended)
+}
+
+// BanUser godoc
+// @Summary      Заблокировать пользователя
+// @Description  Вход запрещён, пользователь не показывается в поиске без фильтра status
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Security     BearerAuth
+// @Param        id    path      string               true  "User ID"
+// @Param        body  body      StatusChangeRequest  true  "Причина"
+// @Success      200   {object}  domain.User
+// @Failure      400   {object}  ErrorResponse
+// @Failure      404   {object}  ErrorResponse
+// @Failure      409   {object}  ErrorResponse
+// @Router       /api/v1/users/{id}/ban [post]
+func (h *UserHandler) BanUser(c *gin.Context) {
+	h.changeStatus(c, domain.StatusBanned)
+}
+
+func (h *UserHandler) changeStatus(c *gin.Context, status string) {
+	const op = "UserHandler.changeStatus"
+	log := h.logger.With("operation", op, "status", status)
+	ctx := c.Request.Context()
+	id := c.Param("id")
+
+	var req StatusChangeRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			log.ErrorContext(ctx, "failed to bind JSON", "error", err)
+			c.Error(bindError(err))
+			return
+		}
+	}
+
+	user, err := h.userService.ChangeStatus(ctx, &id, status, req.Reason)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to change user status", "error", err, "user_id", id)
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
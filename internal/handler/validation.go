@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/satrunjis/user-service/internal/service"
+)
+
+// RegisterValidatorFieldNames заставляет валидатор gin называть поля по json-тегам,
+// чтобы в ошибках привязки были те же имена, что и в теле запроса
+func RegisterValidatorFieldNames() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return f.Name
+		}
+		return name
+	})
+}
+
+// bindError переводит ошибку ShouldBindJSON в ошибку валидации с деталями по полям
+func bindError(err error) *service.ServiceError {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		details := make([]service.FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			details = append(details, validatorFieldError(fe))
+		}
+		return service.NewValidationError(details...)
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return service.NewValidationError(service.FieldError{
+			Field:   typeErr.Field,
+			Code:    service.ValidationType,
+			Message: typeErr.Field + " must be " + typeErr.Type.String(),
+			Params:  map[string]any{"expected": typeErr.Type.String(), "actual": typeErr.Value},
+		})
+	}
+
+	if errors.Is(err, io.EOF) {
+		return service.NewValidationError(service.FieldError{
+			Code:    service.ValidationRequired,
+			Message: "request body is required",
+		})
+	}
+
+	return service.NewValidationError(service.FieldError{
+		Code:    service.ValidationFormat,
+		Message: "Invalid request payload: " + err.Error(),
+	})
+}
+
+func validatorFieldError(fe validator.FieldError) service.FieldError {
+	field := fieldPath(fe.Namespace())
+	out := service.FieldError{Field: field, Code: fe.Tag()}
+
+	switch fe.Tag() {
+	case "required":
+		out.Code = service.ValidationRequired
+		out.Message = field + " is required"
+	case "min":
+		out.Code = service.ValidationMinLength
+		out.Params = map[string]any{"min": fe.Param()}
+		out.Message = field + " must be at least " + fe.Param() + " characters"
+	case "max":
+		out.Code = service.ValidationMaxLength
+		out.Params = map[string]any{"max": fe.Param()}
+		out.Message = field + " must be at most " + fe.Param() + " characters"
+	case "len":
+		out.Code = service.ValidationLength
+		out.Params = map[string]any{"min": fe.Param(), "max": fe.Param()}
+		out.Message = field + " must be exactly " + fe.Param() + " characters"
+	case "oneof":
+		out.Code = service.ValidationUnsupported
+		out.Params = map[string]any{"allowed": fe.Param()}
+		out.Message = field + " must be one of: " + fe.Param()
+	default:
+		if fe.Param() != "" {
+			out.Params = map[string]any{"param": fe.Param()}
+		}
+		out.Message = field + " failed " + fe.Tag() + " validation"
+	}
+	return out
+}
+
+// fieldPath убирает имя корневой структуры: "LoginRequest.login" -> "login"
+func fieldPath(namespace string) string {
+	if _, rest, ok := strings.Cut(namespace, "."); ok {
+		return rest
+	}
+	return namespace
+}
@@ -0,0 +1,237 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/satrunjis/user-service/internal/domain"
+	"github.com/satrunjis/user-service/internal/service"
+)
+
+type WebhookHandler struct {
+	webhookService *service.WebhookService
+	logger         *slog.Logger
+}
+
+func NewWebhookHandler(webhookService *service.WebhookService, logger *slog.Logger) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+		logger:         logger,
+	}
+}
+
+type CreateWebhookRequest struct {
+	URL         string   `json:"url" binding:"required" example:"https://crm.example.com/hooks/users" swagger:"description='Адрес получателя (http или https)'"`
+	Events      []string `json:"events,omitempty" example:"user.created,user.patched" swagger:"description='Типы событий, пустой список - все события'"`
+	Secret      *string  `json:"secret,omitempty" example:"my-shared-secret-value" swagger:"description='Ключ подписи (16-256 символов), если не задан - генерируется'"`
+	Description *string  `json:"description,omitempty" example:"синхронизация с CRM" swagger:"description='Описание'"`
+}
+
+type UpdateWebhookRequest struct {
+	URL         *string   `json:"url,omitempty" example:"https://crm.example.com/hooks/users"`
+	Events      *[]string `json:"events,omitempty" example:"user.created,user.deleted"`
+	Description *string   `json:"description,omitempty" example:"синхронизация с CRM"`
+	Active      *bool     `json:"active,omitempty" example:"false"`
+}
+
+type WebhookListResponse struct {
+	Webhooks []*domain.Webhook `json:"webhooks"`
+	Total    int               `json:"total"`
+}
+
+// CreateWebhook godoc
+// @Summary      Создать вебхук
+// @Description  Подписывает внешнюю систему на события пользователей. Запросы подписываются HMAC-SHA256: заголовок X-Webhook-Signature содержит "sha256=<hex>" от строки "<X-Webhook-Timestamp>.<тело>". Ключ подписи показывается только в ответе на создание
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        body  body      CreateWebhookRequest  true  "Подписка"
+// @Success      201   {object}  domain.Webhook
+// @Failure      400   {object}  ErrorResponse
+// @Failure      401   {object}  ErrorResponse
+// @Failure      403   {object}  ErrorResponse
+// @Failure      500   {object}  ErrorResponse
+// @Router       /api/v1/webhooks [post]
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	const op = "WebhookHandler.CreateWebhook"
+	log := h.logger.With("operation", op)
+	ctx := c.Request.Context()
+
+	var req CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.ErrorContext(ctx, "failed to bind JSON", "error", err)
+		c.Error(bindError(err))
+		return
+	}
+
+	webhook, err := h.webhookService.CreateWebhook(ctx, req.URL, req.Events, req.Secret, req.Description)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to create webhook", "error", err)
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusCreated, webhook)
+}
+
+// ListWebhooks godoc
+// @Summary      Список вебхуков
+// @Tags         webhooks
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Success      200  {object}  WebhookListResponse
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /api/v1/webhooks [get]
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	webhooks, err := h.webhookService.ListWebhooks(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to list webhooks", "err", err)
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, WebhookListResponse{Webhooks: webhooks, Total: len(webhooks)})
+}
+
+// GetWebhook godoc
+// @Summary      Получить вебхук
+// @Tags         webhooks
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id   path      string  true  "Webhook ID"
+// @Success      200  {object}  domain.Webhook
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Router       /api/v1/webhooks/{id} [get]
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	webhook, err := h.webhookService.GetWebhook(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.logger.Error("Failed to get webhook", "err", err)
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, webhook)
+}
+
+// UpdateWebhook godoc
+// @Summary      Изменить вебхук
+// @Description  Меняет адрес, события, описание или включает и отключает подписку. Отключённой подписке события не отправляются
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id    path      string                true  "Webhook ID"
+// @Param        body  body      UpdateWebhookRequest  true  "Изменяемые поля"
+// @Success      200   {object}  domain.Webhook
+// @Failure      400   {object}  ErrorResponse
+// @Failure      401   {object}  ErrorResponse
+// @Failure      403   {object}  ErrorResponse
+// @Failure      404   {object}  ErrorResponse
+// @Router       /api/v1/webhooks/{id} [patch]
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	const op = "WebhookHandler.UpdateWebhook"
+	log := h.logger.With("operation", op)
+	ctx := c.Request.Context()
+
+	var req UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.ErrorContext(ctx, "failed to bind JSON", "error", err)
+		c.Error(bindError(err))
+		return
+	}
+
+	webhook, err := h.webhookService.UpdateWebhook(ctx, c.Param("id"), service.WebhookUpdate{
+		URL:         req.URL,
+		Events:      req.Events,
+		Description: req.Description,
+		Active:      req.Active,
+	})
+	if err != nil {
+		log.ErrorContext(ctx, "failed to update webhook", "error", err)
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, webhook)
+}
+
+// DeleteWebhook godoc
+// @Summary      Удалить вебхук
+// @Description  История доставок сохраняется, ожидающие доставки переносятся в недоставленные
+// @Tags         webhooks
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id   path  string  true  "Webhook ID"
+// @Success      204
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Router       /api/v1/webhooks/{id} [delete]
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	if err := h.webhookService.DeleteWebhook(c.Request.Context(), c.Param("id")); err != nil {
+		h.logger.Error("Failed to delete webhook", "err", err)
+		c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListWebhookDeliveries godoc
+// @Summary      История доставок вебхука
+// @Description  Доставки от новых к старым с результатом последней попытки. status=dead - очередь недоставленных
+// @Tags         webhooks
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id      path      string  true   "Webhook ID"
+// @Param        status  query     string  false  "Статус доставки"  Enums(pending, succeeded, failed, dead)
+// @Param        page    query     int     false  "Номер страницы"  default(1)
+// @Param        size    query     int     false  "Записей на странице (до 100)"  default(20)
+// @Success      200     {object}  domain.WebhookDeliveryPage
+// @Failure      400     {object}  ErrorResponse
+// @Failure      401     {object}  ErrorResponse
+// @Failure      403     {object}  ErrorResponse
+// @Failure      404     {object}  ErrorResponse
+// @Router       /api/v1/webhooks/{id}/deliveries [get]
+func (h *WebhookHandler) ListWebhookDeliveries(c *gin.Context) {
+	page, size := 0, 0
+	if p := parseIntPtr(c.Query("page")); p != nil {
+		page = *p
+	}
+	if s := parseIntPtr(c.Query("size")); s != nil {
+		size = *s
+	}
+
+	deliveries, err := h.webhookService.ListDeliveries(c.Request.Context(), c.Param("id"), c.Query("status"), page, size)
+	if err != nil {
+		h.logger.Error("Failed to list webhook deliveries", "err", err)
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// ReplayWebhookDelivery godoc
+// @Summary      Повторить доставку
+// @Description  Ставит в очередь новую доставку с тем же телом события. Доступно для завершённых доставок, в том числе недоставленных
+// @Tags         webhooks
+// @Produce      json
+// @Security     ApiKeyAuth
+// @Param        id           path      string  true  "Webhook ID"
+// @Param        delivery_id  path      string  true  "Delivery ID"
+// @Success      202          {object}  domain.WebhookDelivery
+// @Failure      401          {object}  ErrorResponse
+// @Failure      403          {object}  ErrorResponse
+// @Failure      404          {object}  ErrorResponse
+// @Failure      409          {object}  ErrorResponse
+// @Router       /api/v1/webhooks/{id}/deliveries/{delivery_id}/replay [post]
+func (h *WebhookHandler) ReplayWebhookDelivery(c *gin.Context) {
+	delivery, err := h.webhookService.ReplayDelivery(c.Request.Context(), c.Param("id"), c.Param("delivery_id"))
+	if err != nil {
+		h.logger.Error("Failed to replay webhook delivery", "err", err)
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusAccepted, delivery)
+}
@@ -0,0 +1,176 @@
+package i18n
+
+// codeMessages общий текст для кода ошибки, если у ошибки нет своего сообщения
+var codeMessages = map[Lang]map[string]string{
+	English: {
+		"NOT_FOUND":           "Resource not found",
+		"INVALID_INPUT":       "Invalid request",
+		"ALREADY_EXISTS":      "Resource already exists",
+		"INTERNAL_ERROR":      "Internal server error",
+		"UNAUTHORIZED":        "Authentication required",
+		"RATE_LIMITED":        "Too many requests",
+		"FORBIDDEN":           "Access denied",
+		"UNAVAILABLE":         "Service is temporarily unavailable",
+		"TIMEOUT":             "Request timed out",
+		"CONFLICT":            "Resource was modified by another request",
+		"PRECONDITION_FAILED": "Precondition failed",
+	},
+	Russian: {
+		"NOT_FOUND":           "Ресурс не найден",
+		"INVALID_INPUT":       "Некорректный запрос",
+		"ALREADY_EXISTS":      "Ресурс уже существует",
+		"INTERNAL_ERROR":      "Внутренняя ошибка сервера",
+		"UNAUTHORIZED":        "Требуется аутентификация",
+		"RATE_LIMITED":        "Слишком много запросов",
+		"FORBIDDEN":           "Доступ запрещён",
+		"UNAVAILABLE":         "Сервис временно недоступен",
+		"TIMEOUT":             "Превышено время ожидания",
+		"CONFLICT":            "Ресурс изменён другим запросом",
+		"PRECONDITION_FAILED": "Условие запроса не выполнено",
+	},
+}
+
+// messages переводы сообщений сервиса по ключу (service.Message.Key), {имя} заменяется параметром ошибки
+var messages = map[Lang]map[string]string{
+	Russian: {
+		"user_not_found":               "Пользователь не найден",
+		"user_exists":                  "Пользователь уже существует",
+		"user_modified":                "Пользователь изменён другим запросом",
+		"user_version_mismatch":        "Версия пользователя не совпадает",
+		"user_id_required":             "Не указан ID пользователя",
+		"user_location_required":       "У пользователя не указано местоположение",
+		"database_error":               "Ошибка базы данных",
+		"system_error":                 "Системная ошибка",
+		"unexpected_error":             "Непредвиденная ошибка",
+		"storage_unavailable":          "Хранилище временно недоступно",
+		"storage_timeout":              "Хранилище не ответило вовремя",
+		"request_timeout":              "Превышено время ожидания",
+		"map_unavailable":              "Картографический сервис недоступен",
+		"map_timeout":                  "Картографический сервис не ответил вовремя",
+		"map_failed":                   "Не удалось получить карту",
+		"map_empty":                    "Пустые данные карты",
+		"rate_limited":                 "Превышен лимит запросов",
+		"login_taken":                  "Логин уже занят",
+		"protected_fields":             "Нельзя изменить защищённые поля (дату регистрации)",
+		"password_endpoint":            "Пароль меняется только через смену или сброс пароля",
+		"two_factor_endpoint":          "Настройки двухфакторной аутентификации меняются только через отдельные методы",
+		"status_endpoint":              "Статус меняется только через отдельные методы",
+		"invalid_credentials":          "Неверный логин или пароль",
+		"credentials_required":         "Укажите логин и пароль",
+		"login_required":               "Укажите логин",
+		"unlock_subject_required":      "Укажите логин или IP-адрес",
+		"login_backoff":                "Слишком много неудачных попыток входа, повторите позже",
+		"login_locked":                 "Слишком много неудачных попыток входа, доступ временно заблокирован",
+		"authentication_required":      "Требуется аутентификация",
+		"session_token_required":       "Не передан токен сессии",
+		"session_invalid":              "Сессия недействительна или истекла",
+		"old_password_incorrect":       "Текущий пароль указан неверно",
+		"password_unchanged":           "Новый пароль должен отличаться от текущего",
+		"reset_token_required":         "Не передан токен сброса",
+		"reset_token_invalid":          "Токен сброса недействителен или истёк",
+		"password_hash_failed":         "Не удалось обработать пароль",
+		"reset_token_failed":           "Не удалось создать токен сброса",
+		"two_factor_enabled":           "Двухфакторная аутентификация уже включена",
+		"two_factor_disabled":          "Двухфакторная аутентификация не включена",
+		"two_factor_unavailable":       "Двухфакторная аутентификация не настроена на сервере",
+		"two_factor_not_started":       "Подключение двухфакторной аутентификации не начато",
+		"two_factor_input_required":    "Укажите токен и код подтверждения",
+		"two_factor_challenge_invalid": "Запрос второго фактора недействителен или истёк",
+		"two_factor_code_invalid":      "Неверный код подтверждения",
+		"totp_secret_failed":           "Не удалось создать секрет TOTP",
+		"totp_encrypt_failed":          "Не удалось зашифровать секрет TOTP",
+		"totp_decrypt_failed":          "Не удалось расшифровать секрет TOTP",
+		"recovery_codes_failed":        "Не удалось создать коды восстановления",
+		"session_token_failed":         "Не удалось создать токен сессии",
+		"two_factor_token_failed":      "Не удалось создать токен второго фактора",
+		"api_key_invalid":              "Неверный API-ключ",
+		"api_key_revoked":              "API-ключ отозван",
+		"api_key_expired":              "Срок действия API-ключа истёк",
+		"api_key_not_found":            "API-ключ не найден",
+		"api_key_name":                 "Название API-ключа должно быть от 1 до 100 символов",
+		"api_key_expiration":           "Срок действия API-ключа должен быть в будущем",
+		"scopes_required":              "Укажите хотя бы одно право",
+		"api_key_failed":               "Не удалось создать API-ключ",
+		"version_not_found":            "Версия не найдена",
+		"user_not_existed":             "На указанный момент пользователя не существовало",
+		"history_incomplete":           "История пользователя неполная, восстановить состояние нельзя",
+		"user_not_deleted":             "Пользователь не удалён",
+		"account_suspended":            "Учётная запись приостановлена",
+		"account_banned":               "Учётная запись заблокирована",
+		"block_rule_not_found":         "Правило не найдено",
+		"attribute_mapping_conflict":   "Тип атрибута не совпадает с уже проиндексированными значениями",
+		"block_rule_kind":              "kind должен быть reserved или pattern",
+		"block_rule_value":             "value должно быть от 1 до 200 символов",
+		"webhook_not_found":            "Вебхук не найден",
+		"webhook_exists":               "Вебхук уже существует",
+		"webhook_inactive":             "Вебхук отключён",
+		"webhook_secret_failed":        "Не удалось создать ключ подписи вебхука",
+		"delivery_not_found":           "Доставка вебхука не найдена",
+		"delivery_exists":              "Доставка вебхука уже существует",
+		"delivery_in_progress":         "Доставка ещё выполняется",
+		"erasure_not_found":            "Задача удаления не найдена",
+		"erasure_not_resumable":        "Продолжить можно только задачу удаления, остановленную ошибкой",
+		"erasure_unavailable":          "Удаление пользователей не настроено",
+		"event_stream_closing":         "Поток событий останавливается",
+		"invalid_scan_cursor":          "Неверный курсор обхода",
+		"unknown_scope":                "неизвестное право: {scope}",
+		"missing_scope":                "не хватает права: {scope}",
+		"invalid_pattern":              "некорректный шаблон: {error}",
+		"status_unchanged":             "у пользователя уже статус {status}",
+		"status_transition":            "недопустимая смена статуса: {from} -> {to}",
+		"erasure_in_progress":          "Для этого пользователя уже выполняется задача удаления {job_id}",
+		"attribute_type_changed":       "нельзя изменить тип атрибута: {name} {from} -> {to}",
+	},
+}
+
+// rules шаблоны сообщений валидации по коду правила
+var rules = map[Lang]map[string]string{
+	Russian: {
+		"required":           "{field}: обязательное поле",
+		"length":             "{field}: длина должна быть от {min} до {max} символов",
+		"min_length":         "{field}: не менее {min} символов",
+		"max_length":         "{field}: не более {max} символов",
+		"invalid_characters": "{field}: недопустимые символы (разрешены a-z, A-Z, 0-9, _, -)",
+		"invalid_format":     "{field}: неверный формат",
+		"invalid_type":       "{field}: неверный тип значения, ожидается {expected}",
+		"out_of_range":       "{field}: значение должно быть от {min} до {max}",
+		"min_value":          "{field}: значение должно быть не меньше {min}",
+		"max_value":          "{field}: значение должно быть не больше {max}",
+		"in_future":          "{field}: дата не может быть в будущем",
+		"unsupported_value":  "{field}: недопустимое значение",
+		"reserved":           "{field}: значение зарезервировано",
+		"blocked":            "{field}: содержит запрещённые слова",
+		"taken":              "{field}: уже занят",
+		"too_many_items":     "{field}: не более {max} элементов",
+		"duplicate":          "{field}: значение повторяется",
+		"unknown_field":      "{field}: неизвестное поле",
+	},
+}
+
+// fields названия полей для сообщений
+var fields = map[Lang]map[string]string{
+	Russian: {
+		"id":           "ID",
+		"login":        "Логин",
+		"username":     "Имя пользователя",
+		"password":     "Пароль",
+		"new_password": "Новый пароль",
+		"status":       "Статус",
+		"page":         "Номер страницы",
+		"version":      "Версия",
+		"as_of":        "Момент времени",
+		"reason":       "Причина",
+		"old_password": "Текущий пароль",
+		"email":        "Email",
+		"description":  "Описание",
+		"comment":      "Комментарий",
+		"reg_date":     "Дата регистрации",
+		"location.lat": "Широта",
+		"location.lon": "Долгота",
+		"social_net":   "Социальная сеть",
+
+		"social_profiles":         "Профили в соц. сетях",
+		"social_profiles.network": "Соц. сеть",
+		"social_profiles.handle":  "Ник в соц. сети",
+	},
+}
@@ -0,0 +1,85 @@
+// Package i18n переводит сообщения об ошибках на язык клиента. Коды ошибок не переводятся.
+package i18n
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+type Lang string
+
+const (
+	English Lang = "en"
+	Russian Lang = "ru"
+)
+
+var listIndex = regexp.MustCompile(`\[\d+\]`)
+
+var matcher = language.NewMatcher([]language.Tag{language.English, language.Russian})
+
+// Negotiate выбирает язык по заголовку Accept-Language
+func Negotiate(acceptLanguage string, fallback Lang) Lang {
+	if acceptLanguage == "" {
+		return fallback
+	}
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return fallback
+	}
+	tag, _, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return fallback
+	}
+	base, _ := tag.Base()
+	return Lang(base.String())
+}
+
+// Message переводит сообщение ошибки по ключу из каталога. Пустое сообщение заменяется общим текстом
+// для кода, сообщение без ключа или без перевода остаётся на английском.
+func Message(lang Lang, code, key, message string, params map[string]any) string {
+	if message == "" {
+		if text, ok := codeMessages[lang][code]; ok {
+			return text
+		}
+		return codeMessages[English][code]
+	}
+	if lang == English {
+		return message
+	}
+
+	template, ok := messages[lang][key]
+	if !ok {
+		return message
+	}
+	return substitute(template, params)
+}
+
+// FieldMessage переводит ошибку валидации поля по коду правила
+func FieldMessage(lang Lang, field, rule, message string, params map[string]any) string {
+	if lang == English {
+		return message
+	}
+	template, ok := rules[lang][rule]
+	if !ok {
+		return message
+	}
+
+	name := field
+	// Индексы элементов списка не влияют на название поля: social_profiles[0].handle
+	if display, ok := fields[lang][listIndex.ReplaceAllString(field, "")]; ok {
+		name = display
+	}
+
+	return substitute(template, params, "{field}", name)
+}
+
+// substitute подставляет параметры вместо {имя} в шаблон
+func substitute(template string, params map[string]any, replacements ...string) string {
+	for key, value := range params {
+		replacements = append(replacements, "{"+key+"}", fmt.Sprint(value))
+	}
+	return strings.NewReplacer(replacements...).Replace(template)
+}
@@ -0,0 +1,44 @@
+// Package logins приводит логины к канонической форме для проверки уникальности.
+package logins
+
+import (
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// confusables сводит похожие по начертанию символы к одному латинскому.
+// Таблица покрывает кириллицу и греческий, которые чаще всего используют для подделки логинов,
+// и цифры, которые визуально совпадают с буквами.
+var confusables = map[rune]rune{
+	'0': 'o', '1': 'l', 'i': 'l', '|': 'l',
+	// кириллица
+	'а': 'a', 'в': 'b', 'е': 'e', 'ё': 'e', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o',
+	'р': 'p', 'с': 'c', 'т': 't', 'у': 'y', 'х': 'x', 'і': 'l', 'ї': 'l', 'ј': 'j',
+	'ѕ': 's', 'ԁ': 'd', 'ԛ': 'q', 'ԝ': 'w', 'һ': 'h', 'ӏ': 'l',
+	// греческий
+	'α': 'a', 'β': 'b', 'ε': 'e', 'η': 'n', 'ι': 'l', 'κ': 'k', 'ν': 'v', 'ο': 'o',
+	'ρ': 'p', 'τ': 't', 'υ': 'u', 'χ': 'x', 'ω': 'w',
+	// латиница
+	'ı': 'l', 'ɡ': 'g', 'ɩ': 'l',
+}
+
+// multiRune последовательности, которые при мелком шрифте читаются как одна буква
+var multiRune = strings.NewReplacer("rn", "m", "vv", "w")
+
+// Normalize возвращает каноническую форму логина: NFKC, нижний регистр, свёртка похожих символов.
+// Два логина с одинаковой канонической формой считаются одним и тем же логином.
+func Normalize(login string) string {
+	s := norm.NFKC.String(strings.TrimSpace(login))
+	s = strings.ToLower(s)
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range s {
+		if folded, ok := confusables[r]; ok {
+			r = folded
+		}
+		b.WriteRune(r)
+	}
+	return multiRune.Replace(b.String())
+}
@@ -0,0 +1,41 @@
+package logins
+
+import "regexp/syntax"
+
+// NormalizePattern приводит к канонической форме буквальные части регулярного выражения,
+// чтобы шаблон находил логины после Normalize: "porn" превращается в "pom", класс [i]
+// дополняется буквой l. Последовательности, разбитые операторами (r+n), не сворачиваются.
+func NormalizePattern(pattern string) (string, error) {
+	re, err := syntax.Parse(pattern, syntax.Perl|syntax.FoldCase)
+	if err != nil {
+		return "", err
+	}
+	normalizeRegexp(re)
+	return re.String(), nil
+}
+
+func normalizeRegexp(re *syntax.Regexp) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		re.Rune = []rune(Normalize(string(re.Rune)))
+	case syntax.OpCharClass:
+		for from, to := range confusables {
+			if classContains(re.Rune, from) && !classContains(re.Rune, to) {
+				re.Rune = append(re.Rune, to, to)
+			}
+		}
+	}
+	for _, sub := range re.Sub {
+		normalizeRegexp(sub)
+	}
+}
+
+// classContains проверяет, входит ли r в класс, заданный парами границ диапазонов
+func classContains(ranges []rune, r rune) bool {
+	for i := 0; i+1 < len(ranges); i += 2 {
+		if ranges[i] <= r && r <= ranges[i+1] {
+			return true
+		}
+	}
+	return false
+}
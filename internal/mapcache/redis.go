@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/go-redis/redis/v8"
+	"github.com/satrunjis/user-service/internal/domain"
 	"log/slog"
 	"time"
 )
@@ -54,6 +55,54 @@ func (c *RedisCache) Get(ctx context.Context, key *string) (*[]byte, error) {
 func (c *RedisCache) Set(ctx context.Context, key *string, data *[]byte) error {
 	return c.client.Set(ctx, *key, *data, c.ttl).Err()
 }
+func (c *RedisCache) Client() *redis.Client {
+	return c.client
+}
 func (c *RedisCache) Close() error {
 	return c.client.Close()
 }
+
+// Stat возвращает размер и срок хранения существующих ключей, отсутствующие пропускаются
+func (c *RedisCache) Stat(ctx context.Context, keys []string) ([]domain.CacheEntry, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	sizes := make([]*redis.IntCmd, len(keys))
+	ttls := make([]*redis.DurationCmd, len(keys))
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			sizes[i] = pipe.StrLen(ctx, key)
+			ttls[i] = pipe.PTTL(ctx, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("mapcache.Stat: %w", err)
+	}
+
+	var entries []domain.CacheEntry
+	now := time.Now().UTC()
+	for i, key := range keys {
+		if sizes[i].Val() == 0 {
+			continue
+		}
+		entry := domain.CacheEntry{Key: key, Size: sizes[i].Val()}
+		if ttl := ttls[i].Val(); ttl > 0 {
+			expiresAt := now.Add(ttl)
+			entry.ExpiresAt = &expiresAt
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (c *RedisCache) Delete(ctx context.Context, keys []string) (int64, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	deleted, err := c.client.Del(ctx, keys...).Result()
+	if err != nil {
+		return 0, fmt.Errorf("mapcache.Delete: %w", err)
+	}
+	return deleted, nil
+}
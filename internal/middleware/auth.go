@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/satrunjis/user-service/internal/domain"
+	"github.com/satrunjis/user-service/internal/service"
+)
+
+const principalKey = "principal"
+
+type Authenticator interface {
+	Authenticate(ctx context.Context, apiKey, bearer string) (*domain.Principal, error)
+}
+
+// Authenticate принимает ключ в заголовке X-API-Key (или "Authorization: ApiKey <key>")
+// либо токен сессии в "Authorization: Bearer <token>"
+func Authenticate(auth Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apiKey, bearer := credentials(c)
+
+		principal, err := auth.Authenticate(c.Request.Context(), apiKey, bearer)
+		if err != nil {
+			c.Error(err)
+			c.Abort()
+			return
+		}
+
+		c.Set(principalKey, principal)
+		c.Request = c.Request.WithContext(domain.WithPrincipal(c.Request.Context(), principal))
+		c.Next()
+	}
+}
+
+// RequireScope пропускает запрос, если у вызывающего есть все перечисленные права
+func RequireScope(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := GetPrincipal(c)
+		for _, scope := range scopes {
+			if !principal.HasScope(scope) {
+				c.Error(service.NewMessageError(service.ErrCodeForbidden, service.MsgMissingScope, "scope", scope))
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// RequireSelfOrScope пропускает пользователя, действующего над своей учётной записью (:id),
+// или вызывающего с указанным правом
+func RequireSelfOrScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := GetPrincipal(c)
+		if principal != nil && principal.Type == domain.PrincipalSession && principal.UserID == c.Param("id") {
+			c.Next()
+			return
+		}
+		if !principal.HasScope(scope) {
+			c.Error(service.NewMessageError(service.ErrCodeForbidden, service.MsgMissingScope, "scope", scope))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func GetPrincipal(c *gin.Context) *domain.Principal {
+	if v, ok := c.Get(principalKey); ok {
+		if p, ok := v.(*domain.Principal); ok {
+			return p
+		}
+	}
+	return nil
+}
+
+func credentials(c *gin.Context) (apiKey, bearer string) {
+	if key := c.GetHeader("X-API-Key"); key != "" {
+		return strings.TrimSpace(key), ""
+	}
+	scheme, value, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !ok {
+		return "", ""
+	}
+	switch strings.ToLower(scheme) {
+	case "apikey":
+		return strings.TrimSpace(value), ""
+	case "bearer":
+		return "", strings.TrimSpace(value)
+	}
+	return "", ""
+}
@@ -1,7 +1,11 @@
 package middleware
 
 import (
+	"context"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -15,10 +19,10 @@ func ErrorHandler() gin.HandlerFunc {
 		if len(c.Errors) > 0 && !c.Writer.Written() {
 			err := c.Errors.Last().Err
 
-			switch e := err.(type) {
-			case *service.ServiceError:
-				handleServiceError(c, e)
-			default:
+			var serviceErr *service.ServiceError
+			if errors.As(err, &serviceErr) {
+				handleServiceError(c, serviceErr)
+			} else {
 				handleUnexpectedError(c, err)
 			}
 		}
@@ -35,32 +39,47 @@ func handleServiceError(c *gin.Context, err *service.ServiceError) {
 		status = http.StatusBadRequest
 	case service.ErrCodeAlreadyExists:
 		status = http.StatusConflict
+	case service.ErrCodeUnauthorized:
+		status = http.StatusUnauthorized
+	case service.ErrCodeForbidden:
+		status = http.StatusForbidden
+	case service.ErrCodeRateLimited:
+		status = http.StatusTooManyRequests
+	case service.ErrCodeConflict:
+		status = http.StatusConflict
+	case service.ErrCodePreconditionFailed:
+		status = http.StatusPreconditionFailed
+	case service.ErrCodeUnavailable:
+		status = http.StatusServiceUnavailable
+	case service.ErrCodeTimeout:
+		status = http.StatusGatewayTimeout
 	case service.ErrCodeInternal:
 		status = http.StatusInternalServerError
 	}
 
-	c.JSON(status, gin.H{
-		"error": gin.H{
-			"code":    err.Code,
-			"message": err.Message,
-		},
-	})
+	if err.RetryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(err.RetryAfter.Seconds()))))
+	}
+
+	writeError(c, status, err)
 }
 
 func handleUnexpectedError(c *gin.Context, err error) {
-	c.JSON(http.StatusInternalServerError, gin.H{
-		"error": gin.H{
-			"code":    "INTERNAL_ERROR",
-			"message": err.Error(),
-		},
+	if errors.Is(err, context.DeadlineExceeded) {
+		writeError(c, http.StatusGatewayTimeout, service.NewMessageError(service.ErrCodeTimeout, service.MsgRequestTimeout))
+		return
+	}
+	writeError(c, http.StatusInternalServerError, &service.ServiceError{
+		Code:    service.ErrCodeInternal,
+		Message: err.Error(),
 	})
 }
 func Cors() gin.HandlerFunc {
 	return cors.New(cors.Config{
 		AllowOrigins:     []string{"*"}, // Разрешить все домены (для разработки)
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Accept", "Accept-Language", "Authorization", "X-API-Key", RequestIDHeader},
+		ExposeHeaders:    []string{"Content-Length", RequestIDHeader, "Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	})
@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/satrunjis/user-service/internal/i18n"
+	"github.com/satrunjis/user-service/internal/service"
+)
+
+const (
+	problemContentType = "application/problem+json"
+	problemTypePrefix  = "urn:user-service:problem:"
+)
+
+// Problem тело ошибки в формате RFC 7807
+type Problem struct {
+	Type     string            `json:"type" example:"urn:user-service:problem:invalid-input"`
+	Title    string            `json:"title" example:"Bad Request"`
+	Status   int               `json:"status" example:"400"`
+	Detail   string            `json:"detail,omitempty" example:"login must be 5-20 characters"`
+	Instance string            `json:"instance,omitempty" example:"/api/v1/users"`
+	Code     service.ErrorCode `json:"code" example:"INVALID_INPUT"`
+	// Errors ошибки отдельных полей
+	Errors []service.FieldError `json:"errors,omitempty"`
+	// RetryAfter через сколько секунд можно повторить запрос
+	RetryAfter int `json:"retry_after,omitempty"`
+}
+
+// writeError отвечает в формате problem+json, если клиент явно его запросил, иначе в обычном формате.
+// Тексты переводятся на язык из Accept-Language, коды остаются прежними.
+func writeError(c *gin.Context, status int, err *service.ServiceError) {
+	lang := i18n.Negotiate(c.GetHeader("Accept-Language"), i18n.English)
+	err = localize(err, lang)
+	c.Header("Content-Language", string(lang))
+	c.Header("Vary", "Accept, Accept-Language")
+
+	if !acceptsProblem(c.GetHeader("Accept")) {
+		body := gin.H{
+			"code":    err.Code,
+			"message": err.Message,
+		}
+		if len(err.Details) > 0 {
+			body["details"] = err.Details
+		}
+		c.JSON(status, gin.H{"error": body})
+		return
+	}
+
+	problem := Problem{
+		Type:     problemTypePrefix + strings.ReplaceAll(strings.ToLower(string(err.Code)), "_", "-"),
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   err.Message,
+		Instance: c.Request.URL.RequestURI(),
+		Code:     err.Code,
+		Errors:   err.Details,
+	}
+	if err.RetryAfter > 0 {
+		problem.RetryAfter = int(math.Ceil(err.RetryAfter.Seconds()))
+	}
+
+	// gin не перезаписывает Content-Type, если он уже выставлен
+	c.Header("Content-Type", problemContentType)
+	c.JSON(status, problem)
+}
+
+// localize возвращает копию ошибки с переведёнными сообщениями
+func localize(err *service.ServiceError, lang i18n.Lang) *service.ServiceError {
+	localized := *err
+	localized.Details = make([]service.FieldError, len(err.Details))
+
+	messages := make([]string, len(err.Details))
+	for i, d := range err.Details {
+		d.Message = i18n.FieldMessage(lang, d.Field, d.Code, d.Message, d.Params)
+		localized.Details[i] = d
+		messages[i] = d.Message
+	}
+
+	if err.Code == service.ErrCodeInvalidInput && len(err.Details) > 0 {
+		localized.Message = strings.Join(messages, "; ")
+	} else {
+		localized.Message = i18n.Message(lang, string(err.Code), err.Key, err.Message, err.Params)
+	}
+	return &localized
+}
+
+// acceptsProblem возвращает true, если problem+json указан в Accept
+// с весом не ниже, чем у application/json
+func acceptsProblem(accept string) bool {
+	if accept == "" {
+		return false
+	}
+
+	problemQ, jsonQ := -1.0, -1.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.EqualFold(key, "q") {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+
+		switch strings.ToLower(strings.TrimSpace(mediaType)) {
+		case problemContentType:
+			problemQ = q
+		case "application/json":
+			jsonQ = q
+		}
+	}
+	return problemQ > 0 && problemQ >= jsonQ
+}
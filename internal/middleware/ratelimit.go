@@ -0,0 +1,192 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/satrunjis/user-service/internal/config"
+	"github.com/satrunjis/user-service/internal/domain"
+	"github.com/satrunjis/user-service/internal/service"
+)
+
+const (
+	memoryBucketsLimit  = 10000
+	fallbackWarnEvery   = time.Minute
+	DefaultRateLimitKey = "default"
+)
+
+// RateLimiter ограничивает частоту запросов по API-ключу, пользователю сессии или IP-адресу.
+// Лимиты общие для всех экземпляров через Redis; если Redis недоступен, лимит считается в памяти процесса.
+type RateLimiter struct {
+	store    domain.RateLimitStore
+	fallback *memoryRateLimiter
+	limits   map[string]domain.RateLimit
+	enabled  bool
+	logger   *slog.Logger
+
+	mu       sync.Mutex
+	lastWarn time.Time
+}
+
+func NewRateLimiter(store domain.RateLimitStore, cfg *config.RateLimitConfig, logger *slog.Logger) (*RateLimiter, error) {
+	limits := make(map[string]domain.RateLimit, len(cfg.Routes)+1)
+
+	def, err := ParseRateLimit(cfg.Default)
+	if err != nil {
+		return nil, fmt.Errorf("middleware.NewRateLimiter: default: %w", err)
+	}
+	limits[DefaultRateLimitKey] = def
+
+	for route, value := range cfg.Routes {
+		limit, err := ParseRateLimit(value)
+		if err != nil {
+			return nil, fmt.Errorf("middleware.NewRateLimiter: route %q: %w", route, err)
+		}
+		limits[route] = limit
+	}
+
+	return &RateLimiter{
+		store:    store,
+		fallback: newMemoryRateLimiter(),
+		limits:   limits,
+		enabled:  cfg.Enabled,
+		logger:   logger,
+	}, nil
+}
+
+// ParseRateLimit разбирает строку вида "100/1m"
+func ParseRateLimit(s string) (domain.RateLimit, error) {
+	count, period, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok {
+		return domain.RateLimit{}, fmt.Errorf("invalid rate limit %q, expected <requests>/<period>", s)
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil || n <= 0 {
+		return domain.RateLimit{}, fmt.Errorf("invalid request count in %q", s)
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return domain.RateLimit{}, fmt.Errorf("invalid period in %q", s)
+	}
+	return domain.RateLimit{Requests: n, Period: d}, nil
+}
+
+// Limit применяет лимит маршрута route (или лимит по умолчанию, если он не задан в конфиге)
+func (l *RateLimiter) Limit(route string) gin.HandlerFunc {
+	limit, ok := l.limits[route]
+	if !ok {
+		limit = l.limits[DefaultRateLimitKey]
+	}
+
+	return func(c *gin.Context) {
+		if !l.enabled {
+			c.Next()
+			return
+		}
+
+		key := route + ":" + rateLimitSubject(c)
+		decision := l.take(c.Request.Context(), key, limit)
+
+		c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Requests, int(limit.Period.Seconds())))
+		c.Header("RateLimit-Limit", strconv.Itoa(limit.Requests))
+		c.Header("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(int(math.Ceil(decision.Reset.Seconds()))))
+
+		if !decision.Allowed {
+			limited := service.NewMessageError(service.ErrCodeRateLimited, service.MsgRateLimited)
+			limited.RetryAfter = decision.RetryAfter
+			c.Error(limited)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func (l *RateLimiter) take(ctx context.Context, key string, limit domain.RateLimit) *domain.RateDecision {
+	decision, err := l.store.Take(ctx, key, limit)
+	if err == nil {
+		return decision
+	}
+
+	l.mu.Lock()
+	if time.Since(l.lastWarn) > fallbackWarnEvery {
+		l.lastWarn = time.Now()
+		l.logger.WarnContext(ctx, "rate limit store unavailable, using in-process limiter", "error", err)
+	}
+	l.mu.Unlock()
+
+	return l.fallback.take(key, limit)
+}
+
+func rateLimitSubject(c *gin.Context) string {
+	if p := GetPrincipal(c); p != nil {
+		if p.Type == domain.PrincipalSession {
+			return "user:" + p.UserID
+		}
+		return p.Type + ":" + p.ID
+	}
+	return "ip:" + c.ClientIP()
+}
+
+type memoryBucket struct {
+	tokens float64
+	ts     time.Time
+}
+
+type memoryRateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*memoryBucket
+}
+
+func newMemoryRateLimiter() *memoryRateLimiter {
+	return &memoryRateLimiter{buckets: make(map[string]*memoryBucket)}
+}
+
+func (m *memoryRateLimiter) take(key string, limit domain.RateLimit) *domain.RateDecision {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	burst := float64(limit.Requests)
+	rate := burst / float64(limit.Period) // токенов в наносекунду
+
+	if len(m.buckets) > memoryBucketsLimit {
+		m.sweep(now, limit.Period)
+	}
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &memoryBucket{tokens: burst, ts: now}
+		m.buckets[key] = b
+	}
+	b.tokens = math.Min(burst, b.tokens+float64(now.Sub(b.ts))*rate)
+	b.ts = now
+
+	decision := &domain.RateDecision{}
+	if b.tokens >= 1 {
+		b.tokens--
+		decision.Allowed = true
+	} else {
+		decision.RetryAfter = time.Duration((1 - b.tokens) / rate)
+	}
+	decision.Remaining = int(b.tokens)
+	decision.Reset = time.Duration((burst - b.tokens) / rate)
+	return decision
+}
+
+// sweep удаляет корзины, которые давно не использовались и уже полностью восстановились
+func (m *memoryRateLimiter) sweep(now time.Time, period time.Duration) {
+	for key, b := range m.buckets {
+		if now.Sub(b.ts) > 2*period {
+			delete(m.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/satrunjis/user-service/internal/domain"
+	"github.com/satrunjis/user-service/internal/service"
+)
+
+// LogNotifier пишет уведомления в лог или построчно в JSON-файл.
+// Предназначен для разработки и тестовых стендов без почтового сервера.
+type LogNotifier struct {
+	w      io.Writer
+	mu     sync.Mutex
+	logger *slog.Logger
+}
+
+type logRecord struct {
+	Type      string    `json:"type"`
+	UserID    string    `json:"user_id"`
+	Login     string    `json:"login"`
+	Email     *string   `json:"email,omitempty"`
+	Token     string    `json:"token"`
+	ResetURL  *string   `json:"reset_url,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+	SentAt    time.Time `json:"sent_at"`
+}
+
+var _ service.Notifier = (*LogNotifier)(nil)
+
+func NewLogNotifier(w io.Writer, logger *slog.Logger) *LogNotifier {
+	return &LogNotifier{w: w, logger: logger}
+}
+
+func NewFileNotifier(path string, logger *slog.Logger) (*LogNotifier, error) {
+	const op = "notifier.NewFileNotifier"
+	if path == "" {
+		return nil, fmt.Errorf("%s: file path is required", op)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	logger.Info("file notifier initialized", "path", path)
+	return NewLogNotifier(f, logger), nil
+}
+
+func (n *LogNotifier) SendPasswordReset(ctx context.Context, msg *domain.PasswordResetNotification) error {
+	const op = "LogNotifier.SendPasswordReset"
+	log := n.logger.With("operation", op, "user_id", msg.UserID)
+
+	if n.w == nil {
+		log.InfoContext(ctx, "password reset notification",
+			"login", msg.Login,
+			"token", msg.Token,
+			"reset_url", msg.ResetURL,
+			"expires_at", msg.ExpiresAt)
+		return nil
+	}
+
+	record := logRecord{
+		Type:      "password_reset",
+		UserID:    msg.UserID,
+		Login:     msg.Login,
+		Email:     msg.Email,
+		Token:     msg.Token,
+		ResetURL:  msg.ResetURL,
+		ExpiresAt: msg.ExpiresAt,
+		SentAt:    time.Now().UTC(),
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if err := json.NewEncoder(n.w).Encode(record); err != nil {
+		log.ErrorContext(ctx, "failed to write notification", "error", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.DebugContext(ctx, "notification written")
+	return nil
+}
+
+func (n *LogNotifier) Close() error {
+	if c, ok := n.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package notifier
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/satrunjis/user-service/internal/config"
+	"github.com/satrunjis/user-service/internal/service"
+)
+
+const (
+	TypeLog  = "log"
+	TypeFile = "file"
+	TypeSMTP = "smtp"
+)
+
+func New(cfg *config.NotifierConfig, logger *slog.Logger) (service.Notifier, error) {
+	switch cfg.Type {
+	case TypeLog:
+		return NewLogNotifier(nil, logger), nil
+	case TypeFile:
+		return NewFileNotifier(cfg.FilePath, logger)
+	case TypeSMTP:
+		return NewSMTPNotifier(&cfg.SMTP, logger), nil
+	default:
+		return nil, fmt.Errorf("notifier.New: unknown notifier type %q", cfg.Type)
+	}
+}
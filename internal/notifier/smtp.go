@@ -0,0 +1,139 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+
+	"github.com/satrunjis/user-service/internal/config"
+	"github.com/satrunjis/user-service/internal/domain"
+	"github.com/satrunjis/user-service/internal/service"
+)
+
+// SMTPNotifier отправляет письма через SMTP-сервер.
+// Без логина и пароля работает с локальными тестовыми серверами (mailpit, MailHog).
+type SMTPNotifier struct {
+	addr     string
+	host     string
+	from     string
+	username string
+	password string
+	timeout  time.Duration
+	logger   *slog.Logger
+}
+
+var _ service.Notifier = (*SMTPNotifier)(nil)
+
+var errNoRecipient = errors.New("user has no email address")
+
+func NewSMTPNotifier(cfg *config.SMTPConfig, logger *slog.Logger) *SMTPNotifier {
+	return &SMTPNotifier{
+		addr:     net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+		host:     cfg.Host,
+		from:     cfg.From,
+		username: cfg.Username,
+		password: string(cfg.Password),
+		timeout:  cfg.Timeout,
+		logger:   logger,
+	}
+}
+
+func (n *SMTPNotifier) SendPasswordReset(ctx context.Context, msg *domain.PasswordResetNotification) error {
+	const op = "SMTPNotifier.SendPasswordReset"
+	log := n.logger.With("operation", op, "user_id", msg.UserID)
+
+	if msg.Email == nil {
+		log.WarnContext(ctx, "cannot send password reset", "error", errNoRecipient)
+		return fmt.Errorf("%s: %w", op, errNoRecipient)
+	}
+
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "Здравствуйте, %s!\r\n\r\n", msg.Login)
+	body.WriteString("Мы получили запрос на сброс пароля.\r\n")
+	if msg.ResetURL != nil {
+		fmt.Fprintf(&body, "Для установки нового пароля перейдите по ссылке:\r\n%s\r\n", *msg.ResetURL)
+	} else {
+		fmt.Fprintf(&body, "Код для сброса пароля:\r\n%s\r\n", msg.Token)
+	}
+	fmt.Fprintf(&body, "\r\nЗапрос действителен до %s.\r\n", msg.ExpiresAt.Format(time.RFC1123))
+	body.WriteString("Если вы не запрашивали сброс, просто проигнорируйте это письмо.\r\n")
+
+	if err := n.send(ctx, *msg.Email, "Сброс пароля", body.Bytes()); err != nil {
+		log.ErrorContext(ctx, "failed to send email", "error", err)
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.InfoContext(ctx, "password reset email sent")
+	return nil
+}
+
+func (n *SMTPNotifier) send(ctx context.Context, to, subject string, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, n.timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", n.addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, n.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: n.host}); err != nil {
+			return err
+		}
+	}
+	if n.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", n.username, n.password, n.host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(n.from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", n.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	msg.Write(body)
+
+	if _, err := w.Write(msg.Bytes()); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
@@ -0,0 +1,190 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/satrunjis/user-service/internal/domain"
+	"github.com/satrunjis/user-service/internal/service"
+)
+
+const apiKeysIndex = "api_keys"
+const apiKeysMappings = `{
+  "mappings": {
+    "properties": {
+      "id":           {"type": "keyword"},
+      "name":         {"type": "keyword"},
+      "hash":         {"type": "keyword", "index": false},
+      "scopes":       {"type": "keyword"},
+      "created_at":   {"type": "date"},
+      "created_by":   {"type": "keyword"},
+      "expires_at":   {"type": "date"},
+      "last_used_at": {"type": "date"},
+      "revoked_at":   {"type": "date"}
+    }
+  }
+}`
+
+type APIKeys struct {
+	client *elasticsearch.Client
+	logger *slog.Logger
+}
+
+var _ domain.APIKeyRepository = (*APIKeys)(nil)
+
+func NewAPIKeys(ctx context.Context, e *Elastic) (*APIKeys, error) {
+	log := e.logger.With("operation", "elastic.NewAPIKeys")
+	if err := ensureIndex(ctx, e.Client, apiKeysIndex, apiKeysMappings, log); err != nil {
+		return nil, err
+	}
+	return &APIKeys{client: e.Client, logger: e.logger}, nil
+}
+
+func (r *APIKeys) Create(ctx context.Context, key *domain.APIKey) error {
+	const op = "APIKeys.Create"
+	log := r.logger.With("operation", op, "key_id", key.ID)
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(key); err != nil {
+		log.ErrorContext(ctx, "document encoding failed", "error", err)
+		return service.StorageError(err)
+	}
+
+	res, err := r.client.Create(
+		apiKeysIndex,
+		key.ID,
+		&buf,
+		r.client.Create.WithContext(ctx),
+		r.client.Create.WithRefresh("wait_for"),
+	)
+	if err != nil {
+		log.ErrorContext(ctx, "index request failed", "error", err)
+		return service.StorageError(err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		if res.StatusCode == 409 {
+			return service.NewServiceError(service.ErrCodeAlreadyExists)
+		}
+		log.ErrorContext(ctx, "index response error", "status", res.Status(), "response", res.String())
+		return responseError(res)
+	}
+
+	log.InfoContext(ctx, "api key created")
+	return nil
+}
+
+func (r *APIKeys) GetByID(ctx context.Context, id string) (*domain.APIKey, error) {
+	const op = "APIKeys.GetByID"
+	log := r.logger.With("operation", op, "key_id", id)
+
+	res, err := r.client.Get(apiKeysIndex, id, r.client.Get.WithContext(ctx))
+	if err != nil {
+		log.ErrorContext(ctx, "get request failed", "error", err)
+		return nil, service.StorageError(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return nil, service.NewServiceError(service.ErrCodeNotFound)
+	}
+	if res.IsError() {
+		log.ErrorContext(ctx, "get response error", "status", res.Status(), "response", res.String())
+		return nil, responseError(res)
+	}
+
+	var doc struct {
+		Source domain.APIKey `json:"_source"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&doc); err != nil {
+		log.ErrorContext(ctx, "document decoding failed", "error", err)
+		return nil, service.StorageError(err)
+	}
+	return &doc.Source, nil
+}
+
+func (r *APIKeys) List(ctx context.Context) ([]*domain.APIKey, error) {
+	const op = "APIKeys.List"
+	log := r.logger.With("operation", op)
+
+	query := `{"query": {"match_all": {}}, "sort": [{"created_at": {"order": "desc"}}], "size": 1000}`
+	res, err := r.client.Search(
+		r.client.Search.WithIndex(apiKeysIndex),
+		r.client.Search.WithContext(ctx),
+		r.client.Search.WithBody(strings.NewReader(query)),
+	)
+	if err != nil {
+		log.ErrorContext(ctx, "search request failed", "error", err)
+		return nil, service.StorageError(err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		log.ErrorContext(ctx, "search response error", "status", res.Status(), "response", res.String())
+		return nil, responseError(res)
+	}
+
+	var response struct {
+		Hits struct {
+			Hits []struct {
+				Source domain.APIKey `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		log.ErrorContext(ctx, "document decoding failed", "error", err)
+		return nil, service.StorageError(err)
+	}
+
+	keys := make([]*domain.APIKey, len(response.Hits.Hits))
+	for i := range response.Hits.Hits {
+		keys[i] = &response.Hits.Hits[i].Source
+	}
+	return keys, nil
+}
+
+func (r *APIKeys) Revoke(ctx context.Context, id string, at time.Time) error {
+	return r.updateTime(ctx, "APIKeys.Revoke", id, "revoked_at", at)
+}
+
+func (r *APIKeys) TouchLastUsed(ctx context.Context, id string, at time.Time) error {
+	return r.updateTime(ctx, "APIKeys.TouchLastUsed", id, "last_used_at", at)
+}
+
+func (r *APIKeys) updateTime(ctx context.Context, op, id, field string, at time.Time) error {
+	log := r.logger.With("operation", op, "key_id", id)
+
+	var buf bytes.Buffer
+	body := map[string]any{"doc": map[string]any{field: at}}
+	if err := json.NewEncoder(&buf).Encode(body); err != nil {
+		log.ErrorContext(ctx, "document encoding failed", "error", err)
+		return service.StorageError(err)
+	}
+
+	res, err := r.client.Update(
+		apiKeysIndex,
+		id,
+		&buf,
+		r.client.Update.WithContext(ctx),
+	)
+	if err != nil {
+		log.ErrorContext(ctx, "update request failed", "error", err)
+		return service.StorageError(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return service.NewServiceError(service.ErrCodeNotFound)
+	}
+	if res.IsError() {
+		log.ErrorContext(ctx, "update response error", "status", res.Status(), "response", res.String())
+		return responseError(res)
+	}
+	return nil
+}
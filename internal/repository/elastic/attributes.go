@@ -0,0 +1,163 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/satrunjis/user-service/internal/domain"
+	"github.com/satrunjis/user-service/internal/service"
+)
+
+const attributeSchemaIndex = "attribute_schema"
+const attributeSchemaDocID = "current"
+
+// Схема хранится целиком в _source, поэтому её содержимое не индексируется
+const attributeSchemaMappings = `{
+  "mappings": {
+    "dynamic": false,
+    "properties": {
+      "version":    {"type": "long"},
+      "updated_at": {"type": "date"},
+      "updated_by": {"type": "keyword"}
+    }
+  }
+}`
+
+// attributeFieldTypes типы полей attributes.<name> в индексе пользователей
+var attributeFieldTypes = map[string]string{
+	domain.AttributeString:  "keyword",
+	domain.AttributeInteger: "long",
+	domain.AttributeNumber:  "double",
+	domain.AttributeBoolean: "boolean",
+	domain.AttributeDate:    "date",
+}
+
+type AttributeSchemas struct {
+	client *elasticsearch.Client
+	logger *slog.Logger
+}
+
+var _ domain.AttributeSchemaRepository = (*AttributeSchemas)(nil)
+
+func NewAttributeSchemas(ctx context.Context, e *Elastic) (*AttributeSchemas, error) {
+	log := e.logger.With("operation", "elastic.NewAttributeSchemas")
+	if err := ensureIndex(ctx, e.Client, attributeSchemaIndex, attributeSchemaMappings, log); err != nil {
+		return nil, err
+	}
+	return &AttributeSchemas{client: e.Client, logger: e.logger}, nil
+}
+
+func (r *AttributeSchemas) Get(ctx context.Context) (*domain.AttributeSchema, error) {
+	const op = "AttributeSchemas.Get"
+	log := r.logger.With("operation", op)
+
+	res, err := r.client.Get(attributeSchemaIndex, attributeSchemaDocID, r.client.Get.WithContext(ctx))
+	if err != nil {
+		log.ErrorContext(ctx, "get request failed", "error", err)
+		return nil, service.StorageError(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return nil, service.NewServiceError(service.ErrCodeNotFound)
+	}
+	if res.IsError() {
+		log.ErrorContext(ctx, "get response error", "status", res.Status(), "response", res.String())
+		return nil, responseError(res)
+	}
+
+	var doc struct {
+		Source domain.AttributeSchema `json:"_source"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&doc); err != nil {
+		log.ErrorContext(ctx, "document decoding failed", "error", err)
+		return nil, service.StorageError(err)
+	}
+	return &doc.Source, nil
+}
+
+// Save сначала дописывает маппинг атрибутов, чтобы значения сразу индексировались
+// с нужным типом. Маппинг удалённых атрибутов остаётся в индексе.
+func (r *AttributeSchemas) Save(ctx context.Context, schema *domain.AttributeSchema) error {
+	const op = "AttributeSchemas.Save"
+	log := r.logger.With("operation", op, "version", schema.Version)
+
+	if err := r.putAttributeMappings(ctx, schema, log); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(schema); err != nil {
+		log.ErrorContext(ctx, "document encoding failed", "error", err)
+		return service.StorageError(err)
+	}
+
+	res, err := r.client.Index(
+		attributeSchemaIndex,
+		&buf,
+		r.client.Index.WithDocumentID(attributeSchemaDocID),
+		r.client.Index.WithContext(ctx),
+		r.client.Index.WithRefresh("wait_for"),
+	)
+	if err != nil {
+		log.ErrorContext(ctx, "index request failed", "error", err)
+		return service.StorageError(err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		log.ErrorContext(ctx, "index response error", "status", res.Status(), "response", res.String())
+		return responseError(res)
+	}
+
+	log.InfoContext(ctx, "attribute schema saved", "attributes", len(schema.Properties))
+	return nil
+}
+
+func (r *AttributeSchemas) putAttributeMappings(ctx context.Context, schema *domain.AttributeSchema, log *slog.Logger) error {
+	if len(schema.Properties) == 0 {
+		return nil
+	}
+
+	fields := make(map[string]any, len(schema.Properties))
+	for name, prop := range schema.Properties {
+		fields[name] = map[string]any{"type": attributeFieldTypes[prop.Type]}
+	}
+	body, err := json.Marshal(map[string]any{
+		"properties": map[string]any{
+			"attributes": map[string]any{
+				"type":       "object",
+				"dynamic":    false,
+				"properties": fields,
+			},
+		},
+	})
+	if err != nil {
+		log.ErrorContext(ctx, "mapping encoding failed", "error", err)
+		return service.StorageError(err)
+	}
+
+	res, err := r.client.Indices.PutMapping(
+		[]string{usersIndex},
+		bytes.NewReader(body),
+		r.client.Indices.PutMapping.WithContext(ctx),
+	)
+	if err != nil {
+		log.ErrorContext(ctx, "mapping update failed", "error", err)
+		return service.StorageError(err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		log.ErrorContext(ctx, "mapping update rejected", "status", res.Status(), "response", res.String())
+		if res.StatusCode == 400 {
+			// Тип уже проиндексированного поля изменить нельзя
+			return service.NewMessageError(service.ErrCodeConflict, service.MsgAttributeMappingConflict)
+		}
+		return responseError(res)
+	}
+	return nil
+}
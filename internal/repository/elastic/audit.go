@@ -0,0 +1,336 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/satrunjis/user-service/internal/domain"
+	"github.com/satrunjis/user-service/internal/service"
+)
+
+const auditIndex = "user_audit"
+
+// Значения в changes бывают разных типов, поэтому хранятся только в _source
+const auditMappings = `{
+  "mappings": {
+    "properties": {
+      "id":         {"type": "keyword"},
+      "user_id":    {"type": "keyword"},
+      "version":    {"type": "long"},
+      "operation":  {"type": "keyword"},
+      "actor":      {"type": "keyword"},
+      "request_id": {"type": "keyword"},
+      "event_id":   {"type": "keyword"},
+      "at":         {"type": "date"},
+      "changes":    {"type": "object", "enabled": false}
+    }
+  }
+}`
+
+// Replay читает историю одним запросом, этого хватает для истории любого разумного размера
+const auditReplayLimit = 10000
+
+// Сколько раз Append пробует следующий номер версии при параллельной записи
+const auditAppendAttempts = 5
+
+type AuditLog struct {
+	client *elasticsearch.Client
+	logger *slog.Logger
+}
+
+var _ domain.AuditRepository = (*AuditLog)(nil)
+
+func NewAuditLog(ctx context.Context, e *Elastic) (*AuditLog, error) {
+	log := e.logger.With("operation", "elastic.NewAuditLog")
+	if err := ensureIndex(ctx, e.Client, auditIndex, auditMappings, log); err != nil {
+		return nil, err
+	}
+	return &AuditLog{client: e.Client, logger: e.logger}, nil
+}
+
+// Append создаёт документ с ID <user_id>:<version>. Create не перезаписывает существующие записи,
+// а при гонке двух запросов один из них получает 409 и берёт следующий номер.
+func (r *AuditLog) Append(ctx context.Context, entry *domain.AuditEntry) error {
+	const op = "AuditLog.Append"
+	log := r.logger.With("operation", op, "user_id", entry.UserID)
+
+	version, seen, err := r.lastVersion(ctx, entry.UserID, entry.EventID)
+	if err != nil {
+		return err
+	}
+	if seen {
+		log.DebugContext(ctx, "audit entry for event already exists", "event_id", entry.EventID)
+		return nil
+	}
+
+	for attempt := 0; attempt < auditAppendAttempts; attempt++ {
+		version++
+		entry.Version = version
+		entry.ID = fmt.Sprintf("%s:%d", entry.UserID, version)
+
+		var buf bytes.Buffer
+		if err := json.NewEncoder(&buf).Encode(entry); err != nil {
+			log.ErrorContext(ctx, "document encoding failed", "error", err)
+			return service.StorageError(err)
+		}
+
+		res, err := r.client.Create(
+			auditIndex,
+			entry.ID,
+			&buf,
+			r.client.Create.WithContext(ctx),
+			r.client.Create.WithRefresh("wait_for"),
+		)
+		if err != nil {
+			log.ErrorContext(ctx, "index request failed", "error", err)
+			return service.StorageError(err)
+		}
+		res.Body.Close()
+
+		if res.StatusCode == 409 {
+			continue
+		}
+		if res.IsError() {
+			log.ErrorContext(ctx, "index response error", "status", res.Status(), "response", res.String())
+			return responseError(res)
+		}
+
+		log.DebugContext(ctx, "audit entry appended", "version", version, "audit_operation", entry.Operation)
+		return nil
+	}
+
+	log.ErrorContext(ctx, "audit version conflict persisted", "version", version)
+	return service.NewServiceError(service.ErrCodeConflict)
+}
+
+// lastVersion возвращает последнюю версию пользователя и признак того, что событие eventID уже записано
+func (r *AuditLog) lastVersion(ctx context.Context, userID, eventID string) (int64, bool, error) {
+	const op = "AuditLog.lastVersion"
+	log := r.logger.With("operation", op, "user_id", userID)
+
+	query := map[string]any{
+		"size":  0,
+		"query": map[string]any{"term": map[string]any{"user_id": userID}},
+		"aggs": map[string]any{
+			"last":  map[string]any{"max": map[string]any{"field": "version"}},
+			"event": map[string]any{"filter": map[string]any{"term": map[string]any{"event_id": eventID}}},
+		},
+	}
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(query); err != nil {
+		return 0, false, service.StorageError(err)
+	}
+
+	res, err := r.client.Search(
+		r.client.Search.WithIndex(auditIndex),
+		r.client.Search.WithContext(ctx),
+		r.client.Search.WithBody(&buf),
+	)
+	if err != nil {
+		log.ErrorContext(ctx, "search request failed", "error", err)
+		return 0, false, service.StorageError(err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		log.ErrorContext(ctx, "search response error", "status", res.Status(), "response", res.String())
+		return 0, false, responseError(res)
+	}
+
+	var response struct {
+		Aggregations struct {
+			Last struct {
+				Value *float64 `json:"value"`
+			} `json:"last"`
+			Event struct {
+				DocCount int64 `json:"doc_count"`
+			} `json:"event"`
+		} `json:"aggregations"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		log.ErrorContext(ctx, "response decoding failed", "error", err)
+		return 0, false, service.StorageError(err)
+	}
+	seen := eventID != "" && response.Aggregations.Event.DocCount > 0
+	if response.Aggregations.Last.Value == nil {
+		return 0, seen, nil
+	}
+	return int64(*response.Aggregations.Last.Value), seen, nil
+}
+
+func (r *AuditLog) History(ctx context.Context, userID string, page, size int) (*domain.AuditPage, error) {
+	const op = "AuditLog.History"
+	log := r.logger.With("operation", op, "user_id", userID)
+
+	query := map[string]any{
+		"query":            map[string]any{"term": map[string]any{"user_id": userID}},
+		"sort":             []map[string]any{{"version": map[string]any{"order": "desc"}}},
+		"from":             (page - 1) * size,
+		"size":             size,
+		"track_total_hits": true,
+	}
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(query); err != nil {
+		return nil, service.StorageError(err)
+	}
+
+	res, err := r.client.Search(
+		r.client.Search.WithIndex(auditIndex),
+		r.client.Search.WithContext(ctx),
+		r.client.Search.WithBody(&buf),
+	)
+	if err != nil {
+		log.ErrorContext(ctx, "search request failed", "error", err)
+		return nil, service.StorageError(err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		log.ErrorContext(ctx, "search response error", "status", res.Status(), "response", res.String())
+		return nil, responseError(res)
+	}
+
+	var response struct {
+		Hits struct {
+			Total struct {
+				Value int64 `json:"value"`
+			} `json:"total"`
+			Hits []struct {
+				Source domain.AuditEntry `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		log.ErrorContext(ctx, "response decoding failed", "error", err)
+		return nil, service.StorageError(err)
+	}
+
+	entries := make([]*domain.AuditEntry, len(response.Hits.Hits))
+	for i := range response.Hits.Hits {
+		entries[i] = &response.Hits.Hits[i].Source
+	}
+	return &domain.AuditPage{Entries: entries, Total: response.Hits.Total.Value, Page: page, Size: size}, nil
+}
+
+func (r *AuditLog) Replay(ctx context.Context, userID string, until time.Time, maxVersion int64) ([]*domain.AuditEntry, error) {
+	const op = "AuditLog.Replay"
+	log := r.logger.With("operation", op, "user_id", userID)
+
+	filters := []map[string]any{
+		{"term": map[string]any{"user_id": userID}},
+	}
+	if !until.IsZero() {
+		filters = append(filters, map[string]any{"range": map[string]any{"at": map[string]any{"lte": until.UTC().Format(time.RFC3339Nano)}}})
+	}
+	if maxVersion > 0 {
+		filters = append(filters, map[string]any{"range": map[string]any{"version": map[string]any{"lte": maxVersion}}})
+	}
+	query := map[string]any{
+		"query": map[string]any{"bool": map[string]any{"filter": filters}},
+		"sort":  []map[string]any{{"version": map[string]any{"order": "asc"}}},
+		"size":  auditReplayLimit,
+	}
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(query); err != nil {
+		return nil, service.StorageError(err)
+	}
+
+	res, err := r.client.Search(
+		r.client.Search.WithIndex(auditIndex),
+		r.client.Search.WithContext(ctx),
+		r.client.Search.WithBody(&buf),
+	)
+	if err != nil {
+		log.ErrorContext(ctx, "search request failed", "error", err)
+		return nil, service.StorageError(err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		log.ErrorContext(ctx, "search response error", "status", res.Status(), "response", res.String())
+		return nil, responseError(res)
+	}
+
+	var response struct {
+		Hits struct {
+			Hits []struct {
+				Source domain.AuditEntry `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		log.ErrorContext(ctx, "response decoding failed", "error", err)
+		return nil, service.StorageError(err)
+	}
+
+	entries := make([]*domain.AuditEntry, len(response.Hits.Hits))
+	for i := range response.Hits.Hits {
+		entries[i] = &response.Hits.Hits[i].Source
+	}
+	if len(entries) == auditReplayLimit {
+		log.WarnContext(ctx, "audit history truncated", "limit", auditReplayLimit)
+	}
+	return entries, nil
+}
+
+// Pseudonymize переносит записи пользователя под псевдоним: ID документа содержит ID пользователя,
+// поэтому записи создаются заново с ID <pseudonym>:<version>, а старые удаляются. Значения полей
+// в changes стираются, остаются названия полей, время, операция и автор. Повторный вызов
+// дописывает то, что не удалось в прошлый раз.
+func (r *AuditLog) Pseudonymize(ctx context.Context, userID, pseudonym string) (int, error) {
+	const op = "AuditLog.Pseudonymize"
+	log := r.logger.With("operation", op, "user_id", userID)
+
+	entries, err := r.Replay(ctx, userID, time.Time{}, 0)
+	if err != nil {
+		return 0, err
+	}
+	if len(entries) == 0 {
+		return 0, nil
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, entry := range entries {
+		oldID := entry.ID
+		entry.ID = fmt.Sprintf("%s:%d", pseudonym, entry.Version)
+		entry.UserID = pseudonym
+		if entry.Actor == domain.PrincipalSession+":"+userID {
+			entry.Actor = domain.PrincipalSession + ":" + pseudonym
+		}
+		for i := range entry.Changes {
+			entry.Changes[i].Before = nil
+			entry.Changes[i].After = nil
+		}
+
+		if err := enc.Encode(map[string]any{"index": map[string]any{"_index": auditIndex, "_id": entry.ID}}); err != nil {
+			return 0, service.StorageError(err)
+		}
+		if err := enc.Encode(entry); err != nil {
+			return 0, service.StorageError(err)
+		}
+		if err := enc.Encode(map[string]any{"delete": map[string]any{"_index": auditIndex, "_id": oldID}}); err != nil {
+			return 0, service.StorageError(err)
+		}
+	}
+
+	items, err := bulk(ctx, r.client, &buf)
+	if err != nil {
+		log.ErrorContext(ctx, "bulk request failed", "error", err)
+		return 0, err
+	}
+	for _, item := range items {
+		if item.Status >= 300 && item.Status != 404 {
+			log.ErrorContext(ctx, "audit entry was not pseudonymized", "status", item.Status, "error", string(item.Error))
+			return 0, statusError(item.Status, fmt.Errorf("audit pseudonymization responded %d", item.Status))
+		}
+	}
+
+	log.InfoContext(ctx, "audit entries pseudonymized", "entries", len(entries))
+	return len(entries), nil
+}
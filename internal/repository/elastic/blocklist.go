@@ -0,0 +1,180 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/google/uuid"
+	"github.com/satrunjis/user-service/internal/domain"
+	"github.com/satrunjis/user-service/internal/logins"
+	"github.com/satrunjis/user-service/internal/service"
+)
+
+const blocklistIndex = "login_blocklist"
+const blocklistMappings = `{
+  "mappings": {
+    "properties": {
+      "id":         {"type": "keyword"},
+      "kind":       {"type": "keyword"},
+      "value":      {"type": "keyword"},
+      "comment":    {"type": "text"},
+      "created_at": {"type": "date"},
+      "created_by": {"type": "keyword"}
+    }
+  }
+}`
+
+type LoginBlocklist struct {
+	client *elasticsearch.Client
+	logger *slog.Logger
+}
+
+var _ domain.LoginBlocklistRepository = (*LoginBlocklist)(nil)
+
+// NewLoginBlocklist при первом создании индекса заполняет его зарезервированными логинами из seed
+func NewLoginBlocklist(ctx context.Context, e *Elastic, seed []string) (*LoginBlocklist, error) {
+	log := e.logger.With("operation", "elastic.NewLoginBlocklist")
+
+	exists, err := e.Client.Indices.Exists([]string{blocklistIndex}, e.Client.Indices.Exists.WithContext(ctx))
+	if err != nil {
+		log.Error("index check failed", "error", err, "index", blocklistIndex)
+		return nil, service.StorageError(err)
+	}
+	exists.Body.Close()
+	created := exists.StatusCode == 404
+
+	if err := ensureIndex(ctx, e.Client, blocklistIndex, blocklistMappings, log); err != nil {
+		return nil, err
+	}
+
+	r := &LoginBlocklist{client: e.Client, logger: e.logger}
+	if created {
+		for _, value := range seed {
+			value = strings.TrimSpace(value)
+			if value == "" {
+				continue
+			}
+			err := r.Create(ctx, &domain.LoginBlockRule{
+				ID:        uuid.New().String(),
+				Kind:      domain.BlockReserved,
+				Value:     logins.Normalize(value),
+				CreatedAt: time.Now().UTC(),
+				CreatedBy: "system",
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		log.Info("login blocklist seeded", "count", len(seed))
+	}
+	return r, nil
+}
+
+func (r *LoginBlocklist) Create(ctx context.Context, rule *domain.LoginBlockRule) error {
+	const op = "LoginBlocklist.Create"
+	log := r.logger.With("operation", op, "rule_id", rule.ID)
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(rule); err != nil {
+		log.ErrorContext(ctx, "document encoding failed", "error", err)
+		return service.StorageError(err)
+	}
+
+	res, err := r.client.Create(
+		blocklistIndex,
+		url.PathEscape(rule.ID),
+		&buf,
+		r.client.Create.WithContext(ctx),
+		r.client.Create.WithRefresh("wait_for"),
+	)
+	if err != nil {
+		log.ErrorContext(ctx, "index request failed", "error", err)
+		return service.StorageError(err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		if res.StatusCode == 409 {
+			return service.NewServiceError(service.ErrCodeAlreadyExists)
+		}
+		log.ErrorContext(ctx, "index response error", "status", res.Status(), "response", res.String())
+		return responseError(res)
+	}
+
+	log.InfoContext(ctx, "login block rule created", "kind", rule.Kind)
+	return nil
+}
+
+func (r *LoginBlocklist) List(ctx context.Context) ([]*domain.LoginBlockRule, error) {
+	const op = "LoginBlocklist.List"
+	log := r.logger.With("operation", op)
+
+	query := `{"query": {"match_all": {}}, "sort": [{"created_at": {"order": "asc"}}], "size": 10000}`
+	res, err := r.client.Search(
+		r.client.Search.WithIndex(blocklistIndex),
+		r.client.Search.WithContext(ctx),
+		r.client.Search.WithBody(strings.NewReader(query)),
+	)
+	if err != nil {
+		log.ErrorContext(ctx, "search request failed", "error", err)
+		return nil, service.StorageError(err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		log.ErrorContext(ctx, "search response error", "status", res.Status(), "response", res.String())
+		return nil, responseError(res)
+	}
+
+	var response struct {
+		Hits struct {
+			Hits []struct {
+				Source domain.LoginBlockRule `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		log.ErrorContext(ctx, "document decoding failed", "error", err)
+		return nil, service.StorageError(err)
+	}
+
+	rules := make([]*domain.LoginBlockRule, len(response.Hits.Hits))
+	for i := range response.Hits.Hits {
+		rules[i] = &response.Hits.Hits[i].Source
+	}
+	return rules, nil
+}
+
+func (r *LoginBlocklist) Delete(ctx context.Context, id string) error {
+	const op = "LoginBlocklist.Delete"
+	log := r.logger.With("operation", op, "rule_id", id)
+
+	res, err := r.client.Delete(
+		blocklistIndex,
+		url.PathEscape(id),
+		r.client.Delete.WithContext(ctx),
+		r.client.Delete.WithRefresh("wait_for"),
+	)
+	if err != nil {
+		log.ErrorContext(ctx, "delete request failed", "error", err)
+		return service.StorageError(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return service.NewServiceError(service.ErrCodeNotFound)
+	}
+	if res.IsError() {
+		log.ErrorContext(ctx, "delete response error", "status", res.Status(), "response", res.String())
+		return responseError(res)
+	}
+
+	log.InfoContext(ctx, "login block rule deleted")
+	return nil
+}
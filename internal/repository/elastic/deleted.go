@@ -0,0 +1,58 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/satrunjis/user-service/internal/domain"
+	"github.com/satrunjis/user-service/internal/service"
+)
+
+// ListDeleted возвращает пользователей, удалённых не позднее before, начиная с самых старых
+func (e *Elastic) ListDeleted(ctx context.Context, before time.Time, size int) ([]*domain.User, error) {
+	const op = "Elastic.ListDeleted"
+	log := e.logger.With("operation", op)
+
+	query := map[string]any{
+		"query": map[string]any{
+			"bool": map[string]any{
+				"filter": []map[string]any{
+					{"term": map[string]any{"status": domain.StatusDeleted}},
+					{"range": map[string]any{"deleted_at": map[string]any{"lte": before.UTC().Format(time.RFC3339)}}},
+				},
+			},
+		},
+		"sort": []map[string]any{{"deleted_at": map[string]any{"order": "asc"}}},
+		"size": size,
+	}
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(query); err != nil {
+		log.ErrorContext(ctx, "query encoding failed", "error", err)
+		return nil, service.StorageError(err)
+	}
+
+	res, err := e.Client.Search(
+		e.Client.Search.WithIndex(usersIndex),
+		e.Client.Search.WithContext(ctx),
+		e.Client.Search.WithBody(&buf),
+	)
+	if err != nil {
+		log.ErrorContext(ctx, "search request failed", "error", err)
+		return nil, service.StorageError(err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		log.ErrorContext(ctx, "search response error", "status", res.Status(), "response", res.String())
+		return nil, responseError(res)
+	}
+
+	users, err := e.parseResults(res.Body)
+	if err != nil {
+		log.ErrorContext(ctx, "document decoding failed", "error", err)
+		return nil, err
+	}
+	return users, nil
+}
@@ -6,12 +6,18 @@ import (
 	"encoding/json"
 	"io"
 	"log/slog"
+	"maps"
+	"net/url"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/elastic/go-elasticsearch/v9/esapi"
 	"github.com/google/uuid"
 	"github.com/satrunjis/user-service/internal/domain"
+	"github.com/satrunjis/user-service/internal/logins"
+	"github.com/satrunjis/user-service/internal/secrets"
 	"github.com/satrunjis/user-service/internal/service"
 )
 
@@ -22,11 +28,20 @@ const mappings = `{
       "login":               {"type": "text", "fields": {"keyword": {"type": "keyword"}}},
       "username":            {"type": "text", "fields": {"keyword": {"type": "keyword"}}},
       "password":            {"type": "keyword"},
+      "email":               {"type": "keyword"},
       "description":         {"type": "text", "fields": {"keyword": {"type": "keyword"}}},
       "comment":             {"type": "text", "fields": {"keyword": {"type": "keyword"}}},
       "reg_date":            {"type": "date"},
       "location":            {"type": "geo_point"},
-      "social_net":          {"type": "keyword"}
+      "social_net":          {"type": "keyword"},
+      "social_profiles":     {"type": "nested", "properties": {"network": {"type": "keyword"}, "handle": {"type": "keyword"}}},
+      "attributes":          {"type": "object", "dynamic": false},
+      "status":              {"type": "keyword"},
+      "deleted_at":          {"type": "date"},
+      "status_change":       {"properties": {"from": {"type": "keyword"}, "reason": {"type": "text"}, "actor": {"type": "keyword"}, "at": {"type": "date"}}},
+      "two_factor":          {"type": "object", "enabled": false},
+      "encrypted":           {"type": "object", "enabled": false},
+      "blind":               {"type": "object", "dynamic": false, "properties": {"comment": {"type": "keyword"}}}
     }
   }
 }`
@@ -34,11 +49,14 @@ const mappings = `{
 type Elastic struct {
 	Client *elasticsearch.Client
 	logger *slog.Logger
+	// envelope и encrypted задаёт EnableFieldEncryption: ключи и шифруемые поля с режимом поиска
+	envelope  *secrets.Envelope
+	encrypted map[string]string
 }
 
 type elasticHit struct {
-	ID     string      `json:"_id"`
-	Source domain.User `json:"_source"`
+	ID     string     `json:"_id"`
+	Source storedUser `json:"_source"`
 }
 
 type elasticResponse struct {
@@ -54,7 +72,7 @@ type elasticResponse2 struct {
     SeqNo       int            `json:"_seq_no"`
     PrimaryTerm int            `json:"_primary_term"`
     Found       bool           `json:"found"`
-    Source      domain.User    `json:"_source"`
+    Source      storedUser     `json:"_source"`
 }
 
 var _ domain.UserRepository = (*Elastic)(nil) //проверка, что Elastic реализует интерфейс UserRepository
@@ -85,45 +103,75 @@ func Init(ctx context.Context, url string, logger *slog.Logger) (*Elastic, error
 	}
 	defer res.Body.Close()
 	log.Debug("cluster info", "status", res.Status())
-	exists, err := client.Indices.Exists([]string{usersIndex})
+	if err := ensureIndex(ctx, client, usersIndex, mappings, log); err != nil {
+		return nil, err
+	}
+
+	log.Info("Elasticsearch initialized")
+	return &Elastic{Client: client, logger: logger}, nil
+}
+
+// ensureIndex создаёт индекс с маппингом, а для существующего индекса дописывает новые поля
+func ensureIndex(ctx context.Context, client *elasticsearch.Client, index, mapping string, log *slog.Logger) error {
+	exists, err := client.Indices.Exists([]string{index}, client.Indices.Exists.WithContext(ctx))
 	if err != nil {
-		log.Error("index check failed", "error", err)
-		return nil, service.NewServiceError(
-			service.ErrCodeInternal)
+		log.Error("index check failed", "error", err, "index", index)
+		return service.StorageError(err)
 	}
 	defer exists.Body.Close()
 
 	if exists.StatusCode == 404 {
-		log.Info("index not found, creating", "index", usersIndex)
-		mapping := mappings
+		log.Info("index not found, creating", "index", index)
 
 		createRes, err := client.Indices.Create(
-			usersIndex,
+			index,
 			client.Indices.Create.WithBody(strings.NewReader(mapping)),
+			client.Indices.Create.WithContext(ctx),
 		)
 		if err != nil {
-			log.Error("index creation failed", "error", err)
-			return nil, service.NewServiceError(
-				service.ErrCodeInternal)
+			log.Error("index creation failed", "error", err, "index", index)
+			return service.StorageError(err)
 		}
 		defer createRes.Body.Close()
 
 		if createRes.IsError() {
 			errBody, _ := io.ReadAll(createRes.Body)
-			log.Error("index creation error", "response", string(errBody))
-			return nil, service.NewServiceError(
-				service.ErrCodeInternal)
+			log.Error("index creation error", "response", string(errBody), "index", index)
+			return responseError(createRes)
 		}
-		log.Info("index created", "index", usersIndex)
-	} else {
-		log.Debug("index exists", "index", usersIndex)
+		log.Info("index created", "index", index)
+		return nil
 	}
 
-	log.Info("Elasticsearch initialized")
-	return &Elastic{Client: client, logger: logger}, nil
+	var parsed struct {
+		Mappings json.RawMessage `json:"mappings"`
+	}
+	if err := json.Unmarshal([]byte(mapping), &parsed); err != nil {
+		log.Error("invalid mapping", "error", err, "index", index)
+		return service.StorageError(err)
+	}
+
+	putRes, err := client.Indices.PutMapping(
+		[]string{index},
+		bytes.NewReader(parsed.Mappings),
+		client.Indices.PutMapping.WithContext(ctx),
+	)
+	if err != nil {
+		log.Error("mapping update failed", "error", err, "index", index)
+		return service.StorageError(err)
+	}
+	defer putRes.Body.Close()
+
+	if putRes.IsError() {
+		// Несовместимое изменение типа поля не блокирует запуск, но требует ручной миграции
+		log.Warn("mapping update rejected", "index", index, "response", putRes.String())
+		return nil
+	}
+	log.Debug("index exists, mapping updated", "index", index)
+	return nil
 }
 
-func (e *Elastic) Create(ctx context.Context, user *domain.User) error {
+func (e *Elastic) Create(ctx context.Context, user *domain.User, events ...domain.UserEvent) error {
 	const op = "Elastic.Create"
 	log := e.logger.With("operation", op, "user_id", user.ID)
 
@@ -136,10 +184,20 @@ func (e *Elastic) Create(ctx context.Context, user *domain.User) error {
 		log.DebugContext(ctx, "generated new user ID")
 	}
 
+	doc, err := e.sealUser(*user.ID, user)
+	if err != nil {
+		log.ErrorContext(ctx, "field encryption failed", "error", err)
+		return service.StorageError(err)
+	}
+
+	if len(events) > 0 {
+		return e.writeWithEvents(ctx, op, "create", *user.ID, nil, doc, events)
+	}
+
 	var buf bytes.Buffer
-	if err := json.NewEncoder(&buf).Encode(user); err != nil {
+	if err := json.NewEncoder(&buf).Encode(doc); err != nil {
 		log.ErrorContext(ctx, "document encoding failed", "error", err)
-		return service.NewServiceError(service.ErrCodeInternal)
+		return service.StorageError(err)
 	}
 
 	res, err := e.Client.Create(
@@ -151,7 +209,7 @@ func (e *Elastic) Create(ctx context.Context, user *domain.User) error {
 	)
 	if err != nil {
 		log.ErrorContext(ctx, "index request failed", "error", err)
-		return service.NewServiceError(service.ErrCodeInternal)
+		return service.StorageError(err)
 	}
 	defer res.Body.Close()
 
@@ -160,7 +218,7 @@ func (e *Elastic) Create(ctx context.Context, user *domain.User) error {
 			return service.NewServiceError(service.ErrCodeAlreadyExists)
 		}
 		log.ErrorContext(ctx, "index response error", "status", res.Status(), "response", res.String())
-		return service.NewServiceError(service.ErrCodeInternal)
+		return responseError(res)
 	}
 
 	log.InfoContext(ctx, "user created", "duration", time.Since(start), "index", usersIndex)
@@ -176,7 +234,7 @@ func (e *Elastic) GetByID(ctx context.Context, id *string) (*domain.User, error)
 	res, err := e.Client.Get(usersIndex, *id, e.Client.Get.WithContext(ctx))
 	if err != nil {
 		log.ErrorContext(ctx, "get request failed", "error", err)
-		return nil, service.NewServiceError(service.ErrCodeInternal)
+		return nil, service.StorageError(err)
 	}
 	defer res.Body.Close()
 
@@ -186,50 +244,159 @@ func (e *Elastic) GetByID(ctx context.Context, id *string) (*domain.User, error)
 
 	if res.IsError() {
 		log.ErrorContext(ctx, "get response error", "status", res.Status(), "response", res.String())
-		return nil, service.NewServiceError(service.ErrCodeInternal)
+		return nil, responseError(res)
 	}
 
 	var user *domain.User
 	var elasticUser elasticResponse2
 	if err := json.NewDecoder(res.Body).Decode(&elasticUser); err != nil {
 		log.ErrorContext(ctx, "document decoding failed", "error", err)
-		return nil, service.NewServiceError(service.ErrCodeInternal)
+		return nil, service.StorageError(err)
 	}
-	user = &elasticUser.Source
+	if err := e.openUser(*id, &elasticUser.Source); err != nil {
+		log.ErrorContext(ctx, "field decryption failed", "error", err)
+		return nil, service.StorageError(err)
+	}
+	user = &elasticUser.Source.User
+	user.Revision = formatRevision(elasticUser.SeqNo, elasticUser.PrimaryTerm)
 	log.DebugContext(ctx, "user fetched", "duration", time.Since(start))
 	return user, nil
 }
 
-func (e *Elastic) UpdatePartial(ctx context.Context, user *domain.User) error {
+// GetByLogin ищет пользователя через резерв нормализованного логина, поэтому John_Doe и john_doe
+// находят одну учётную запись. Логин без резерва ищется по точному совпадению.
+func (e *Elastic) GetByLogin(ctx context.Context, login *string) (*domain.User, error) {
+	const op = "Elastic.GetByLogin"
+	log := e.logger.With("operation", op, "login", login)
+	log.DebugContext(ctx, "fetching user by login")
+	start := time.Now()
+
+	userID, err := e.reservedBy(ctx, logins.Normalize(*login))
+	if err != nil {
+		log.ErrorContext(ctx, "login reservation lookup failed", "error", err)
+		return nil, err
+	}
+	if userID != "" {
+		user, err := e.GetByID(ctx, &userID)
+		if err != nil {
+			return nil, err
+		}
+		user.ID = &userID
+		log.DebugContext(ctx, "user fetched by reservation", "duration", time.Since(start))
+		return user, nil
+	}
+
+	query := map[string]any{
+		"query": map[string]any{
+			"term": map[string]any{
+				"login.keyword": *login,
+			},
+		},
+		"size": 1,
+	}
+	b, err := json.Marshal(query)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to build query", "error", err)
+		return nil, service.StorageError(err)
+	}
+
+	res, err := e.Client.Search(
+		e.Client.Search.WithIndex(usersIndex),
+		e.Client.Search.WithContext(ctx),
+		e.Client.Search.WithBody(bytes.NewReader(b)),
+	)
+	if err != nil {
+		log.ErrorContext(ctx, "search request failed", "error", err)
+		return nil, service.StorageError(err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		log.ErrorContext(ctx, "search response error", "status", res.Status(), "response", res.String())
+		return nil, responseError(res)
+	}
+
+	users, err := e.parseResults(res.Body)
+	if err != nil {
+		log.ErrorContext(ctx, "document decoding failed", "error", err)
+		return nil, service.StorageError(err)
+	}
+	if len(users) == 0 {
+		return nil, service.NewServiceError(service.ErrCodeNotFound)
+	}
+
+	log.DebugContext(ctx, "user fetched", "duration", time.Since(start))
+	return users[0], nil
+}
+
+// reservedBy возвращает владельца резерва логина или пустую строку, если резерва нет
+func (e *Elastic) reservedBy(ctx context.Context, normalized string) (string, error) {
+	res, err := e.Client.Get(loginsIndex, url.PathEscape(normalized), e.Client.Get.WithContext(ctx))
+	if err != nil {
+		return "", service.StorageError(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return "", nil
+	}
+	if res.IsError() {
+		return "", responseError(res)
+	}
+
+	var doc struct {
+		Source domain.LoginReservation `json:"_source"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&doc); err != nil {
+		return "", service.StorageError(err)
+	}
+	return doc.Source.UserID, nil
+}
+
+func (e *Elastic) UpdatePartial(ctx context.Context, user *domain.User, events ...domain.UserEvent) error {
 	const op = "Elastic.UpdatePartial"
 	id := *user.ID
 	user.ID = nil
 	log := e.logger.With("operation", op, "user_id", id)
-	log.DebugContext(ctx, "updating user", "fields", user)
+	log.DebugContext(ctx, "updating user", "fields", fieldNames(user))
 	start := time.Now()
 
+	doc, err := e.sealUser(id, user)
+	if err != nil {
+		log.ErrorContext(ctx, "field encryption failed", "error", err)
+		return service.StorageError(err)
+	}
 	updateBody := struct {
-        Doc *domain.User `json:"doc"`
+        Doc any `json:"doc"`
     }{
-        Doc: user,
+        Doc: doc,
     }
 
+	if len(events) > 0 {
+		return e.writeWithEvents(ctx, op, "update", id, &user.Revision, updateBody, events)
+	}
+
 	var buf bytes.Buffer
 	if err := json.NewEncoder(&buf).Encode(updateBody); err != nil {
 		log.ErrorContext(ctx, "document encoding failed", "error", err)
-		return service.NewServiceError(service.ErrCodeInternal)
+		return service.StorageError(err)
 	}
 
-	res, err := e.Client.Update(
-		usersIndex,
-		id,
-		&buf,
+	opts := []func(*esapi.UpdateRequest){
 		e.Client.Update.WithContext(ctx),
 		e.Client.Update.WithRefresh("wait_for"),
-	)
+	}
+	if user.Revision != "" {
+		seqNo, primaryTerm, err := parseRevision(user.Revision)
+		if err != nil {
+			return service.StorageError(err)
+		}
+		opts = append(opts, e.Client.Update.WithIfSeqNo(seqNo), e.Client.Update.WithIfPrimaryTerm(primaryTerm))
+	}
+	res, err := e.Client.Update(usersIndex, id, &buf, opts...)
 	if err != nil {
 		log.ErrorContext(ctx, "update request failed", "error", err)
-		return service.NewServiceError(service.ErrCodeInternal)
+		return service.StorageError(err)
 	}
 	defer res.Body.Close()
 
@@ -239,37 +406,54 @@ func (e *Elastic) UpdatePartial(ctx context.Context, user *domain.User) error {
 
 	if res.IsError() {
 		log.ErrorContext(ctx, "update response error", "status", res.Status(), "response", res.String())
-		return service.NewServiceError(service.ErrCodeInternal)
+		return responseError(res)
 	}
+	user.Revision = decodeRevision(res.Body)
 
 	log.InfoContext(ctx, "user updated", "duration", time.Since(start))
 	return nil
 }
 
-func (e *Elastic) Replace(ctx context.Context, user *domain.User) error {
+func (e *Elastic) Replace(ctx context.Context, user *domain.User, events ...domain.UserEvent) error {
 	const op = "Elastic.Replace"
-	log := e.logger.With("operation", op, "user_id", user.ID)
+	log := e.logger.With("operation", op, "user_id", *user.ID)
 
-	log.DebugContext(ctx, "replace user", "fields", user)
+	log.DebugContext(ctx, "replace user", "fields", fieldNames(user))
 	start := time.Now()
 
+	doc, err := e.sealUser(*user.ID, user)
+	if err != nil {
+		log.ErrorContext(ctx, "field encryption failed", "error", err)
+		return service.StorageError(err)
+	}
+
+	if len(events) > 0 {
+		return e.writeWithEvents(ctx, op, "index", *user.ID, &user.Revision, doc, events)
+	}
+
 	var buf bytes.Buffer
-	if err := json.NewEncoder(&buf).Encode(user); err != nil {
+	if err := json.NewEncoder(&buf).Encode(doc); err != nil {
 		log.ErrorContext(ctx, "document encoding failed", "error", err)
-		return service.NewServiceError(service.ErrCodeInternal)
+		return service.StorageError(err)
 	}
 
-	res, err := e.Client.Index(
-		usersIndex,
-		&buf,
+	opts := []func(*esapi.IndexRequest){
 		e.Client.Index.WithDocumentID(*user.ID),
 		e.Client.Index.WithContext(ctx),
 		e.Client.Index.WithRefresh("wait_for"),
-	)
+	}
+	if user.Revision != "" {
+		seqNo, primaryTerm, err := parseRevision(user.Revision)
+		if err != nil {
+			return service.StorageError(err)
+		}
+		opts = append(opts, e.Client.Index.WithIfSeqNo(seqNo), e.Client.Index.WithIfPrimaryTerm(primaryTerm))
+	}
+	res, err := e.Client.Index(usersIndex, &buf, opts...)
 
 	if err != nil {
 		log.ErrorContext(ctx, "replace request failed", "error", err)
-		return service.NewServiceError(service.ErrCodeInternal)
+		return service.StorageError(err)
 	}
 	defer res.Body.Close()
 
@@ -282,21 +466,26 @@ func (e *Elastic) Replace(ctx context.Context, user *domain.User) error {
 		log.ErrorContext(ctx, "replace response error",
 			"status", res.Status(),
 			"response", res.String())
-		return service.NewServiceError(service.ErrCodeInternal)
+		return responseError(res)
 	}
+	user.Revision = decodeRevision(res.Body)
 
 	log.InfoContext(ctx, "user replaced",
 		"duration", time.Since(start))
 	return nil
 }
 
-func (e *Elastic) Delete(ctx context.Context, id *string) error {
+func (e *Elastic) Delete(ctx context.Context, id *string, events ...domain.UserEvent) error {
 	const op = "Elastic.Delete"
 	log := e.logger.With("operation", op, "user_id", id)
 
 	log.InfoContext(ctx, "deleting user")
 	start := time.Now()
 
+	if len(events) > 0 {
+		return e.writeWithEvents(ctx, op, "delete", *id, nil, nil, events)
+	}
+
 	res, err := e.Client.Delete(
 		usersIndex,
 		*id,
@@ -305,7 +494,7 @@ func (e *Elastic) Delete(ctx context.Context, id *string) error {
 	)
 	if err != nil {
 		log.ErrorContext(ctx, "delete request failed", "error", err)
-		return service.NewServiceError(service.ErrCodeInternal)
+		return service.StorageError(err)
 	}
 	defer res.Body.Close()
 
@@ -318,7 +507,7 @@ func (e *Elastic) Delete(ctx context.Context, id *string) error {
 		log.ErrorContext(ctx, "delete response error",
 			"status", res.Status(),
 			"response", res.String())
-		return service.NewServiceError(service.ErrCodeInternal)
+		return responseError(res)
 	}
 
 	log.InfoContext(ctx, "user deleted",
@@ -336,7 +525,7 @@ func (e *Elastic) Search(ctx context.Context, filters *domain.UserFilter) ([]*do
 	reader, err := e.buildElasticsearchQuery(filters)
 	if err != nil {
 		log.ErrorContext(ctx, "failed to build query", "error", err)
-		return nil, service.NewServiceError(service.ErrCodeInternal)
+		return nil, service.StorageError(err)
 	}
 
 	res, err := e.Client.Search(
@@ -346,7 +535,7 @@ func (e *Elastic) Search(ctx context.Context, filters *domain.UserFilter) ([]*do
 	)
 	if err != nil {
 		log.ErrorContext(ctx, "search request failed", "error", err)
-		return nil, service.NewServiceError(service.ErrCodeInternal)
+		return nil, service.StorageError(err)
 	}
 	defer res.Body.Close()
 
@@ -358,13 +547,13 @@ func (e *Elastic) Search(ctx context.Context, filters *domain.UserFilter) ([]*do
 		log.ErrorContext(ctx, "search response error",
 			"status", res.Status(),
 			"response", res.String())
-		return nil, service.NewServiceError(service.ErrCodeInternal)
+		return nil, responseError(res)
 	}
 
 	var results []*domain.User
-	if results, err = parseResults(res.Body); err != nil {
+	if results, err = e.parseResults(res.Body); err != nil {
 		log.ErrorContext(ctx, "document decoding failed", "error", err)
-		return nil, service.NewServiceError(service.ErrCodeInternal)
+		return nil, service.StorageError(err)
 	}
 	log.InfoContext(ctx, "search completed",
 		"result_count", len(results),
@@ -373,11 +562,17 @@ func (e *Elastic) Search(ctx context.Context, filters *domain.UserFilter) ([]*do
 	return results, nil
 }
 
-func parseResults(r io.ReadCloser) ([]*domain.User, error) {
+func (e *Elastic) parseResults(r io.ReadCloser) ([]*domain.User, error) {
 
 	var response elasticResponse
 	if err := json.NewDecoder(r).Decode(&response); err != nil {
-		return nil, service.NewServiceError(service.ErrCodeInternal)
+		return nil, service.StorageError(err)
+	}
+	for i := range response.Hits.Hits {
+		hit := &response.Hits.Hits[i]
+		if err := e.openUser(hit.ID, &hit.Source); err != nil {
+			return nil, service.StorageError(err)
+		}
 	}
 
 	return shoveTheId(response.Hits.Hits), nil
@@ -394,10 +589,24 @@ func (e *Elastic) Close() error {
 	return nil
 }
 
+// fieldNames имена заполненных полей пользователя. В отладочный лог пишутся только они:
+// значения содержат хеш пароля, секреты 2FA и расшифрованные поля.
+func fieldNames(user *domain.User) []string {
+	raw, err := json.Marshal(user)
+	if err != nil {
+		return nil
+	}
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil
+	}
+	return slices.Sorted(maps.Keys(doc))
+}
+
 func shoveTheId(hits []elasticHit) []*domain.User {
 	users := make([]*domain.User, len(hits))
 	for i := range hits {
-		user := &hits[i].Source
+		user := &hits[i].Source.User
 
 		id := hits[i].ID
 		user.ID = &id
@@ -420,16 +629,23 @@ func (e *Elastic) buildElasticsearchQuery(f *domain.UserFilter) (io.Reader, erro
 	if f.Search != nil && *f.Search != "" {
 		searchQuery := map[string]any{
 			"multi_match": map[string]any{
-				"query": *f.Search,
-				"fields": []string{
-					"username",
-					"login",
-					"comment",
-					"description",
-				},
-				"type": "best_fields",
+				"query":  *f.Search,
+				"fields": e.searchFields(),
+				"type":   "best_fields",
 			},
 		}
+		// Зашифрованный комментарий находится только по точному совпадению с текстом поиска
+		if e.encrypted["comment"] == domain.EncryptedSearchExact {
+			searchQuery = map[string]any{
+				"bool": map[string]any{
+					"should": []map[string]any{
+						searchQuery,
+						{"terms": map[string]any{"blind.comment": e.blindIndexes("comment", strings.TrimSpace(*f.Search))}},
+					},
+					"minimum_should_match": 1,
+				},
+			}
+		}
 		mustQueries = append(mustQueries, searchQuery)
 	}
 	if f.DateFrom != nil || f.DateTo != nil {
@@ -460,13 +676,41 @@ func (e *Elastic) buildElasticsearchQuery(f *domain.UserFilter) (io.Reader, erro
 		}
 		mustQueries = append(mustQueries, locationFilter)
 	}
+	socialQueries := []map[string]any{}
 	if f.SocialType != nil && *f.SocialType != "" {
-		socialTypeFilter := map[string]any{
-			"term": map[string]any{
-				"social_net": *f.SocialType,
+		socialQueries = append(socialQueries, map[string]any{
+			"term": map[string]any{"social_profiles.network": *f.SocialType},
+		})
+	}
+	if f.SocialHandle != nil && *f.SocialHandle != "" {
+		socialQueries = append(socialQueries, map[string]any{
+			"term": map[string]any{"social_profiles.handle": *f.SocialHandle},
+		})
+	}
+	if len(socialQueries) > 0 {
+		// nested, чтобы сеть и ник совпали в одном профиле
+		socialFilter := map[string]any{
+			"nested": map[string]any{
+				"path": "social_profiles",
+				"query": map[string]any{
+					"bool": map[string]any{"must": socialQueries},
+				},
 			},
 		}
-		mustQueries = append(mustQueries, socialTypeFilter)
+		mustQueries = append(mustQueries, socialFilter)
+	}
+	mustQueries = append(mustQueries, statusFilter(f.Status))
+	for _, a := range f.Attributes {
+		field := "attributes." + a.Name
+		if a.Op == "eq" {
+			mustQueries = append(mustQueries, map[string]any{
+				"term": map[string]any{field: a.Value},
+			})
+			continue
+		}
+		mustQueries = append(mustQueries, map[string]any{
+			"range": map[string]any{field: map[string]any{a.Op: a.Value}},
+		})
 	}
 	if len(mustQueries) == 0 {
 		query["query"] = map[string]any{
@@ -517,3 +761,38 @@ func (e *Elastic) buildElasticsearchQuery(f *domain.UserFilter) (io.Reader, erro
 
 	return bytes.NewReader(b), nil
 }
+
+// searchFields поля полнотекстового поиска, зашифрованные в нём не участвуют
+func (e *Elastic) searchFields() []string {
+	fields := make([]string, 0, 4)
+	for _, field := range []string{"username", "login", "comment", "description"} {
+		if _, ok := e.encrypted[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// statusFilter без явного списка исключает заблокированных и удалённых.
+// У документов без поля status статус active.
+func statusFilter(statuses []string) map[string]any {
+	if len(statuses) == 0 {
+		return map[string]any{
+			"bool": map[string]any{
+				"must_not": map[string]any{"terms": map[string]any{"status": domain.HiddenStatuses}},
+			},
+		}
+	}
+
+	should := []map[string]any{
+		{"terms": map[string]any{"status": statuses}},
+	}
+	if slices.Contains(statuses, domain.StatusActive) {
+		should = append(should, map[string]any{
+			"bool": map[string]any{"must_not": map[string]any{"exists": map[string]any{"field": "status"}}},
+		})
+	}
+	return map[string]any{
+		"bool": map[string]any{"should": should, "minimum_should_match": 1},
+	}
+}
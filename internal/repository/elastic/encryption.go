@@ -0,0 +1,292 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/satrunjis/user-service/internal/domain"
+	"github.com/satrunjis/user-service/internal/secrets"
+	"github.com/satrunjis/user-service/internal/service"
+)
+
+// encryptableFields поля, которые можно хранить зашифрованными, и допустимые режимы поиска.
+// Геоточку по точному значению не ищут, поэтому для location только none.
+var encryptableFields = map[string][]string{
+	"comment":  {domain.EncryptedSearchNone, domain.EncryptedSearchExact},
+	"location": {domain.EncryptedSearchNone},
+}
+
+// storedUser документ индекса users. Зашифрованные значения лежат в encrypted, открытое поле
+// при этом пустое; слепые индексы для поиска по точному значению - в blind.
+type storedUser struct {
+	domain.User
+	Encrypted map[string]string `json:"encrypted,omitempty"`
+	Blind     map[string]string `json:"blind,omitempty"`
+}
+
+// ParseEncryptedFields разбирает список "<поле>[:<режим поиска>]" через запятую, по умолчанию режим none
+func ParseEncryptedFields(spec string) ([]domain.EncryptedField, error) {
+	var fields []domain.EncryptedField
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, mode, _ := strings.Cut(item, ":")
+		name = strings.TrimSpace(name)
+		mode = strings.TrimSpace(mode)
+		if mode == "" {
+			mode = domain.EncryptedSearchNone
+		}
+		modes, ok := encryptableFields[name]
+		if !ok {
+			return nil, fmt.Errorf("field %q cannot be encrypted", name)
+		}
+		if !slices.Contains(modes, mode) {
+			return nil, fmt.Errorf("field %q supports search modes %s, got %q", name, strings.Join(modes, ", "), mode)
+		}
+		if slices.ContainsFunc(fields, func(f domain.EncryptedField) bool { return f.Field == name }) {
+			return nil, fmt.Errorf("field %q is listed twice", name)
+		}
+		fields = append(fields, domain.EncryptedField{Field: name, Search: mode})
+	}
+	return fields, nil
+}
+
+// EnableFieldEncryption включает шифрование полей. Без него зашифрованные документы не читаются,
+// поэтому ключи нужны и для того, чтобы выключить шифрование и расшифровать данные через ReencryptUsers.
+func (e *Elastic) EnableFieldEncryption(envelope *secrets.Envelope, fields []domain.EncryptedField) {
+	e.envelope = envelope
+	e.encrypted = make(map[string]string, len(fields))
+	for _, f := range fields {
+		e.encrypted[f.Field] = f.Search
+	}
+}
+
+func (e *Elastic) EncryptedFields() []domain.EncryptedField {
+	fields := make([]domain.EncryptedField, 0, len(e.encrypted))
+	for name, mode := range e.encrypted {
+		fields = append(fields, domain.EncryptedField{Field: name, Search: mode})
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Field < fields[j].Field })
+	return fields
+}
+
+// sealUser готовит документ к записи: значения шифруемых полей переносятся в encrypted,
+// а само поле записывается как null, чтобы частичное обновление стёрло прежнее открытое значение
+func (e *Elastic) sealUser(id string, user *domain.User) (any, error) {
+	if len(e.encrypted) == 0 {
+		return user, nil
+	}
+
+	raw, err := json.Marshal(user)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]any
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	encrypted := map[string]any{}
+	blind := map[string]any{}
+	for field, mode := range e.encrypted {
+		value, ok := doc[field]
+		if !ok {
+			continue
+		}
+		plain, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		sealed, err := e.envelope.Encrypt(plain, fieldAAD(id, field))
+		if err != nil {
+			return nil, err
+		}
+		doc[field] = nil
+		encrypted[field] = sealed
+		switch {
+		case mode == domain.EncryptedSearchExact:
+			blind[field] = e.envelope.BlindIndex(plain, []byte(field))
+		case slices.Contains(encryptableFields[field], domain.EncryptedSearchExact):
+			// Режим сменили на none, прежний слепой индекс больше не нужен
+			blind[field] = nil
+		}
+	}
+	if len(encrypted) > 0 {
+		doc["encrypted"] = encrypted
+		doc["blind"] = blind
+	}
+	return doc, nil
+}
+
+// openUser расшифровывает поля документа в doc.User
+func (e *Elastic) openUser(id string, doc *storedUser) error {
+	opened := map[string]json.RawMessage{}
+	for field, value := range doc.Encrypted {
+		if value == "" {
+			continue
+		}
+		if e.envelope == nil {
+			return fmt.Errorf("user %s: field %s is encrypted, but no master key is configured", id, field)
+		}
+		plain, err := e.envelope.Decrypt(value, fieldAAD(id, field))
+		if err != nil {
+			return fmt.Errorf("user %s: field %s: %w", id, field, err)
+		}
+		opened[field] = plain
+	}
+	if len(opened) == 0 {
+		return nil
+	}
+	raw, err := json.Marshal(opened)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, &doc.User)
+}
+
+// blindIndexes значения слепого индекса поля для поиска по точному совпадению
+func (e *Elastic) blindIndexes(field string, value any) []string {
+	plain, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	return e.envelope.BlindIndexes(plain, []byte(field))
+}
+
+// fieldAAD привязывает шифртекст к пользователю и полю
+func fieldAAD(id, field string) []byte {
+	return []byte(usersIndex + "/" + id + "/" + field)
+}
+
+// needsReencryption документ хранит открытым поле, которое должно быть зашифровано, зашифрован
+// прежним мастер-ключом или зашифровано поле, которое больше не шифруется
+func (e *Elastic) needsReencryption(raw map[string]json.RawMessage, doc *storedUser) bool {
+	for field, mode := range e.encrypted {
+		if value, ok := raw[field]; ok && string(value) != "null" {
+			return true
+		}
+		sealed := doc.Encrypted[field]
+		if sealed == "" {
+			continue
+		}
+		if !e.envelope.Current(sealed) {
+			return true
+		}
+		hasBlind := doc.Blind[field] != ""
+		if hasBlind != (mode == domain.EncryptedSearchExact) {
+			return true
+		}
+		if hasBlind && !strings.HasPrefix(doc.Blind[field], e.envelope.KeyID()+":") {
+			return true
+		}
+	}
+	for field, sealed := range doc.Encrypted {
+		if _, ok := e.encrypted[field]; !ok && sealed != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// ReencryptUsers приводит документы к текущим настройкам: шифрует открытые значения, перешифровывает
+// прежним мастер-ключом и расшифровывает поля, исключённые из шифрования. Документ, изменённый
+// во время обхода, пропускается, его подхватит следующий запуск. Возвращает число перезаписанных.
+func (e *Elastic) ReencryptUsers(ctx context.Context, batch int) (int, error) {
+	const op = "Elastic.ReencryptUsers"
+	log := e.logger.With("operation", op)
+	start := time.Now()
+
+	if e.envelope == nil {
+		return 0, service.StorageError(fmt.Errorf("field encryption is not configured"))
+	}
+	if batch <= 0 {
+		batch = 500
+	}
+
+	// Обход по снимку индекса: перезапись документа не меняет порядок и не сдвигает страницы
+	cursor, err := e.openScan(ctx, log)
+	if err != nil {
+		return 0, err
+	}
+	defer e.closeScan(context.WithoutCancel(ctx), log, cursor)
+
+	rewritten, skipped := 0, 0
+	for {
+		hits, err := e.scanPage(ctx, log, cursor, batch, true)
+		if err != nil {
+			return rewritten, err
+		}
+		if len(hits) == 0 {
+			break
+		}
+
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		var ids []string
+		for _, hit := range hits {
+			var raw map[string]json.RawMessage
+			var doc storedUser
+			if err := json.Unmarshal(hit.Source, &raw); err != nil {
+				return rewritten, service.StorageError(err)
+			}
+			if err := json.Unmarshal(hit.Source, &doc); err != nil {
+				return rewritten, service.StorageError(err)
+			}
+			if !e.needsReencryption(raw, &doc) {
+				continue
+			}
+			if err := e.openUser(hit.ID, &doc); err != nil {
+				log.ErrorContext(ctx, "failed to decrypt user", "user_id", hit.ID, "error", err)
+				return rewritten, service.StorageError(err)
+			}
+			sealed, err := e.sealUser(hit.ID, &doc.User)
+			if err != nil {
+				return rewritten, service.StorageError(err)
+			}
+			meta := map[string]any{"_index": usersIndex, "_id": hit.ID, "if_seq_no": hit.SeqNo, "if_primary_term": hit.PrimaryTerm}
+			if err := enc.Encode(map[string]any{"index": meta}); err != nil {
+				return rewritten, service.StorageError(err)
+			}
+			if err := enc.Encode(sealed); err != nil {
+				return rewritten, service.StorageError(err)
+			}
+			ids = append(ids, hit.ID)
+		}
+
+		if len(ids) > 0 {
+			items, err := bulk(ctx, e.Client, &buf)
+			if err != nil {
+				log.ErrorContext(ctx, "bulk request failed", "error", err)
+				return rewritten, err
+			}
+			for i, item := range items {
+				switch {
+				case item.Status == 409:
+					skipped++
+				case item.Status >= 300:
+					log.ErrorContext(ctx, "user was not re-encrypted", "user_id", ids[i], "status", item.Status, "error", string(item.Error))
+					return rewritten, statusError(item.Status, fmt.Errorf("re-encryption of %s responded %d", ids[i], item.Status))
+				default:
+					rewritten++
+				}
+			}
+			log.InfoContext(ctx, "re-encryption in progress", "rewritten", rewritten, "skipped", skipped)
+		}
+		if len(hits) < batch {
+			break
+		}
+	}
+
+	log.InfoContext(ctx, "re-encryption finished", "key_id", e.envelope.KeyID(), "rewritten", rewritten, "skipped", skipped, "duration", time.Since(start))
+	return rewritten, nil
+}
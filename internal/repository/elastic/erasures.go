@@ -0,0 +1,211 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/satrunjis/user-service/internal/domain"
+	"github.com/satrunjis/user-service/internal/service"
+)
+
+const erasureJobsIndex = "erasure_jobs"
+
+// Шаги и квитанция нужны только целиком, поиск идёт по служебным полям
+const erasureJobsMappings = `{
+  "mappings": {
+    "properties": {
+      "id":           {"type": "keyword"},
+      "user_id":      {"type": "keyword"},
+      "pseudonym":    {"type": "keyword"},
+      "status":       {"type": "keyword"},
+      "steps":        {"type": "object", "enabled": false},
+      "requested_by": {"type": "keyword"},
+      "request_id":   {"type": "keyword"},
+      "created_at":   {"type": "date"},
+      "updated_at":   {"type": "date"},
+      "completed_at": {"type": "date"},
+      "receipt":      {"type": "object", "enabled": false}
+    }
+  }
+}`
+
+type ErasureJobs struct {
+	client *elasticsearch.Client
+	logger *slog.Logger
+}
+
+var _ domain.ErasureJobRepository = (*ErasureJobs)(nil)
+
+func NewErasureJobs(ctx context.Context, e *Elastic) (*ErasureJobs, error) {
+	log := e.logger.With("operation", "elastic.NewErasureJobs")
+	if err := ensureIndex(ctx, e.Client, erasureJobsIndex, erasureJobsMappings, log); err != nil {
+		return nil, err
+	}
+	return &ErasureJobs{client: e.Client, logger: e.logger}, nil
+}
+
+func (r *ErasureJobs) Create(ctx context.Context, job *domain.ErasureJob) error {
+	const op = "ErasureJobs.Create"
+	log := r.logger.With("operation", op, "job_id", job.ID)
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(job); err != nil {
+		log.ErrorContext(ctx, "document encoding failed", "error", err)
+		return service.StorageError(err)
+	}
+
+	res, err := r.client.Create(
+		erasureJobsIndex,
+		job.ID,
+		&buf,
+		r.client.Create.WithContext(ctx),
+		r.client.Create.WithRefresh("wait_for"),
+	)
+	if err != nil {
+		log.ErrorContext(ctx, "index request failed", "error", err)
+		return service.StorageError(err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		if res.StatusCode == 409 {
+			return service.NewServiceError(service.ErrCodeAlreadyExists)
+		}
+		log.ErrorContext(ctx, "index response error", "status", res.Status(), "response", res.String())
+		return responseError(res)
+	}
+	return nil
+}
+
+func (r *ErasureJobs) GetByID(ctx context.Context, id string) (*domain.ErasureJob, error) {
+	const op = "ErasureJobs.GetByID"
+	log := r.logger.With("operation", op, "job_id", id)
+
+	res, err := r.client.Get(erasureJobsIndex, id, r.client.Get.WithContext(ctx))
+	if err != nil {
+		log.ErrorContext(ctx, "get request failed", "error", err)
+		return nil, service.StorageError(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return nil, service.NewServiceError(service.ErrCodeNotFound)
+	}
+	if res.IsError() {
+		log.ErrorContext(ctx, "get response error", "status", res.Status(), "response", res.String())
+		return nil, responseError(res)
+	}
+
+	var doc struct {
+		Source domain.ErasureJob `json:"_source"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&doc); err != nil {
+		log.ErrorContext(ctx, "document decoding failed", "error", err)
+		return nil, service.StorageError(err)
+	}
+	return &doc.Source, nil
+}
+
+func (r *ErasureJobs) Save(ctx context.Context, job *domain.ErasureJob) error {
+	const op = "ErasureJobs.Save"
+	log := r.logger.With("operation", op, "job_id", job.ID)
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(job); err != nil {
+		log.ErrorContext(ctx, "document encoding failed", "error", err)
+		return service.StorageError(err)
+	}
+
+	res, err := r.client.Index(
+		erasureJobsIndex,
+		&buf,
+		r.client.Index.WithDocumentID(job.ID),
+		r.client.Index.WithContext(ctx),
+		r.client.Index.WithRefresh("wait_for"),
+	)
+	if err != nil {
+		log.ErrorContext(ctx, "index request failed", "error", err)
+		return service.StorageError(err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		log.ErrorContext(ctx, "index response error", "status", res.Status(), "response", res.String())
+		return responseError(res)
+	}
+	return nil
+}
+
+func (r *ErasureJobs) Runnable(ctx context.Context, limit int) ([]*domain.ErasureJob, error) {
+	query := map[string]any{
+		"query": map[string]any{"terms": map[string]any{"status": []string{domain.ErasurePending, domain.ErasureRunning}}},
+		"sort":  []map[string]any{{"created_at": map[string]any{"order": "asc"}}},
+		"size":  limit,
+	}
+	return r.search(ctx, "ErasureJobs.Runnable", query)
+}
+
+func (r *ErasureJobs) ActiveForUser(ctx context.Context, userID string) (*domain.ErasureJob, error) {
+	query := map[string]any{
+		"query": map[string]any{"bool": map[string]any{
+			"filter":   []map[string]any{{"term": map[string]any{"user_id": userID}}},
+			"must_not": []map[string]any{{"term": map[string]any{"status": domain.ErasureCompleted}}},
+		}},
+		"sort": []map[string]any{{"created_at": map[string]any{"order": "desc"}}},
+		"size": 1,
+	}
+	jobs, err := r.search(ctx, "ErasureJobs.ActiveForUser", query)
+	if err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, service.NewServiceError(service.ErrCodeNotFound)
+	}
+	return jobs[0], nil
+}
+
+func (r *ErasureJobs) search(ctx context.Context, op string, query map[string]any) ([]*domain.ErasureJob, error) {
+	log := r.logger.With("operation", op)
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(query); err != nil {
+		return nil, service.StorageError(err)
+	}
+
+	res, err := r.client.Search(
+		r.client.Search.WithIndex(erasureJobsIndex),
+		r.client.Search.WithContext(ctx),
+		r.client.Search.WithBody(&buf),
+	)
+	if err != nil {
+		log.ErrorContext(ctx, "search request failed", "error", err)
+		return nil, service.StorageError(err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		log.ErrorContext(ctx, "search response error", "status", res.Status(), "response", res.String())
+		return nil, responseError(res)
+	}
+
+	var response struct {
+		Hits struct {
+			Hits []struct {
+				Source domain.ErasureJob `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		log.ErrorContext(ctx, "response decoding failed", "error", err)
+		return nil, service.StorageError(err)
+	}
+
+	jobs := make([]*domain.ErasureJob, len(response.Hits.Hits))
+	for i := range response.Hits.Hits {
+		jobs[i] = &response.Hits.Hits[i].Source
+	}
+	return jobs, nil
+}
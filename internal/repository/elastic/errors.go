@@ -0,0 +1,28 @@
+package elastic
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v9/esapi"
+	"github.com/satrunjis/user-service/internal/service"
+)
+
+// responseError переводит статус ответа Elasticsearch в код ошибки сервиса
+func responseError(res *esapi.Response) error {
+	return statusError(res.StatusCode, fmt.Errorf("elasticsearch responded %s", res.Status()))
+}
+
+func statusError(status int, cause error) error {
+	switch status {
+	case 408, 504:
+		return service.WrapError(service.ErrCodeTimeout, cause)
+	case 429, 502, 503:
+		err := service.WrapError(service.ErrCodeUnavailable, cause)
+		err.RetryAfter = time.Second
+		return err
+	case 409:
+		return service.WrapError(service.ErrCodeConflict, cause)
+	}
+	return service.WrapError(service.ErrCodeInternal, cause)
+}
@@ -0,0 +1,338 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/satrunjis/user-service/internal/domain"
+	"github.com/satrunjis/user-service/internal/logins"
+	"github.com/satrunjis/user-service/internal/service"
+)
+
+const loginsIndex = "logins"
+const loginsMappings = `{
+  "mappings": {
+    "properties": {
+      "normalized":  {"type": "keyword"},
+      "login":       {"type": "keyword"},
+      "user_id":     {"type": "keyword"},
+      "reserved_at": {"type": "date"}
+    }
+  }
+}`
+
+// LoginReservations хранит резервы логинов, ID документа равен нормализованному логину
+type LoginReservations struct {
+	client *elasticsearch.Client
+	logger *slog.Logger
+}
+
+var _ domain.LoginReservationRepository = (*LoginReservations)(nil)
+
+type loginReservationDoc struct {
+	SeqNo       int                     `json:"_seq_no"`
+	PrimaryTerm int                     `json:"_primary_term"`
+	Source      domain.LoginReservation `json:"_source"`
+}
+
+func NewLoginReservations(ctx context.Context, e *Elastic) (*LoginReservations, error) {
+	log := e.logger.With("operation", "elastic.NewLoginReservations")
+
+	if err := ensureIndex(ctx, e.Client, loginsIndex, loginsMappings, log); err != nil {
+		return nil, err
+	}
+
+	r := &LoginReservations{client: e.Client, logger: e.logger}
+	// Пользователи, созданные до появления резервов, занимают свои логины при запуске. Отметка о завершении
+	// ставится только после полного прохода, поэтому прерванное заполнение продолжится при следующем запуске.
+	done, err := r.backfilled(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !done {
+		if err := r.backfill(ctx); err != nil {
+			return nil, err
+		}
+		if err := r.markBackfilled(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func (r *LoginReservations) Reserve(ctx context.Context, reservation *domain.LoginReservation) error {
+	const op = "LoginReservations.Reserve"
+	log := r.logger.With("operation", op, "login", reservation.Login, "user_id", reservation.UserID)
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(reservation); err != nil {
+		log.ErrorContext(ctx, "document encoding failed", "error", err)
+		return service.StorageError(err)
+	}
+
+	res, err := r.client.Create(
+		loginsIndex,
+		url.PathEscape(reservation.Normalized),
+		&buf,
+		r.client.Create.WithContext(ctx),
+		r.client.Create.WithRefresh("true"),
+	)
+	if err != nil {
+		log.ErrorContext(ctx, "index request failed", "error", err)
+		return service.StorageError(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 409 {
+		current, err := r.Get(ctx, reservation.Normalized)
+		if err != nil {
+			return err
+		}
+		if current.UserID == reservation.UserID {
+			return nil
+		}
+		log.DebugContext(ctx, "login already reserved", "owner", current.UserID)
+		return service.NewServiceError(service.ErrCodeAlreadyExists)
+	}
+	if res.IsError() {
+		log.ErrorContext(ctx, "index response error", "status", res.Status(), "response", res.String())
+		return responseError(res)
+	}
+
+	log.DebugContext(ctx, "login reserved")
+	return nil
+}
+
+func (r *LoginReservations) Get(ctx context.Context, normalized string) (*domain.LoginReservation, error) {
+	doc, err := r.get(ctx, normalized)
+	if err != nil {
+		return nil, err
+	}
+	return &doc.Source, nil
+}
+
+func (r *LoginReservations) get(ctx context.Context, normalized string) (*loginReservationDoc, error) {
+	const op = "LoginReservations.Get"
+	log := r.logger.With("operation", op)
+
+	res, err := r.client.Get(loginsIndex, url.PathEscape(normalized), r.client.Get.WithContext(ctx))
+	if err != nil {
+		log.ErrorContext(ctx, "get request failed", "error", err)
+		return nil, service.StorageError(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return nil, service.NewServiceError(service.ErrCodeNotFound)
+	}
+	if res.IsError() {
+		log.ErrorContext(ctx, "get response error", "status", res.Status(), "response", res.String())
+		return nil, responseError(res)
+	}
+
+	var doc loginReservationDoc
+	if err := json.NewDecoder(res.Body).Decode(&doc); err != nil {
+		log.ErrorContext(ctx, "document decoding failed", "error", err)
+		return nil, service.StorageError(err)
+	}
+	return &doc, nil
+}
+
+func (r *LoginReservations) Release(ctx context.Context, normalized, userID string) error {
+	const op = "LoginReservations.Release"
+	log := r.logger.With("operation", op, "user_id", userID)
+
+	doc, err := r.get(ctx, normalized)
+	if err != nil {
+		if service.HasCode(err, service.ErrCodeNotFound) {
+			return nil
+		}
+		return err
+	}
+	if doc.Source.UserID != userID {
+		log.WarnContext(ctx, "login reserved by another user, not released", "owner", doc.Source.UserID)
+		return nil
+	}
+
+	// Удаление с проверкой версии не затронет резерв, который успел перехватить другой пользователь
+	res, err := r.client.Delete(
+		loginsIndex,
+		url.PathEscape(normalized),
+		r.client.Delete.WithContext(ctx),
+		r.client.Delete.WithIfSeqNo(doc.SeqNo),
+		r.client.Delete.WithIfPrimaryTerm(doc.PrimaryTerm),
+		r.client.Delete.WithRefresh("true"),
+	)
+	if err != nil {
+		log.ErrorContext(ctx, "delete request failed", "error", err)
+		return service.StorageError(err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() && res.StatusCode != 404 && res.StatusCode != 409 {
+		log.ErrorContext(ctx, "delete response error", "status", res.Status(), "response", res.String())
+		return responseError(res)
+	}
+
+	log.DebugContext(ctx, "login released")
+	return nil
+}
+
+func (r *LoginReservations) backfill(ctx context.Context) error {
+	const op = "LoginReservations.backfill"
+	log := r.logger.With("operation", op)
+	start := time.Now()
+
+	query := `{"query": {"exists": {"field": "login"}}, "_source": ["login"], "size": 500}`
+	res, err := r.client.Search(
+		r.client.Search.WithIndex(usersIndex),
+		r.client.Search.WithContext(ctx),
+		r.client.Search.WithBody(strings.NewReader(query)),
+		r.client.Search.WithScroll(time.Minute),
+	)
+
+	var reserved, conflicts int
+	for {
+		if err != nil {
+			log.ErrorContext(ctx, "search request failed", "error", err)
+			return service.StorageError(err)
+		}
+		if res.IsError() {
+			log.ErrorContext(ctx, "search response error", "status", res.Status(), "response", res.String())
+			res.Body.Close()
+			return responseError(res)
+		}
+
+		var page struct {
+			ScrollID string `json:"_scroll_id"`
+			elasticResponse
+		}
+		err = json.NewDecoder(res.Body).Decode(&page)
+		res.Body.Close()
+		if err != nil {
+			log.ErrorContext(ctx, "document decoding failed", "error", err)
+			return service.StorageError(err)
+		}
+		if len(page.Hits.Hits) == 0 {
+			r.clearScroll(ctx, page.ScrollID)
+			break
+		}
+
+		for _, user := range shoveTheId(page.Hits.Hits) {
+			if user.Login == nil || *user.Login == "" {
+				continue
+			}
+			err := r.Reserve(ctx, &domain.LoginReservation{
+				Normalized: logins.Normalize(*user.Login),
+				Login:      *user.Login,
+				UserID:     *user.ID,
+				ReservedAt: time.Now().UTC(),
+			})
+			switch {
+			case err == nil:
+				reserved++
+			case service.HasCode(err, service.ErrCodeAlreadyExists):
+				// Такие дубли нужно разрешить вручную, резерв остаётся за первым найденным пользователем
+				conflicts++
+				log.WarnContext(ctx, "duplicate login found", "login", *user.Login, "user_id", *user.ID)
+			default:
+				return err
+			}
+		}
+
+		res, err = r.client.Scroll(
+			r.client.Scroll.WithContext(ctx),
+			r.client.Scroll.WithScrollID(page.ScrollID),
+			r.client.Scroll.WithScroll(time.Minute),
+		)
+	}
+
+	log.InfoContext(ctx, "login reservations backfilled", "reserved", reserved, "conflicts", conflicts, "duration", time.Since(start))
+	return nil
+}
+
+// backfilled проверяет отметку о завершённом заполнении в _meta маппинга индекса
+func (r *LoginReservations) backfilled(ctx context.Context) (bool, error) {
+	const op = "LoginReservations.backfilled"
+	log := r.logger.With("operation", op)
+
+	res, err := r.client.Indices.GetMapping(
+		r.client.Indices.GetMapping.WithIndex(loginsIndex),
+		r.client.Indices.GetMapping.WithContext(ctx),
+	)
+	if err != nil {
+		log.ErrorContext(ctx, "mapping request failed", "error", err)
+		return false, service.StorageError(err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		log.ErrorContext(ctx, "mapping response error", "status", res.Status(), "response", res.String())
+		return false, responseError(res)
+	}
+
+	var mappings map[string]struct {
+		Mappings struct {
+			Meta struct {
+				BackfilledAt string `json:"backfilled_at"`
+			} `json:"_meta"`
+		} `json:"mappings"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&mappings); err != nil {
+		log.ErrorContext(ctx, "response decoding failed", "error", err)
+		return false, service.StorageError(err)
+	}
+	for _, index := range mappings {
+		if index.Mappings.Meta.BackfilledAt != "" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *LoginReservations) markBackfilled(ctx context.Context) error {
+	const op = "LoginReservations.markBackfilled"
+	log := r.logger.With("operation", op)
+
+	body := map[string]any{"_meta": map[string]any{"backfilled_at": time.Now().UTC()}}
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(body); err != nil {
+		return service.StorageError(err)
+	}
+
+	res, err := r.client.Indices.PutMapping(
+		[]string{loginsIndex},
+		&buf,
+		r.client.Indices.PutMapping.WithContext(ctx),
+	)
+	if err != nil {
+		log.ErrorContext(ctx, "mapping update failed", "error", err)
+		return service.StorageError(err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		log.ErrorContext(ctx, "mapping update rejected", "status", res.Status(), "response", res.String())
+		return responseError(res)
+	}
+	return nil
+}
+
+func (r *LoginReservations) clearScroll(ctx context.Context, scrollID string) {
+	if scrollID == "" {
+		return
+	}
+	res, err := r.client.ClearScroll(
+		r.client.ClearScroll.WithContext(ctx),
+		r.client.ClearScroll.WithScrollID(scrollID),
+	)
+	if err == nil {
+		res.Body.Close()
+	}
+}
@@ -0,0 +1,62 @@
+package elastic
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/satrunjis/user-service/internal/service"
+)
+
+// socialNetMigration переносит устаревшее поле social_net в список social_profiles
+const socialNetMigration = `{
+  "query": {"exists": {"field": "social_net"}},
+  "script": {
+    "lang": "painless",
+    "source": "def net = ctx._source.remove('social_net'); if (net == null || net.toString().trim().isEmpty()) { return; } String id = net.toString().trim().toLowerCase(); if (ctx._source.social_profiles == null) { ctx._source.social_profiles = []; } for (def p : ctx._source.social_profiles) { if (p.network == id) { return; } } ctx._source.social_profiles.add(['network': id]);"
+  }
+}`
+
+// MigrateSocialNet переносит social_net в social_profiles. Повторный запуск ничего не меняет.
+func (e *Elastic) MigrateSocialNet(ctx context.Context) error {
+	const op = "Elastic.MigrateSocialNet"
+	log := e.logger.With("operation", op)
+	start := time.Now()
+
+	res, err := e.Client.UpdateByQuery(
+		[]string{usersIndex},
+		e.Client.UpdateByQuery.WithContext(ctx),
+		e.Client.UpdateByQuery.WithBody(strings.NewReader(socialNetMigration)),
+		e.Client.UpdateByQuery.WithConflicts("proceed"),
+		e.Client.UpdateByQuery.WithRefresh(true),
+		e.Client.UpdateByQuery.WithWaitForCompletion(true),
+	)
+	if err != nil {
+		log.ErrorContext(ctx, "update by query request failed", "error", err)
+		return service.StorageError(err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		log.ErrorContext(ctx, "update by query response error", "status", res.Status(), "response", res.String())
+		return responseError(res)
+	}
+
+	var result struct {
+		Updated   int `json:"updated"`
+		Conflicts int `json:"version_conflicts"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		log.ErrorContext(ctx, "response decoding failed", "error", err)
+		return service.StorageError(err)
+	}
+
+	if result.Updated > 0 || result.Conflicts > 0 {
+		log.InfoContext(ctx, "social_net migrated to social_profiles",
+			"updated", result.Updated,
+			"conflicts", result.Conflicts,
+			"duration", time.Since(start))
+	}
+	return nil
+}
@@ -0,0 +1,461 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/satrunjis/user-service/internal/domain"
+	"github.com/satrunjis/user-service/internal/service"
+)
+
+const outboxIndex = "user_outbox"
+
+// Событие хранится только в _source, поиск идёт по служебным полям
+const outboxMappings = `{
+  "mappings": {
+    "properties": {
+      "id":           {"type": "keyword"},
+      "user_id":      {"type": "keyword"},
+      "position":     {"type": "integer"},
+      "event":        {"type": "object", "enabled": false},
+      "created_at":   {"type": "date"},
+      "published_at": {"type": "date"},
+      "attempts":     {"type": "integer"},
+      "last_error":   {"type": "text", "index": false},
+      "retry_at":     {"type": "date"},
+      "parked_at":    {"type": "date"}
+    }
+  }
+}`
+
+// settled условия записей, которые relay больше не публикует: опубликованных и отложенных
+var settled = []map[string]any{
+	{"exists": map[string]any{"field": "published_at"}},
+	{"exists": map[string]any{"field": "parked_at"}},
+}
+
+type Outbox struct {
+	client *elasticsearch.Client
+	logger *slog.Logger
+}
+
+var _ domain.OutboxRepository = (*Outbox)(nil)
+
+// NewOutbox создаёт индекс outbox. Вызывается до первой записи пользователя,
+// иначе Elasticsearch создаст индекс с динамическим маппингом.
+func NewOutbox(ctx context.Context, e *Elastic) (*Outbox, error) {
+	log := e.logger.With("operation", "elastic.NewOutbox")
+	if err := ensureIndex(ctx, e.Client, outboxIndex, outboxMappings, log); err != nil {
+		return nil, err
+	}
+	return &Outbox{client: e.Client, logger: e.logger}, nil
+}
+
+func (r *Outbox) Pending(ctx context.Context, limit int) ([]*domain.OutboxEntry, error) {
+	const op = "Outbox.Pending"
+	log := r.logger.With("operation", op)
+
+	query := map[string]any{
+		"query": map[string]any{"bool": map[string]any{
+			"must_not": settled,
+		}},
+		"sort": []map[string]any{
+			{"created_at": map[string]any{"order": "asc"}},
+			{"position": map[string]any{"order": "asc"}},
+		},
+		"size": limit,
+	}
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(query); err != nil {
+		return nil, service.StorageError(err)
+	}
+
+	res, err := r.client.Search(
+		r.client.Search.WithIndex(outboxIndex),
+		r.client.Search.WithContext(ctx),
+		r.client.Search.WithBody(&buf),
+	)
+	if err != nil {
+		log.ErrorContext(ctx, "search request failed", "error", err)
+		return nil, service.StorageError(err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		log.ErrorContext(ctx, "search response error", "status", res.Status(), "response", res.String())
+		return nil, responseError(res)
+	}
+
+	var response struct {
+		Hits struct {
+			Hits []struct {
+				Source domain.OutboxEntry `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		log.ErrorContext(ctx, "response decoding failed", "error", err)
+		return nil, service.StorageError(err)
+	}
+
+	entries := make([]*domain.OutboxEntry, len(response.Hits.Hits))
+	for i := range response.Hits.Hits {
+		entries[i] = &response.Hits.Hits[i].Source
+	}
+	return entries, nil
+}
+
+func (r *Outbox) MarkPublished(ctx context.Context, ids []string, at time.Time) error {
+	const op = "Outbox.MarkPublished"
+	log := r.logger.With("operation", op)
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, id := range ids {
+		if err := enc.Encode(map[string]any{"update": map[string]any{"_index": outboxIndex, "_id": id}}); err != nil {
+			return service.StorageError(err)
+		}
+		if err := enc.Encode(map[string]any{"doc": map[string]any{"published_at": at}}); err != nil {
+			return service.StorageError(err)
+		}
+	}
+
+	// Опубликованные записи не должны снова попасть в выборку Pending
+	items, err := bulk(ctx, r.client, &buf)
+	if err != nil {
+		log.ErrorContext(ctx, "bulk request failed", "error", err)
+		return err
+	}
+	for i, item := range items {
+		if item.Status >= 300 && item.Status != 404 {
+			log.ErrorContext(ctx, "outbox entry was not marked as published", "event_id", ids[i], "status", item.Status, "error", item.Error)
+			return statusError(item.Status, fmt.Errorf("outbox update responded %d", item.Status))
+		}
+	}
+	return nil
+}
+
+func (r *Outbox) RecordFailure(ctx context.Context, entry *domain.OutboxEntry) error {
+	const op = "Outbox.RecordFailure"
+	log := r.logger.With("operation", op, "event_id", entry.ID)
+
+	var buf bytes.Buffer
+	body := map[string]any{"doc": map[string]any{
+		"attempts":   entry.Attempts,
+		"last_error": entry.LastError,
+		"retry_at":   entry.RetryAt,
+		"parked_at":  entry.ParkedAt,
+	}}
+	if err := json.NewEncoder(&buf).Encode(body); err != nil {
+		log.ErrorContext(ctx, "document encoding failed", "error", err)
+		return service.StorageError(err)
+	}
+
+	res, err := r.client.Update(
+		outboxIndex,
+		entry.ID,
+		&buf,
+		r.client.Update.WithContext(ctx),
+		r.client.Update.WithRefresh("wait_for"),
+	)
+	if err != nil {
+		log.ErrorContext(ctx, "update request failed", "error", err)
+		return service.StorageError(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return service.NewServiceError(service.ErrCodeNotFound)
+	}
+	if res.IsError() {
+		log.ErrorContext(ctx, "update response error", "status", res.Status(), "response", res.String())
+		return responseError(res)
+	}
+	return nil
+}
+
+func (r *Outbox) DeletePublished(ctx context.Context, before time.Time) (int64, error) {
+	query := map[string]any{
+		"query": map[string]any{"range": map[string]any{"published_at": map[string]any{"lt": before}}},
+	}
+	return r.deleteByQuery(ctx, "Outbox.DeletePublished", query)
+}
+
+func (r *Outbox) PendingForUser(ctx context.Context, userID string) (int64, error) {
+	const op = "Outbox.PendingForUser"
+	log := r.logger.With("operation", op, "user_id", userID)
+
+	query := map[string]any{
+		"query": map[string]any{"bool": map[string]any{
+			"filter":   []map[string]any{{"term": map[string]any{"user_id": userID}}},
+			"must_not": settled,
+		}},
+	}
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(query); err != nil {
+		return 0, service.StorageError(err)
+	}
+
+	res, err := r.client.Count(
+		r.client.Count.WithIndex(outboxIndex),
+		r.client.Count.WithContext(ctx),
+		r.client.Count.WithBody(&buf),
+	)
+	if err != nil {
+		log.ErrorContext(ctx, "count request failed", "error", err)
+		return 0, service.StorageError(err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		log.ErrorContext(ctx, "count response error", "status", res.Status(), "response", res.String())
+		return 0, responseError(res)
+	}
+
+	var response struct {
+		Count int64 `json:"count"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		log.ErrorContext(ctx, "response decoding failed", "error", err)
+		return 0, service.StorageError(err)
+	}
+	return response.Count, nil
+}
+
+func (r *Outbox) DeleteByUser(ctx context.Context, userID string) (int64, error) {
+	query := map[string]any{
+		"query": map[string]any{"bool": map[string]any{
+			"filter":               []map[string]any{{"term": map[string]any{"user_id": userID}}},
+			"should":               settled,
+			"minimum_should_match": 1,
+		}},
+	}
+	return r.deleteByQuery(ctx, "Outbox.DeleteByUser", query)
+}
+
+func (r *Outbox) deleteByQuery(ctx context.Context, op string, query map[string]any) (int64, error) {
+	log := r.logger.With("operation", op)
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(query); err != nil {
+		return 0, service.StorageError(err)
+	}
+
+	res, err := r.client.DeleteByQuery(
+		[]string{outboxIndex},
+		&buf,
+		r.client.DeleteByQuery.WithContext(ctx),
+		r.client.DeleteByQuery.WithConflicts("proceed"),
+	)
+	if err != nil {
+		log.ErrorContext(ctx, "delete by query request failed", "error", err)
+		return 0, service.StorageError(err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		log.ErrorContext(ctx, "delete by query response error", "status", res.Status(), "response", res.String())
+		return 0, responseError(res)
+	}
+
+	var response struct {
+		Deleted int64 `json:"deleted"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		log.ErrorContext(ctx, "response decoding failed", "error", err)
+		return 0, service.StorageError(err)
+	}
+	return response.Deleted, nil
+}
+
+// bulkItem результат одной операции bulk-запроса
+type bulkItem struct {
+	Status      int             `json:"status"`
+	Error       json.RawMessage `json:"error,omitempty"`
+	SeqNo       int             `json:"_seq_no"`
+	PrimaryTerm int             `json:"_primary_term"`
+}
+
+func decodeBulk(body io.Reader) ([]bulkItem, error) {
+	var response struct {
+		Items []map[string]bulkItem `json:"items"`
+	}
+	if err := json.NewDecoder(body).Decode(&response); err != nil {
+		return nil, err
+	}
+	items := make([]bulkItem, len(response.Items))
+	for i, item := range response.Items {
+		// В каждом элементе ровно один ключ - тип операции
+		for _, result := range item {
+			items[i] = result
+		}
+	}
+	return items, nil
+}
+
+// writeWithEvents выполняет операцию action над документом пользователя и добавляет события в outbox
+// одним bulk-запросом, поэтому остановка сервиса после записи не теряет события.
+// Операции bulk-запроса выполняются независимо: если запись пользователя не удалась,
+// уже добавленные события удаляются, если не удалось сохранить событие - оно пишется повторно.
+// Непустая revision - версия, которую должен иметь документ; после записи в неё попадает новая версия.
+func (e *Elastic) writeWithEvents(ctx context.Context, op, action, id string, revision *string, doc any, events []domain.UserEvent) error {
+	log := e.logger.With("operation", op, "user_id", id)
+	start := time.Now()
+
+	meta := map[string]any{"_index": usersIndex, "_id": id}
+	if revision != nil && *revision != "" {
+		seqNo, primaryTerm, err := parseRevision(*revision)
+		if err != nil {
+			return service.StorageError(err)
+		}
+		meta["if_seq_no"] = seqNo
+		meta["if_primary_term"] = primaryTerm
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	if err := enc.Encode(map[string]any{action: meta}); err != nil {
+		log.ErrorContext(ctx, "document encoding failed", "error", err)
+		return service.StorageError(err)
+	}
+	if doc != nil {
+		if err := enc.Encode(doc); err != nil {
+			log.ErrorContext(ctx, "document encoding failed", "error", err)
+			return service.StorageError(err)
+		}
+	}
+	entries := outboxEntries(events)
+	if err := encodeOutbox(enc, entries); err != nil {
+		log.ErrorContext(ctx, "outbox encoding failed", "error", err)
+		return service.StorageError(err)
+	}
+
+	items, err := bulk(ctx, e.Client, &buf)
+	if err != nil {
+		log.ErrorContext(ctx, "bulk request failed", "error", err)
+		return err
+	}
+	if len(items) != len(entries)+1 {
+		log.ErrorContext(ctx, "unexpected bulk response", "items", len(items))
+		return service.StorageError(fmt.Errorf("bulk response has %d items, expected %d", len(items), len(entries)+1))
+	}
+
+	if status := items[0].Status; status >= 300 {
+		e.discardOutbox(ctx, log, entries, items[1:])
+		switch {
+		case status == 404:
+			return service.NewServiceError(service.ErrCodeNotFound)
+		case status == 409 && action == "create":
+			return service.NewServiceError(service.ErrCodeAlreadyExists)
+		}
+		log.ErrorContext(ctx, "bulk item error", "status", status, "error", string(items[0].Error))
+		return statusError(status, fmt.Errorf("elasticsearch %s responded %d", action, status))
+	}
+
+	if revision != nil {
+		*revision = formatRevision(items[0].SeqNo, items[0].PrimaryTerm)
+	}
+
+	var failed []*domain.OutboxEntry
+	for i, item := range items[1:] {
+		if item.Status >= 300 && item.Status != 409 {
+			failed = append(failed, entries[i])
+		}
+	}
+	if len(failed) > 0 {
+		e.retryOutbox(ctx, log, failed)
+	}
+
+	log.InfoContext(ctx, "user written with events", "action", action, "events", len(entries), "duration", time.Since(start))
+	return nil
+}
+
+// retryOutbox повторяет запись событий, которые не сохранились вместе с документом
+func (e *Elastic) retryOutbox(ctx context.Context, log *slog.Logger, entries []*domain.OutboxEntry) {
+	var buf bytes.Buffer
+	if err := encodeOutbox(json.NewEncoder(&buf), entries); err != nil {
+		log.ErrorContext(ctx, "outbox encoding failed", "error", err)
+		return
+	}
+	items, err := bulk(ctx, e.Client, &buf)
+	if err == nil {
+		for i, item := range items {
+			if item.Status >= 300 && item.Status != 409 {
+				log.ErrorContext(ctx, "event lost, outbox entry was not saved", "event_id", entries[i].ID, "event_type", entries[i].Event.Type, "status", item.Status)
+			}
+		}
+		return
+	}
+	for _, entry := range entries {
+		log.ErrorContext(ctx, "event lost, outbox entry was not saved", "event_id", entry.ID, "event_type", entry.Event.Type, "error", err)
+	}
+}
+
+// discardOutbox удаляет события записи, которая не удалась
+func (e *Elastic) discardOutbox(ctx context.Context, log *slog.Logger, entries []*domain.OutboxEntry, items []bulkItem) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for i, entry := range entries {
+		if items[i].Status >= 300 {
+			continue
+		}
+		if err := enc.Encode(map[string]any{"delete": map[string]any{"_index": outboxIndex, "_id": entry.ID}}); err != nil {
+			return
+		}
+	}
+	if buf.Len() == 0 {
+		return
+	}
+	if _, err := bulk(ctx, e.Client, &buf); err != nil {
+		log.ErrorContext(ctx, "failed to discard events of failed write", "error", err)
+	}
+}
+
+func bulk(ctx context.Context, client *elasticsearch.Client, body *bytes.Buffer) ([]bulkItem, error) {
+	res, err := client.Bulk(body, client.Bulk.WithContext(ctx), client.Bulk.WithRefresh("wait_for"))
+	if err != nil {
+		return nil, service.StorageError(err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, responseError(res)
+	}
+	items, err := decodeBulk(res.Body)
+	if err != nil {
+		return nil, service.StorageError(err)
+	}
+	return items, nil
+}
+
+func outboxEntries(events []domain.UserEvent) []*domain.OutboxEntry {
+	now := time.Now().UTC()
+	entries := make([]*domain.OutboxEntry, len(events))
+	for i, event := range events {
+		entries[i] = &domain.OutboxEntry{ID: event.ID, UserID: event.UserID, Position: i, Event: event, CreatedAt: now}
+	}
+	return entries
+}
+
+func encodeOutbox(enc *json.Encoder, entries []*domain.OutboxEntry) error {
+	for _, entry := range entries {
+		// create не перезаписывает событие, если запрос повторили
+		if err := enc.Encode(map[string]any{"create": map[string]any{"_index": outboxIndex, "_id": entry.ID}}); err != nil {
+			return err
+		}
+		if err := enc.Encode(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
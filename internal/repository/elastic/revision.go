@@ -0,0 +1,41 @@
+package elastic
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// formatRevision версия документа из _seq_no и _primary_term, вместе они однозначно задают его состояние
+func formatRevision(seqNo, primaryTerm int) string {
+	return fmt.Sprintf("%d-%d", seqNo, primaryTerm)
+}
+
+func parseRevision(revision string) (seqNo, primaryTerm int, err error) {
+	seq, term, ok := strings.Cut(revision, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid revision %q", revision)
+	}
+	if seqNo, err = strconv.Atoi(seq); err != nil {
+		return 0, 0, fmt.Errorf("invalid revision %q", revision)
+	}
+	if primaryTerm, err = strconv.Atoi(term); err != nil {
+		return 0, 0, fmt.Errorf("invalid revision %q", revision)
+	}
+	return seqNo, primaryTerm, nil
+}
+
+// decodeRevision новая версия из ответа на запись. Если её не удалось прочитать, следующая запись
+// того же документа пройдёт без проверки версии.
+func decodeRevision(body io.Reader) string {
+	var written struct {
+		SeqNo       *int `json:"_seq_no"`
+		PrimaryTerm *int `json:"_primary_term"`
+	}
+	if err := json.NewDecoder(body).Decode(&written); err != nil || written.SeqNo == nil || written.PrimaryTerm == nil {
+		return ""
+	}
+	return formatRevision(*written.SeqNo, *written.PrimaryTerm)
+}
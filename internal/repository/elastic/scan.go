@@ -0,0 +1,190 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/satrunjis/user-service/internal/domain"
+	"github.com/satrunjis/user-service/internal/service"
+)
+
+// scanKeepAlive сколько живёт снимок индекса между страницами обхода
+const scanKeepAlive = "5m"
+
+// scanCursor позиция обхода: снимок индекса (point in time) и значения сортировки последнего документа
+type scanCursor struct {
+	PIT   string `json:"pit"`
+	After []any  `json:"after,omitempty"`
+}
+
+type scanHit struct {
+	ID          string          `json:"_id"`
+	SeqNo       int64           `json:"_seq_no"`
+	PrimaryTerm int64           `json:"_primary_term"`
+	Source      json.RawMessage `json:"_source"`
+	Sort        []any           `json:"sort"`
+}
+
+// Scan обходит индекс постранично по снимку (point in time) с сортировкой по _shard_doc:
+// порядок уникален и не зависит от полей документа, поэтому обход не ограничен окном from+size
+// и не пропускает пользователей. Пустой cursor начинает обход, пустой next означает его конец.
+func (e *Elastic) Scan(ctx context.Context, cursor string, size int) ([]*domain.User, string, error) {
+	const op = "Elastic.Scan"
+	log := e.logger.With("operation", op)
+
+	var c *scanCursor
+	if cursor == "" {
+		var err error
+		if c, err = e.openScan(ctx, log); err != nil {
+			return nil, "", err
+		}
+	} else {
+		c = &scanCursor{}
+		raw, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err == nil {
+			dec := json.NewDecoder(bytes.NewReader(raw))
+			dec.UseNumber()
+			err = dec.Decode(c)
+		}
+		if err != nil || c.PIT == "" {
+			return nil, "", service.NewMessageError(service.ErrCodeInvalidInput, service.MsgInvalidScanCursor)
+		}
+	}
+
+	hits, err := e.scanPage(ctx, log, c, size, false)
+	if err != nil {
+		return nil, "", err
+	}
+
+	users := make([]*domain.User, len(hits))
+	for i, hit := range hits {
+		var doc storedUser
+		if err := json.Unmarshal(hit.Source, &doc); err != nil {
+			log.ErrorContext(ctx, "document decoding failed", "error", err)
+			return nil, "", service.StorageError(err)
+		}
+		if err := e.openUser(hit.ID, &doc); err != nil {
+			log.ErrorContext(ctx, "failed to decrypt user", "user_id", hit.ID, "error", err)
+			return nil, "", service.StorageError(err)
+		}
+		id := hit.ID
+		doc.User.ID = &id
+		users[i] = &doc.User
+	}
+
+	if len(hits) < size {
+		e.closeScan(ctx, log, c)
+		return users, "", nil
+	}
+	raw, err := json.Marshal(c)
+	if err != nil {
+		return nil, "", service.StorageError(err)
+	}
+	return users, base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// openScan открывает снимок индекса пользователей для обхода
+func (e *Elastic) openScan(ctx context.Context, log *slog.Logger) (*scanCursor, error) {
+	res, err := e.Client.OpenPointInTime(
+		[]string{usersIndex},
+		scanKeepAlive,
+		e.Client.OpenPointInTime.WithContext(ctx),
+	)
+	if err != nil {
+		log.ErrorContext(ctx, "open point in time request failed", "error", err)
+		return nil, service.StorageError(err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		log.ErrorContext(ctx, "open point in time response error", "status", res.Status(), "response", res.String())
+		return nil, responseError(res)
+	}
+	var pit struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&pit); err != nil {
+		log.ErrorContext(ctx, "response decoding failed", "error", err)
+		return nil, service.StorageError(err)
+	}
+	return &scanCursor{PIT: pit.ID}, nil
+}
+
+// scanPage читает следующую страницу обхода и сдвигает cursor
+func (e *Elastic) scanPage(ctx context.Context, log *slog.Logger, c *scanCursor, size int, seqNo bool) ([]scanHit, error) {
+	query := map[string]any{
+		"query":               map[string]any{"match_all": map[string]any{}},
+		"pit":                 map[string]any{"id": c.PIT, "keep_alive": scanKeepAlive},
+		"sort":                []map[string]any{{"_shard_doc": map[string]any{"order": "asc"}}},
+		"size":                size,
+		"seq_no_primary_term": seqNo,
+	}
+	if c.After != nil {
+		query["search_after"] = c.After
+	}
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(query); err != nil {
+		log.ErrorContext(ctx, "query encoding failed", "error", err)
+		return nil, service.StorageError(err)
+	}
+
+	// Индекс задан снимком, в запросе его не указывают
+	res, err := e.Client.Search(
+		e.Client.Search.WithContext(ctx),
+		e.Client.Search.WithBody(&buf),
+	)
+	if err != nil {
+		log.ErrorContext(ctx, "search request failed", "error", err)
+		return nil, service.StorageError(err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		log.ErrorContext(ctx, "search response error", "status", res.Status(), "response", res.String())
+		return nil, responseError(res)
+	}
+
+	var page struct {
+		PIT  string `json:"pit_id"`
+		Hits struct {
+			Hits []scanHit `json:"hits"`
+		} `json:"hits"`
+	}
+	dec := json.NewDecoder(res.Body)
+	// Значения _shard_doc не помещаются в float64 без потери точности
+	dec.UseNumber()
+	if err := dec.Decode(&page); err != nil {
+		log.ErrorContext(ctx, "response decoding failed", "error", err)
+		return nil, service.StorageError(err)
+	}
+	if page.PIT != "" {
+		c.PIT = page.PIT
+	}
+	if hits := page.Hits.Hits; len(hits) > 0 {
+		c.After = hits[len(hits)-1].Sort
+	}
+	return page.Hits.Hits, nil
+}
+
+// closeScan освобождает снимок. Ошибка не мешает обходу: снимок истечёт сам через scanKeepAlive.
+func (e *Elastic) closeScan(ctx context.Context, log *slog.Logger, c *scanCursor) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(map[string]string{"id": c.PIT}); err != nil {
+		return
+	}
+	res, err := e.Client.ClosePointInTime(
+		e.Client.ClosePointInTime.WithContext(ctx),
+		e.Client.ClosePointInTime.WithBody(&buf),
+	)
+	if err != nil {
+		log.WarnContext(ctx, "close point in time request failed", "error", err)
+		return
+	}
+	defer res.Body.Close()
+	if res.IsError() && res.StatusCode != 404 {
+		log.WarnContext(ctx, "close point in time response error", "status", res.Status(), "response", res.String())
+	}
+}
@@ -0,0 +1,459 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v9"
+	"github.com/satrunjis/user-service/internal/domain"
+	"github.com/satrunjis/user-service/internal/service"
+)
+
+const webhooksIndex = "webhooks"
+const webhooksMappings = `{
+  "mappings": {
+    "properties": {
+      "id":          {"type": "keyword"},
+      "url":         {"type": "keyword"},
+      "events":      {"type": "keyword"},
+      "secret":      {"type": "keyword", "index": false},
+      "description": {"type": "text"},
+      "active":      {"type": "boolean"},
+      "created_at":  {"type": "date"},
+      "created_by":  {"type": "keyword"},
+      "updated_at":  {"type": "date"}
+    }
+  }
+}`
+
+const webhookDeliveriesIndex = "webhook_deliveries"
+
+// Тело запроса хранится только в _source для повторной отправки
+const webhookDeliveriesMappings = `{
+  "mappings": {
+    "properties": {
+      "id":              {"type": "keyword"},
+      "webhook_id":      {"type": "keyword"},
+      "event_id":        {"type": "keyword"},
+      "event_type":      {"type": "keyword"},
+      "user_id":         {"type": "keyword"},
+      "payload":         {"type": "object", "enabled": false},
+      "status":          {"type": "keyword"},
+      "attempts":        {"type": "integer"},
+      "response_status": {"type": "integer"},
+      "error":           {"type": "text"},
+      "next_attempt_at": {"type": "date"},
+      "last_attempt_at": {"type": "date"},
+      "replay_of":       {"type": "keyword"},
+      "created_at":      {"type": "date"},
+      "updated_at":      {"type": "date"}
+    }
+  }
+}`
+
+type Webhooks struct {
+	client *elasticsearch.Client
+	logger *slog.Logger
+}
+
+var _ domain.WebhookRepository = (*Webhooks)(nil)
+
+func NewWebhooks(ctx context.Context, e *Elastic) (*Webhooks, error) {
+	log := e.logger.With("operation", "elastic.NewWebhooks")
+	if err := ensureIndex(ctx, e.Client, webhooksIndex, webhooksMappings, log); err != nil {
+		return nil, err
+	}
+	return &Webhooks{client: e.Client, logger: e.logger}, nil
+}
+
+func (r *Webhooks) Create(ctx context.Context, webhook *domain.Webhook) error {
+	const op = "Webhooks.Create"
+	log := r.logger.With("operation", op, "webhook_id", webhook.ID)
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(webhook); err != nil {
+		log.ErrorContext(ctx, "document encoding failed", "error", err)
+		return service.StorageError(err)
+	}
+
+	res, err := r.client.Create(
+		webhooksIndex,
+		webhook.ID,
+		&buf,
+		r.client.Create.WithContext(ctx),
+		r.client.Create.WithRefresh("wait_for"),
+	)
+	if err != nil {
+		log.ErrorContext(ctx, "index request failed", "error", err)
+		return service.StorageError(err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		if res.StatusCode == 409 {
+			return service.NewServiceError(service.ErrCodeAlreadyExists)
+		}
+		log.ErrorContext(ctx, "index response error", "status", res.Status(), "response", res.String())
+		return responseError(res)
+	}
+
+	log.InfoContext(ctx, "webhook created", "url", webhook.URL)
+	return nil
+}
+
+func (r *Webhooks) GetByID(ctx context.Context, id string) (*domain.Webhook, error) {
+	const op = "Webhooks.GetByID"
+	log := r.logger.With("operation", op, "webhook_id", id)
+
+	res, err := r.client.Get(webhooksIndex, id, r.client.Get.WithContext(ctx))
+	if err != nil {
+		log.ErrorContext(ctx, "get request failed", "error", err)
+		return nil, service.StorageError(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return nil, service.NewServiceError(service.ErrCodeNotFound)
+	}
+	if res.IsError() {
+		log.ErrorContext(ctx, "get response error", "status", res.Status(), "response", res.String())
+		return nil, responseError(res)
+	}
+
+	var doc struct {
+		Source domain.Webhook `json:"_source"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&doc); err != nil {
+		log.ErrorContext(ctx, "document decoding failed", "error", err)
+		return nil, service.StorageError(err)
+	}
+	return &doc.Source, nil
+}
+
+func (r *Webhooks) List(ctx context.Context) ([]*domain.Webhook, error) {
+	const op = "Webhooks.List"
+	log := r.logger.With("operation", op)
+
+	query := `{"query": {"match_all": {}}, "sort": [{"created_at": {"order": "asc"}}], "size": 1000}`
+	res, err := r.client.Search(
+		r.client.Search.WithIndex(webhooksIndex),
+		r.client.Search.WithContext(ctx),
+		r.client.Search.WithBody(strings.NewReader(query)),
+	)
+	if err != nil {
+		log.ErrorContext(ctx, "search request failed", "error", err)
+		return nil, service.StorageError(err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		log.ErrorContext(ctx, "search response error", "status", res.Status(), "response", res.String())
+		return nil, responseError(res)
+	}
+
+	var response struct {
+		Hits struct {
+			Hits []struct {
+				Source domain.Webhook `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		log.ErrorContext(ctx, "document decoding failed", "error", err)
+		return nil, service.StorageError(err)
+	}
+
+	webhooks := make([]*domain.Webhook, len(response.Hits.Hits))
+	for i := range response.Hits.Hits {
+		webhooks[i] = &response.Hits.Hits[i].Source
+	}
+	return webhooks, nil
+}
+
+func (r *Webhooks) Update(ctx context.Context, webhook *domain.Webhook) error {
+	const op = "Webhooks.Update"
+	log := r.logger.With("operation", op, "webhook_id", webhook.ID)
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(webhook); err != nil {
+		log.ErrorContext(ctx, "document encoding failed", "error", err)
+		return service.StorageError(err)
+	}
+
+	res, err := r.client.Index(
+		webhooksIndex,
+		&buf,
+		r.client.Index.WithDocumentID(webhook.ID),
+		r.client.Index.WithContext(ctx),
+		r.client.Index.WithRefresh("wait_for"),
+	)
+	if err != nil {
+		log.ErrorContext(ctx, "index request failed", "error", err)
+		return service.StorageError(err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		log.ErrorContext(ctx, "index response error", "status", res.Status(), "response", res.String())
+		return responseError(res)
+	}
+
+	log.InfoContext(ctx, "webhook updated")
+	return nil
+}
+
+func (r *Webhooks) Delete(ctx context.Context, id string) error {
+	const op = "Webhooks.Delete"
+	log := r.logger.With("operation", op, "webhook_id", id)
+
+	res, err := r.client.Delete(
+		webhooksIndex,
+		id,
+		r.client.Delete.WithContext(ctx),
+		r.client.Delete.WithRefresh("wait_for"),
+	)
+	if err != nil {
+		log.ErrorContext(ctx, "delete request failed", "error", err)
+		return service.StorageError(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return service.NewServiceError(service.ErrCodeNotFound)
+	}
+	if res.IsError() {
+		log.ErrorContext(ctx, "delete response error", "status", res.Status(), "response", res.String())
+		return responseError(res)
+	}
+
+	log.InfoContext(ctx, "webhook deleted")
+	return nil
+}
+
+type WebhookDeliveries struct {
+	client *elasticsearch.Client
+	logger *slog.Logger
+}
+
+var _ domain.WebhookDeliveryRepository = (*WebhookDeliveries)(nil)
+
+func NewWebhookDeliveries(ctx context.Context, e *Elastic) (*WebhookDeliveries, error) {
+	log := e.logger.With("operation", "elastic.NewWebhookDeliveries")
+	if err := ensureIndex(ctx, e.Client, webhookDeliveriesIndex, webhookDeliveriesMappings, log); err != nil {
+		return nil, err
+	}
+	return &WebhookDeliveries{client: e.Client, logger: e.logger}, nil
+}
+
+func (r *WebhookDeliveries) Create(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	const op = "WebhookDeliveries.Create"
+	log := r.logger.With("operation", op, "delivery_id", delivery.ID)
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(delivery); err != nil {
+		log.ErrorContext(ctx, "document encoding failed", "error", err)
+		return service.StorageError(err)
+	}
+
+	res, err := r.client.Create(
+		webhookDeliveriesIndex,
+		delivery.ID,
+		&buf,
+		r.client.Create.WithContext(ctx),
+	)
+	if err != nil {
+		log.ErrorContext(ctx, "index request failed", "error", err)
+		return service.StorageError(err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		if res.StatusCode == 409 {
+			return service.NewServiceError(service.ErrCodeAlreadyExists)
+		}
+		log.ErrorContext(ctx, "index response error", "status", res.Status(), "response", res.String())
+		return responseError(res)
+	}
+	return nil
+}
+
+func (r *WebhookDeliveries) Save(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	const op = "WebhookDeliveries.Save"
+	log := r.logger.With("operation", op, "delivery_id", delivery.ID)
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(delivery); err != nil {
+		log.ErrorContext(ctx, "document encoding failed", "error", err)
+		return service.StorageError(err)
+	}
+
+	res, err := r.client.Index(
+		webhookDeliveriesIndex,
+		&buf,
+		r.client.Index.WithDocumentID(delivery.ID),
+		r.client.Index.WithContext(ctx),
+	)
+	if err != nil {
+		log.ErrorContext(ctx, "index request failed", "error", err)
+		return service.StorageError(err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		log.ErrorContext(ctx, "index response error", "status", res.Status(), "response", res.String())
+		return responseError(res)
+	}
+	return nil
+}
+
+func (r *WebhookDeliveries) GetByID(ctx context.Context, id string) (*domain.WebhookDelivery, error) {
+	const op = "WebhookDeliveries.GetByID"
+	log := r.logger.With("operation", op, "delivery_id", id)
+
+	res, err := r.client.Get(webhookDeliveriesIndex, id, r.client.Get.WithContext(ctx))
+	if err != nil {
+		log.ErrorContext(ctx, "get request failed", "error", err)
+		return nil, service.StorageError(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return nil, service.NewServiceError(service.ErrCodeNotFound)
+	}
+	if res.IsError() {
+		log.ErrorContext(ctx, "get response error", "status", res.Status(), "response", res.String())
+		return nil, responseError(res)
+	}
+
+	var doc struct {
+		Source domain.WebhookDelivery `json:"_source"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&doc); err != nil {
+		log.ErrorContext(ctx, "document decoding failed", "error", err)
+		return nil, service.StorageError(err)
+	}
+	return &doc.Source, nil
+}
+
+func (r *WebhookDeliveries) List(ctx context.Context, webhookID, status string, page, size int) (*domain.WebhookDeliveryPage, error) {
+	const op = "WebhookDeliveries.List"
+	log := r.logger.With("operation", op, "webhook_id", webhookID)
+
+	filters := []map[string]any{{"term": map[string]any{"webhook_id": webhookID}}}
+	if status != "" {
+		filters = append(filters, map[string]any{"term": map[string]any{"status": status}})
+	}
+	query := map[string]any{
+		"query":            map[string]any{"bool": map[string]any{"filter": filters}},
+		"sort":             []map[string]any{{"created_at": map[string]any{"order": "desc"}}},
+		"from":             (page - 1) * size,
+		"size":             size,
+		"track_total_hits": true,
+	}
+
+	deliveries, total, err := r.search(ctx, log, query)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.WebhookDeliveryPage{Deliveries: deliveries, Total: total, Page: page, Size: size}, nil
+}
+
+func (r *WebhookDeliveries) ListByUser(ctx context.Context, userID string, limit int) ([]*domain.WebhookDelivery, error) {
+	const op = "WebhookDeliveries.ListByUser"
+	log := r.logger.With("operation", op, "user_id", userID)
+
+	query := map[string]any{
+		"query": map[string]any{"bool": map[string]any{"filter": []map[string]any{{"term": map[string]any{"user_id": userID}}}}},
+		"sort":  []map[string]any{{"created_at": map[string]any{"order": "desc"}}},
+		"size":  limit,
+	}
+	deliveries, _, err := r.search(ctx, log, query)
+	return deliveries, err
+}
+
+func (r *WebhookDeliveries) search(ctx context.Context, log *slog.Logger, query map[string]any) ([]*domain.WebhookDelivery, int64, error) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(query); err != nil {
+		return nil, 0, service.StorageError(err)
+	}
+
+	res, err := r.client.Search(
+		r.client.Search.WithIndex(webhookDeliveriesIndex),
+		r.client.Search.WithContext(ctx),
+		r.client.Search.WithBody(&buf),
+	)
+	if err != nil {
+		log.ErrorContext(ctx, "search request failed", "error", err)
+		return nil, 0, service.StorageError(err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		log.ErrorContext(ctx, "search response error", "status", res.Status(), "response", res.String())
+		return nil, 0, responseError(res)
+	}
+
+	var response struct {
+		Hits struct {
+			Total struct {
+				Value int64 `json:"value"`
+			} `json:"total"`
+			Hits []struct {
+				Source domain.WebhookDelivery `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		log.ErrorContext(ctx, "response decoding failed", "error", err)
+		return nil, 0, service.StorageError(err)
+	}
+
+	deliveries := make([]*domain.WebhookDelivery, len(response.Hits.Hits))
+	for i := range response.Hits.Hits {
+		deliveries[i] = &response.Hits.Hits[i].Source
+	}
+	return deliveries, response.Hits.Total.Value, nil
+}
+
+func (r *WebhookDeliveries) DeleteByUser(ctx context.Context, userID string) (int64, error) {
+	const op = "WebhookDeliveries.DeleteByUser"
+	log := r.logger.With("operation", op, "user_id", userID)
+
+	query := map[string]any{
+		"query": map[string]any{"term": map[string]any{"user_id": userID}},
+	}
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(query); err != nil {
+		return 0, service.StorageError(err)
+	}
+
+	res, err := r.client.DeleteByQuery(
+		[]string{webhookDeliveriesIndex},
+		&buf,
+		r.client.DeleteByQuery.WithContext(ctx),
+		r.client.DeleteByQuery.WithConflicts("proceed"),
+		r.client.DeleteByQuery.WithRefresh(true),
+	)
+	if err != nil {
+		log.ErrorContext(ctx, "delete by query request failed", "error", err)
+		return 0, service.StorageError(err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		log.ErrorContext(ctx, "delete by query response error", "status", res.Status(), "response", res.String())
+		return 0, responseError(res)
+	}
+
+	var response struct {
+		Deleted int64 `json:"deleted"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		log.ErrorContext(ctx, "response decoding failed", "error", err)
+		return 0, service.StorageError(err)
+	}
+	return response.Deleted, nil
+}
@@ -0,0 +1,81 @@
+package redisstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/satrunjis/user-service/internal/domain"
+	"github.com/satrunjis/user-service/pkg/cdc"
+)
+
+const changeSeenTTL = 24 * time.Hour
+
+// Конверт добавляется, только если событие с этим ID ещё не публиковалось
+var publishChange = redis.NewScript(`
+if not redis.call('SET', KEYS[2], '1', 'NX', 'PX', ARGV[1]) then
+  return false
+end
+return redis.call('XADD', KEYS[1], 'MAXLEN', '~', ARGV[2], '*', ARGV[3], ARGV[4], ARGV[5], ARGV[6], ARGV[7], ARGV[8])
+`)
+
+// ChangeStream публикует события пользователей в Redis Stream в формате cdc.Envelope
+type ChangeStream struct {
+	client *redis.Client
+	stream string
+	maxLen int64
+}
+
+var _ domain.ChangeStream = (*ChangeStream)(nil)
+
+func NewChangeStream(client *redis.Client, stream string, maxLen int64) *ChangeStream {
+	return &ChangeStream{client: client, stream: stream, maxLen: maxLen}
+}
+
+func (s *ChangeStream) Publish(ctx context.Context, event domain.UserEvent) (bool, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return false, fmt.Errorf("redisstore.ChangeStream.Publish: %w", err)
+	}
+	envelope := cdc.Envelope{
+		Version:    cdc.Version,
+		ID:         event.ID,
+		Type:       event.Type,
+		UserID:     event.UserID,
+		OccurredAt: event.OccurredAt,
+		Payload:    payload,
+	}
+	values, err := envelope.Values()
+	if err != nil {
+		return false, fmt.Errorf("redisstore.ChangeStream.Publish: %w", err)
+	}
+
+	keys := []string{s.stream, s.stream + ":seen:" + event.ID}
+	err = publishChange.Run(ctx, s.client, keys,
+		changeSeenTTL.Milliseconds(), s.maxLen,
+		cdc.FieldVersion, values[cdc.FieldVersion],
+		cdc.FieldType, values[cdc.FieldType],
+		cdc.FieldEnvelope, values[cdc.FieldEnvelope],
+	).Err()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("redisstore.ChangeStream.Publish: %w", err)
+	}
+	return true, nil
+}
+
+func (s *ChangeStream) DeleteByUser(ctx context.Context, userID string) (int64, error) {
+	deleted, err := purgeStream(ctx, s.client, s.stream, func(values map[string]any) bool {
+		envelope, err := cdc.Decode(values)
+		return err == nil && envelope.UserID == userID
+	})
+	if err != nil {
+		return deleted, fmt.Errorf("redisstore.ChangeStream.DeleteByUser: %w", err)
+	}
+	return deleted, nil
+}
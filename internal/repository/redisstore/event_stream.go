@@ -0,0 +1,154 @@
+package redisstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/satrunjis/user-service/internal/domain"
+)
+
+const (
+	eventStreamKey = "events:users"
+	// Отметки о добавленных событиях, outbox может опубликовать событие повторно
+	eventSeenPrefix = "events:users:seen:"
+	eventSeenTTL    = 24 * time.Hour
+	// purgeBatch сколько записей потока читается за раз при удалении событий пользователя
+	purgeBatch = 500
+)
+
+// Событие добавляется, только если его ID ещё не встречался. Журнал обрезается примерно до ARGV[3] записей.
+var appendEvent = redis.NewScript(`
+if not redis.call('SET', KEYS[2], '1', 'NX', 'PX', ARGV[2]) then
+  return false
+end
+return redis.call('XADD', KEYS[1], 'MAXLEN', '~', ARGV[3], '*', 'event', ARGV[1])
+`)
+
+type EventStream struct {
+	client *redis.Client
+	maxLen int64
+}
+
+var _ domain.EventStream = (*EventStream)(nil)
+
+func NewEventStream(client *redis.Client, maxLen int64) *EventStream {
+	return &EventStream{client: client, maxLen: maxLen}
+}
+
+func (s *EventStream) Append(ctx context.Context, event domain.UserEvent) (bool, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return false, fmt.Errorf("redisstore.EventStream.Append: %w", err)
+	}
+	keys := []string{eventStreamKey, eventSeenPrefix + event.ID}
+	err = appendEvent.Run(ctx, s.client, keys, data, eventSeenTTL.Milliseconds(), s.maxLen).Err()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("redisstore.EventStream.Append: %w", err)
+	}
+	return true, nil
+}
+
+func (s *EventStream) Range(ctx context.Context, after string, count int) ([]domain.StreamedEvent, error) {
+	start := "-"
+	if after != "" {
+		start = "(" + after
+	}
+	messages, err := s.client.XRangeN(ctx, eventStreamKey, start, "+", int64(count)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redisstore.EventStream.Range: %w", err)
+	}
+	return decodeStreamed(messages)
+}
+
+func (s *EventStream) Read(ctx context.Context, after string, count int, block time.Duration) ([]domain.StreamedEvent, error) {
+	streams, err := s.client.XRead(ctx, &redis.XReadArgs{
+		Streams: []string{eventStreamKey, after},
+		Count:   int64(count),
+		Block:   block,
+	}).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("redisstore.EventStream.Read: %w", err)
+	}
+	if len(streams) == 0 {
+		return nil, nil
+	}
+	return decodeStreamed(streams[0].Messages)
+}
+
+func (s *EventStream) LastID(ctx context.Context) (string, error) {
+	messages, err := s.client.XRevRangeN(ctx, eventStreamKey, "+", "-", 1).Result()
+	if err != nil {
+		return "", fmt.Errorf("redisstore.EventStream.LastID: %w", err)
+	}
+	if len(messages) == 0 {
+		return "0-0", nil
+	}
+	return messages[0].ID, nil
+}
+
+func (s *EventStream) DeleteByUser(ctx context.Context, userID string) (int64, error) {
+	deleted, err := purgeStream(ctx, s.client, eventStreamKey, func(values map[string]any) bool {
+		data, _ := values["event"].(string)
+		var event struct {
+			UserID string `json:"user_id"`
+		}
+		return json.Unmarshal([]byte(data), &event) == nil && event.UserID == userID
+	})
+	if err != nil {
+		return deleted, fmt.Errorf("redisstore.EventStream.DeleteByUser: %w", err)
+	}
+	return deleted, nil
+}
+
+// purgeStream удаляет записи потока, для которых match возвращает true. Поток ограничен
+// по длине, поэтому читается целиком; записи, добавленные во время обхода, тоже проверяются.
+func purgeStream(ctx context.Context, client *redis.Client, stream string, match func(values map[string]any) bool) (int64, error) {
+	var deleted int64
+	start := "-"
+	for {
+		messages, err := client.XRangeN(ctx, stream, start, "+", purgeBatch).Result()
+		if err != nil {
+			return deleted, err
+		}
+		var ids []string
+		for _, message := range messages {
+			if match(message.Values) {
+				ids = append(ids, message.ID)
+			}
+		}
+		if len(ids) > 0 {
+			n, err := client.XDel(ctx, stream, ids...).Result()
+			if err != nil {
+				return deleted, err
+			}
+			deleted += n
+		}
+		if len(messages) < purgeBatch {
+			return deleted, nil
+		}
+		start = "(" + messages[len(messages)-1].ID
+	}
+}
+
+func decodeStreamed(messages []redis.XMessage) ([]domain.StreamedEvent, error) {
+	events := make([]domain.StreamedEvent, 0, len(messages))
+	for _, message := range messages {
+		data, _ := message.Values["event"].(string)
+		var event domain.UserEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return nil, fmt.Errorf("redisstore.EventStream: decode %s: %w", message.ID, err)
+		}
+		events = append(events, domain.StreamedEvent{ID: message.ID, Event: event})
+	}
+	return events, nil
+}
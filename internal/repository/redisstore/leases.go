@@ -0,0 +1,55 @@
+package redisstore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/satrunjis/user-service/internal/domain"
+)
+
+const leasePrefix = "lease:"
+
+// Захват свободной аренды или продление своей
+var acquireLease = redis.NewScript(`
+local owner = redis.call('GET', KEYS[1])
+if owner == false or owner == ARGV[1] then
+  redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+  return 1
+end
+return 0
+`)
+
+// Освобождается только своя аренда, чужая могла быть захвачена после истечения нашей
+var releaseLease = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+type Leases struct {
+	client *redis.Client
+}
+
+var _ domain.LeaseStore = (*Leases)(nil)
+
+func NewLeases(client *redis.Client) *Leases {
+	return &Leases{client: client}
+}
+
+func (l *Leases) Acquire(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	ok, err := acquireLease.Run(ctx, l.client, []string{leasePrefix + name}, owner, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("redisstore.Leases.Acquire: %w", err)
+	}
+	return ok == 1, nil
+}
+
+func (l *Leases) Release(ctx context.Context, name, owner string) error {
+	if err := releaseLease.Run(ctx, l.client, []string{leasePrefix + name}, owner).Err(); err != nil {
+		return fmt.Errorf("redisstore.Leases.Release: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package redisstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/satrunjis/user-service/internal/domain"
+	"github.com/satrunjis/user-service/internal/service"
+)
+
+const (
+	failuresPrefix = "login_attempts:failures:"
+	backoffPrefix  = "login_attempts:backoff:"
+	lockPrefix     = "login_attempts:lock:"
+)
+
+type LoginAttempts struct {
+	client *redis.Client
+	logger *slog.Logger
+}
+
+var _ domain.LoginAttemptStore = (*LoginAttempts)(nil)
+
+func NewLoginAttempts(client *redis.Client, logger *slog.Logger) *LoginAttempts {
+	return &LoginAttempts{client: client, logger: logger}
+}
+
+func (s *LoginAttempts) RegisterFailure(ctx context.Context, subject string, window time.Duration) (int64, error) {
+	const op = "LoginAttempts.RegisterFailure"
+
+	key := failuresPrefix + subject
+	var incr *redis.IntCmd
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(ctx, key)
+		// Окно считается от последней неудачи
+		pipe.Expire(ctx, key, window)
+		return nil
+	})
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to register login failure", "operation", op, "subject", subject, "error", err)
+		return 0, service.StorageError(err)
+	}
+	return incr.Val(), nil
+}
+
+func (s *LoginAttempts) ResetFailures(ctx context.Context, subject string) error {
+	const op = "LoginAttempts.ResetFailures"
+
+	if err := s.client.Del(ctx, failuresPrefix+subject, backoffPrefix+subject).Err(); err != nil {
+		s.logger.ErrorContext(ctx, "failed to reset login failures", "operation", op, "subject", subject, "error", err)
+		return service.StorageError(err)
+	}
+	return nil
+}
+
+func (s *LoginAttempts) SetBackoff(ctx context.Context, subject string, d time.Duration) error {
+	const op = "LoginAttempts.SetBackoff"
+
+	if err := s.client.Set(ctx, backoffPrefix+subject, 1, d).Err(); err != nil {
+		s.logger.ErrorContext(ctx, "failed to set login backoff", "operation", op, "subject", subject, "error", err)
+		return service.StorageError(err)
+	}
+	return nil
+}
+
+func (s *LoginAttempts) Backoff(ctx context.Context, subject string) (time.Duration, error) {
+	const op = "LoginAttempts.Backoff"
+
+	ttl, err := s.client.PTTL(ctx, backoffPrefix+subject).Result()
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to read login backoff", "operation", op, "subject", subject, "error", err)
+		return 0, service.StorageError(err)
+	}
+	// Отрицательный TTL: ключа нет (-2) или нет срока жизни (-1)
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+func (s *LoginAttempts) Lock(ctx context.Context, lockout *domain.Lockout) error {
+	const op = "LoginAttempts.Lock"
+
+	data, err := json.Marshal(lockout)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "lockout encoding failed", "operation", op, "error", err)
+		return service.StorageError(err)
+	}
+
+	if err := s.client.Set(ctx, lockPrefix+lockout.Subject, data, time.Until(lockout.Until)).Err(); err != nil {
+		s.logger.ErrorContext(ctx, "failed to save lockout", "operation", op, "subject", lockout.Subject, "error", err)
+		return service.StorageError(err)
+	}
+	return nil
+}
+
+func (s *LoginAttempts) GetLock(ctx context.Context, subject string) (*domain.Lockout, error) {
+	const op = "LoginAttempts.GetLock"
+
+	data, err := s.client.Get(ctx, lockPrefix+subject).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to read lockout", "operation", op, "subject", subject, "error", err)
+		return nil, service.StorageError(err)
+	}
+
+	var lockout domain.Lockout
+	if err := json.Unmarshal(data, &lockout); err != nil {
+		s.logger.ErrorContext(ctx, "lockout decoding failed", "operation", op, "error", err)
+		return nil, service.StorageError(err)
+	}
+	return &lockout, nil
+}
+
+func (s *LoginAttempts) Unlock(ctx context.Context, subject string) (bool, error) {
+	const op = "LoginAttempts.Unlock"
+
+	n, err := s.client.Del(ctx, lockPrefix+subject, failuresPrefix+subject, backoffPrefix+subject).Result()
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to unlock", "operation", op, "subject", subject, "error", err)
+		return false, service.StorageError(err)
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,71 @@
+package redisstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/satrunjis/user-service/internal/domain"
+	"github.com/satrunjis/user-service/internal/service"
+)
+
+const mfaChallengePrefix = "mfa_challenge:"
+
+type MFAChallenges struct {
+	client *redis.Client
+	logger *slog.Logger
+}
+
+var _ domain.MFAChallengeStore = (*MFAChallenges)(nil)
+
+func NewMFAChallenges(client *redis.Client, logger *slog.Logger) *MFAChallenges {
+	return &MFAChallenges{client: client, logger: logger}
+}
+
+func (s *MFAChallenges) Save(ctx context.Context, tokenHash string, challenge *domain.MFAChallenge) error {
+	const op = "MFAChallenges.Save"
+
+	data, err := json.Marshal(challenge)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "challenge encoding failed", "operation", op, "error", err)
+		return service.StorageError(err)
+	}
+	if err := s.client.Set(ctx, mfaChallengePrefix+tokenHash, data, time.Until(challenge.ExpiresAt)).Err(); err != nil {
+		s.logger.ErrorContext(ctx, "failed to save challenge", "operation", op, "error", err)
+		return service.StorageError(err)
+	}
+	return nil
+}
+
+func (s *MFAChallenges) Get(ctx context.Context, tokenHash string) (*domain.MFAChallenge, error) {
+	const op = "MFAChallenges.Get"
+
+	data, err := s.client.Get(ctx, mfaChallengePrefix+tokenHash).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, service.NewServiceError(service.ErrCodeNotFound)
+	}
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to read challenge", "operation", op, "error", err)
+		return nil, service.StorageError(err)
+	}
+
+	var challenge domain.MFAChallenge
+	if err := json.Unmarshal(data, &challenge); err != nil {
+		s.logger.ErrorContext(ctx, "challenge decoding failed", "operation", op, "error", err)
+		return nil, service.StorageError(err)
+	}
+	return &challenge, nil
+}
+
+func (s *MFAChallenges) Delete(ctx context.Context, tokenHash string) error {
+	const op = "MFAChallenges.Delete"
+
+	if err := s.client.Del(ctx, mfaChallengePrefix+tokenHash).Err(); err != nil {
+		s.logger.ErrorContext(ctx, "failed to delete challenge", "operation", op, "error", err)
+		return service.StorageError(err)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package redisstore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/satrunjis/user-service/internal/domain"
+)
+
+const rateLimitPrefix = "ratelimit:"
+
+// Token bucket. Время берётся из Redis, чтобы экземпляры сервиса с разными часами считали одинаково.
+var tokenBucket = redis.NewScript(`
+local key = KEYS[1]
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local ttl = tonumber(ARGV[3])
+
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local data = redis.call('HMGET', key, 'tokens', 'ts')
+local tokens = tonumber(data[1])
+local ts = tonumber(data[2])
+if tokens == nil or ts == nil then
+  tokens = burst
+  ts = now
+end
+
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate)
+
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  retry = math.ceil((1 - tokens) / rate)
+end
+
+redis.call('HSET', key, 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', key, ttl)
+
+return {allowed, math.floor(tokens), retry, math.ceil((burst - tokens) / rate)}
+`)
+
+type RateLimits struct {
+	client *redis.Client
+}
+
+var _ domain.RateLimitStore = (*RateLimits)(nil)
+
+func NewRateLimits(client *redis.Client) *RateLimits {
+	return &RateLimits{client: client}
+}
+
+func (s *RateLimits) Take(ctx context.Context, key string, limit domain.RateLimit) (*domain.RateDecision, error) {
+	rate := float64(limit.Requests) / float64(limit.Period.Milliseconds())
+	ttl := limit.Period.Milliseconds() * 2
+
+	res, err := tokenBucket.Run(ctx, s.client, []string{rateLimitPrefix + key}, rate, limit.Requests, ttl).Int64Slice()
+	if err != nil {
+		return nil, fmt.Errorf("redisstore.RateLimits.Take: %w", err)
+	}
+	if len(res) != 4 {
+		return nil, fmt.Errorf("redisstore.RateLimits.Take: unexpected script result %v", res)
+	}
+
+	return &domain.RateDecision{
+		Allowed:    res[0] == 1,
+		Remaining:  int(res[1]),
+		RetryAfter: time.Duration(res[2]) * time.Millisecond,
+		Reset:      time.Duration(res[3]) * time.Millisecond,
+	}, nil
+}
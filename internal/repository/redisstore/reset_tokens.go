@@ -0,0 +1,76 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/satrunjis/user-service/internal/domain"
+	"github.com/satrunjis/user-service/internal/service"
+)
+
+const (
+	resetTokenPrefix     = "password_reset:token:"
+	resetTokenUserPrefix = "password_reset:user:"
+)
+
+type ResetTokens struct {
+	client *redis.Client
+	logger *slog.Logger
+}
+
+var _ domain.PasswordResetStore = (*ResetTokens)(nil)
+
+func NewResetTokens(client *redis.Client, logger *slog.Logger) *ResetTokens {
+	return &ResetTokens{client: client, logger: logger}
+}
+
+func (s *ResetTokens) Save(ctx context.Context, tokenHash, userID string, ttl time.Duration) error {
+	const op = "ResetTokens.Save"
+	log := s.logger.With("operation", op, "user_id", userID)
+
+	userKey := resetTokenUserPrefix + userID
+	previous, err := s.client.Get(ctx, userKey).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		log.ErrorContext(ctx, "failed to read previous token", "error", err)
+		return service.StorageError(err)
+	}
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if previous != "" {
+			pipe.Del(ctx, resetTokenPrefix+previous)
+		}
+		pipe.Set(ctx, resetTokenPrefix+tokenHash, userID, ttl)
+		pipe.Set(ctx, userKey, tokenHash, ttl)
+		return nil
+	})
+	if err != nil {
+		log.ErrorContext(ctx, "failed to save reset token", "error", err)
+		return service.StorageError(err)
+	}
+
+	log.DebugContext(ctx, "reset token saved", "ttl", ttl)
+	return nil
+}
+
+func (s *ResetTokens) Consume(ctx context.Context, tokenHash string) (string, error) {
+	const op = "ResetTokens.Consume"
+	log := s.logger.With("operation", op)
+
+	userID, err := s.client.GetDel(ctx, resetTokenPrefix+tokenHash).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", service.NewServiceError(service.ErrCodeNotFound)
+	}
+	if err != nil {
+		log.ErrorContext(ctx, "failed to consume reset token", "error", err)
+		return "", service.StorageError(err)
+	}
+
+	if err := s.client.Del(ctx, resetTokenUserPrefix+userID).Err(); err != nil {
+		log.WarnContext(ctx, "failed to clear user token reference", "error", err, "user_id", userID)
+	}
+
+	return userID, nil
+}
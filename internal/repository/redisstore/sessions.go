@@ -0,0 +1,163 @@
+package redisstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/satrunjis/user-service/internal/domain"
+	"github.com/satrunjis/user-service/internal/service"
+)
+
+const (
+	sessionPrefix     = "session:"
+	userSessionPrefix = "session:user:"
+)
+
+type Sessions struct {
+	client *redis.Client
+	logger *slog.Logger
+}
+
+var _ domain.SessionStore = (*Sessions)(nil)
+
+func NewSessions(client *redis.Client, logger *slog.Logger) *Sessions {
+	return &Sessions{client: client, logger: logger}
+}
+
+func (s *Sessions) Create(ctx context.Context, tokenHash string, session *domain.Session) error {
+	const op = "Sessions.Create"
+	log := s.logger.With("operation", op, "user_id", session.UserID)
+
+	data, err := json.Marshal(session)
+	if err != nil {
+		log.ErrorContext(ctx, "session encoding failed", "error", err)
+		return service.StorageError(err)
+	}
+
+	ttl := time.Until(session.ExpiresAt)
+	userKey := userSessionPrefix + session.UserID
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, sessionPrefix+tokenHash, data, ttl)
+		pipe.SAdd(ctx, userKey, tokenHash)
+		// Индекс сессий пользователя живёт не меньше самой длинной сессии
+		pipe.Expire(ctx, userKey, ttl)
+		return nil
+	})
+	if err != nil {
+		log.ErrorContext(ctx, "failed to save session", "error", err)
+		return service.StorageError(err)
+	}
+
+	log.DebugContext(ctx, "session created", "ttl", ttl)
+	return nil
+}
+
+func (s *Sessions) Get(ctx context.Context, tokenHash string) (*domain.Session, error) {
+	const op = "Sessions.Get"
+
+	data, err := s.client.Get(ctx, sessionPrefix+tokenHash).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, service.NewServiceError(service.ErrCodeNotFound)
+	}
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to read session", "operation", op, "error", err)
+		return nil, service.StorageError(err)
+	}
+
+	var session domain.Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		s.logger.ErrorContext(ctx, "session decoding failed", "operation", op, "error", err)
+		return nil, service.StorageError(err)
+	}
+	return &session, nil
+}
+
+func (s *Sessions) Delete(ctx context.Context, tokenHash string) error {
+	const op = "Sessions.Delete"
+
+	session, err := s.Get(ctx, tokenHash)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, sessionPrefix+tokenHash)
+		pipe.SRem(ctx, userSessionPrefix+session.UserID, tokenHash)
+		return nil
+	})
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to delete session", "operation", op, "error", err)
+		return service.StorageError(err)
+	}
+	return nil
+}
+
+func (s *Sessions) DeleteAllForUser(ctx context.Context, userID string) error {
+	const op = "Sessions.DeleteAllForUser"
+	log := s.logger.With("operation", op, "user_id", userID)
+
+	userKey := userSessionPrefix + userID
+	hashes, err := s.client.SMembers(ctx, userKey).Result()
+	if err != nil {
+		log.ErrorContext(ctx, "failed to list sessions", "error", err)
+		return service.StorageError(err)
+	}
+
+	keys := make([]string, 0, len(hashes)+1)
+	for _, h := range hashes {
+		keys = append(keys, sessionPrefix+h)
+	}
+	keys = append(keys, userKey)
+
+	if err := s.client.Del(ctx, keys...).Err(); err != nil {
+		log.ErrorContext(ctx, "failed to delete sessions", "error", err)
+		return service.StorageError(err)
+	}
+
+	log.InfoContext(ctx, "sessions revoked", "count", len(hashes))
+	return nil
+}
+
+func (s *Sessions) ListForUser(ctx context.Context, userID string) ([]*domain.Session, error) {
+	const op = "Sessions.ListForUser"
+	log := s.logger.With("operation", op, "user_id", userID)
+
+	hashes, err := s.client.SMembers(ctx, userSessionPrefix+userID).Result()
+	if err != nil {
+		log.ErrorContext(ctx, "failed to list sessions", "error", err)
+		return nil, service.StorageError(err)
+	}
+	if len(hashes) == 0 {
+		return []*domain.Session{}, nil
+	}
+
+	keys := make([]string, len(hashes))
+	for i, h := range hashes {
+		keys[i] = sessionPrefix + h
+	}
+	values, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		log.ErrorContext(ctx, "failed to read sessions", "error", err)
+		return nil, service.StorageError(err)
+	}
+
+	sessions := make([]*domain.Session, 0, len(values))
+	for _, value := range values {
+		// Истёкшие сессии остаются в индексе пользователя до его истечения
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+		var session domain.Session
+		if err := json.Unmarshal([]byte(data), &session); err != nil {
+			log.ErrorContext(ctx, "session decoding failed", "error", err)
+			return nil, service.StorageError(err)
+		}
+		sessions = append(sessions, &session)
+	}
+	return sessions, nil
+}
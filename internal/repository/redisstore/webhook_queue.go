@@ -0,0 +1,76 @@
+package redisstore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/satrunjis/user-service/internal/domain"
+)
+
+const (
+	// Задачи в sorted set, оценка - время следующей попытки в миллисекундах
+	webhookQueueKey = "webhooks:queue"
+	// Недоставленные, оценка - время переноса
+	webhookDeadKey = "webhooks:dead"
+)
+
+// Выбор и продление задач одним скриптом, чтобы два экземпляра не взяли одну задачу
+var claimWebhooks = redis.NewScript(`
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, tonumber(ARGV[3]))
+for _, id in ipairs(ids) do
+  redis.call('ZADD', KEYS[1], ARGV[2], id)
+end
+return ids
+`)
+
+type WebhookQueue struct {
+	client *redis.Client
+}
+
+var _ domain.WebhookQueue = (*WebhookQueue)(nil)
+
+func NewWebhookQueue(client *redis.Client) *WebhookQueue {
+	return &WebhookQueue{client: client}
+}
+
+func (q *WebhookQueue) Enqueue(ctx context.Context, deliveryID string, at time.Time) error {
+	err := q.client.ZAdd(ctx, webhookQueueKey, &redis.Z{Score: float64(at.UnixMilli()), Member: deliveryID}).Err()
+	if err != nil {
+		return fmt.Errorf("redisstore.WebhookQueue.Enqueue: %w", err)
+	}
+	return nil
+}
+
+func (q *WebhookQueue) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]string, error) {
+	ids, err := claimWebhooks.Run(ctx, q.client, []string{webhookQueueKey}, now.UnixMilli(), now.Add(lease).UnixMilli(), limit).StringSlice()
+	if err != nil {
+		return nil, fmt.Errorf("redisstore.WebhookQueue.Claim: %w", err)
+	}
+	return ids, nil
+}
+
+func (q *WebhookQueue) Complete(ctx context.Context, deliveryID string) error {
+	_, err := q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, webhookQueueKey, deliveryID)
+		pipe.ZRem(ctx, webhookDeadKey, deliveryID)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("redisstore.WebhookQueue.Complete: %w", err)
+	}
+	return nil
+}
+
+func (q *WebhookQueue) DeadLetter(ctx context.Context, deliveryID string, at time.Time) error {
+	_, err := q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, webhookQueueKey, deliveryID)
+		pipe.ZAdd(ctx, webhookDeadKey, &redis.Z{Score: float64(at.UnixMilli()), Member: deliveryID})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("redisstore.WebhookQueue.DeadLetter: %w", err)
+	}
+	return nil
+}
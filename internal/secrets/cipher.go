@@ -0,0 +1,70 @@
+// Package secrets шифрует небольшие значения (секреты TOTP и т.п.) для хранения в Elasticsearch.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const prefix = "v1:"
+
+var ErrMalformed = errors.New("malformed ciphertext")
+
+// Cipher AES-256-GCM, результат: v1:base64(nonce|ciphertext)
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// NewCipherFromBase64 принимает 32-байтный ключ в base64
+func NewCipherFromBase64(key string) (*Cipher, error) {
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("secrets.NewCipher: key is not valid base64: %w", err)
+	}
+	return NewCipher(raw)
+}
+
+func NewCipher(key []byte) (*Cipher, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("secrets.NewCipher: key must be 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("secrets.NewCipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("secrets.NewCipher: %w", err)
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+func (c *Cipher) Encrypt(plaintext string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("secrets.Encrypt: %w", err)
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return prefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (c *Cipher) Decrypt(ciphertext string) (string, error) {
+	if !strings.HasPrefix(ciphertext, prefix) {
+		return "", ErrMalformed
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(ciphertext, prefix))
+	if err != nil || len(raw) < c.aead.NonceSize() {
+		return "", ErrMalformed
+	}
+	nonce, sealed := raw[:c.aead.NonceSize()], raw[c.aead.NonceSize():]
+	plain, err := c.aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", fmt.Errorf("secrets.Decrypt: %w", err)
+	}
+	return string(plain), nil
+}
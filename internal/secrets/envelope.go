@@ -0,0 +1,223 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const envelopePrefix = "ev1"
+
+var keyIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+type MasterKey struct {
+	ID  string
+	Key []byte
+}
+
+// ParseMasterKeys разбирает список ключей "<id>:<32 байта в base64>" через запятую или с новой строки.
+// Первый ключ текущий, остальные нужны, чтобы читать данные, зашифрованные до замены ключа.
+func ParseMasterKeys(spec string) ([]MasterKey, error) {
+	var keys []MasterKey
+	for _, item := range strings.FieldsFunc(spec, func(r rune) bool { return r == ',' || r == '\n' || r == '\r' }) {
+		item = strings.TrimSpace(item)
+		if item == "" || strings.HasPrefix(item, "#") {
+			continue
+		}
+		id, value, ok := strings.Cut(item, ":")
+		if !ok {
+			return nil, fmt.Errorf("secrets.ParseMasterKeys: expected <id>:<base64 key>")
+		}
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("secrets.ParseMasterKeys: key %q is not valid base64: %w", id, err)
+		}
+		keys = append(keys, MasterKey{ID: strings.TrimSpace(id), Key: raw})
+	}
+	return keys, nil
+}
+
+// Envelope шифрование конвертом: каждое значение шифруется своим ключом данных AES-256-GCM,
+// ключ данных хранится рядом, зашифрованный мастер-ключом.
+// Результат: ev1:<id мастер-ключа>:base64(nonce|ключ данных):base64(nonce|ciphertext)
+type Envelope struct {
+	current string
+	masters map[string]cipher.AEAD
+	// Ключи слепого индекса выводятся из мастер-ключей, ключ из конфигурации напрямую не используется
+	index map[string][]byte
+	order []string
+}
+
+func NewEnvelope(keys []MasterKey) (*Envelope, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("secrets.NewEnvelope: no master keys")
+	}
+	e := &Envelope{
+		current: keys[0].ID,
+		masters: make(map[string]cipher.AEAD, len(keys)),
+		index:   make(map[string][]byte, len(keys)),
+	}
+	for _, k := range keys {
+		if !keyIDPattern.MatchString(k.ID) {
+			return nil, fmt.Errorf("secrets.NewEnvelope: invalid key id %q", k.ID)
+		}
+		if _, ok := e.masters[k.ID]; ok {
+			return nil, fmt.Errorf("secrets.NewEnvelope: duplicate key id %q", k.ID)
+		}
+		aead, err := newAEAD(k.Key)
+		if err != nil {
+			return nil, fmt.Errorf("secrets.NewEnvelope: key %q: %w", k.ID, err)
+		}
+		mac := hmac.New(sha256.New, k.Key)
+		mac.Write([]byte("blind-index"))
+		e.masters[k.ID] = aead
+		e.index[k.ID] = mac.Sum(nil)
+		e.order = append(e.order, k.ID)
+	}
+	return e, nil
+}
+
+// KeyID текущий мастер-ключ, им шифруются новые значения
+func (e *Envelope) KeyID() string {
+	return e.current
+}
+
+// Encrypt шифрует значение. aad привязывает шифртекст к месту хранения: расшифровать
+// его можно только с тем же aad, поэтому значение нельзя перенести в другое поле или документ.
+func (e *Envelope) Encrypt(plaintext, aad []byte) (string, error) {
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", fmt.Errorf("secrets.Envelope.Encrypt: %w", err)
+	}
+	wrapped, err := seal(e.masters[e.current], dataKey, []byte(e.current))
+	if err != nil {
+		return "", fmt.Errorf("secrets.Envelope.Encrypt: %w", err)
+	}
+	data, err := newAEAD(dataKey)
+	if err != nil {
+		return "", fmt.Errorf("secrets.Envelope.Encrypt: %w", err)
+	}
+	sealed, err := seal(data, plaintext, aad)
+	if err != nil {
+		return "", fmt.Errorf("secrets.Envelope.Encrypt: %w", err)
+	}
+	return strings.Join([]string{
+		envelopePrefix,
+		e.current,
+		base64.StdEncoding.EncodeToString(wrapped),
+		base64.StdEncoding.EncodeToString(sealed),
+	}, ":"), nil
+}
+
+func (e *Envelope) Decrypt(value string, aad []byte) ([]byte, error) {
+	keyID, wrapped, sealed, err := parseEnvelope(value)
+	if err != nil {
+		return nil, err
+	}
+	master, ok := e.masters[keyID]
+	if !ok {
+		return nil, fmt.Errorf("secrets.Envelope.Decrypt: unknown master key %q", keyID)
+	}
+	dataKey, err := open(master, wrapped, []byte(keyID))
+	if err != nil {
+		return nil, fmt.Errorf("secrets.Envelope.Decrypt: unwrap data key: %w", err)
+	}
+	data, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	plain, err := open(data, sealed, aad)
+	if err != nil {
+		return nil, fmt.Errorf("secrets.Envelope.Decrypt: %w", err)
+	}
+	return plain, nil
+}
+
+// Current сообщает, зашифровано ли значение текущим мастер-ключом
+func (e *Envelope) Current(value string) bool {
+	keyID, _, _, err := parseEnvelope(value)
+	return err == nil && keyID == e.current
+}
+
+// BlindIndex HMAC значения для поиска по точному совпадению без расшифровки
+func (e *Envelope) BlindIndex(value, context []byte) string {
+	return blindIndex(e.current, e.index[e.current], value, context)
+}
+
+// BlindIndexes слепые индексы значения для всех мастер-ключей: пока данные не перешифрованы
+// новым ключом, в индексе хранятся значения, посчитанные прежними
+func (e *Envelope) BlindIndexes(value, context []byte) []string {
+	indexes := make([]string, len(e.order))
+	for i, id := range e.order {
+		indexes[i] = blindIndex(id, e.index[id], value, context)
+	}
+	return indexes
+}
+
+func blindIndex(keyID string, key, value, context []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(context)
+	mac.Write([]byte{0})
+	mac.Write(value)
+	return keyID + ":" + hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+func parseEnvelope(value string) (keyID string, wrapped, sealed []byte, err error) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 4 || parts[0] != envelopePrefix {
+		return "", nil, nil, ErrMalformed
+	}
+	if wrapped, err = base64.StdEncoding.DecodeString(parts[2]); err != nil {
+		return "", nil, nil, ErrMalformed
+	}
+	if sealed, err = base64.StdEncoding.DecodeString(parts[3]); err != nil {
+		return "", nil, nil, ErrMalformed
+	}
+	return parts[1], wrapped, sealed, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("key must be 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func seal(aead cipher.AEAD, plaintext, aad []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func open(aead cipher.AEAD, sealed, aad []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrMalformed
+	}
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], aad)
+}
+
+// LoadMasterKeys берёт ключи из строки, а если она пуста - из файла в том же формате
+func LoadMasterKeys(spec, file string) ([]MasterKey, error) {
+	if spec == "" && file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("secrets.LoadMasterKeys: %w", err)
+		}
+		spec = string(data)
+	}
+	return ParseMasterKeys(spec)
+}
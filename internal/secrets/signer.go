@@ -0,0 +1,46 @@
+package secrets
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// Signer подписывает документы, которые проверяют вне сервиса (квитанции об удалении данных), ключом Ed25519
+type Signer struct {
+	key ed25519.PrivateKey
+}
+
+// NewSignerFromBase64 принимает 32-байтное начальное значение ключа Ed25519 в base64
+func NewSignerFromBase64(seed string) (*Signer, error) {
+	raw, err := base64.StdEncoding.DecodeString(seed)
+	if err != nil {
+		return nil, fmt.Errorf("secrets.NewSigner: key is not valid base64: %w", err)
+	}
+	if len(raw) != ed25519.SeedSize {
+		return nil, fmt.Errorf("secrets.NewSigner: key must be %d bytes, got %d", ed25519.SeedSize, len(raw))
+	}
+	return &Signer{key: ed25519.NewKeyFromSeed(raw)}, nil
+}
+
+func (s *Signer) Algorithm() string {
+	return "Ed25519"
+}
+
+// PublicKey открытый ключ в base64
+func (s *Signer) PublicKey() string {
+	return base64.StdEncoding.EncodeToString(s.key.Public().(ed25519.PublicKey))
+}
+
+// KeyID первые 8 байт SHA-256 открытого ключа в hex, отличает ключи после замены
+func (s *Signer) KeyID() string {
+	sum := sha256.Sum256(s.key.Public().(ed25519.PublicKey))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Sign возвращает подпись data в base64
+func (s *Signer) Sign(data []byte) string {
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(s.key, data))
+}
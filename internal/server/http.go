@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"time"
@@ -25,14 +26,27 @@ type Server struct {
 func NewServer(
 	cfg *config.HTTPServerConfig,
 	logger *slog.Logger,
-	userRepo domain.UserRepository,
-	cacheService service.CacheService,
-	mapService service.MapService,
-) *Server {
-	userService := service.NewUserService(userRepo, cacheService, mapService)
+	userService *service.UserService,
+	authService *service.AuthService,
+	webhookService *service.WebhookService,
+	streamService *service.EventStreamService,
+	privacyService *service.PrivacyService,
+	limiter *middleware.RateLimiter,
+) (*Server, error) {
+	handler.RegisterValidatorFieldNames()
 	userHandler := handler.NewUserHandler(userService, logger)
+	authHandler := handler.NewAuthHandler(authService, logger)
+	webhookHandler := handler.NewWebhookHandler(webhookService, logger)
+	streamHandler := handler.NewEventStreamHandler(streamService, logger)
+	privacyHandler := handler.NewPrivacyHandler(privacyService, logger)
 	router := gin.New()
+	// По адресу клиента считаются блокировка входа и лимит запросов, поэтому X-Forwarded-For
+	// принимается только от заданных прокси, по умолчанию ни от каких
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
 	router.Use(gin.Recovery())
+	router.Use(middleware.RequestID())
 	router.Use(middleware.ErrorHandler())
 	router.Use(middleware.Cors())
 	router.Use(func(c *gin.Context) {
@@ -43,10 +57,11 @@ func NewServer(
 			"path", c.Request.URL.Path,
 			"status", c.Writer.Status(),
 			"duration", time.Since(start),
+			"request_id", domain.RequestIDFrom(c.Request.Context()),
 		)
 	})
 
-	setupRoutes(router, userHandler)
+	setupRoutes(router, userHandler, authHandler, webhookHandler, streamHandler, privacyHandler, middleware.Authenticate(authService), limiter)
 
 	httpServer := &http.Server{
 		Addr:         cfg.Address,
@@ -60,25 +75,95 @@ func NewServer(
 		logger:     logger,
 		httpServer: httpServer,
 		router:     router,
-	}
+	}, nil
 }
-func setupRoutes(router *gin.Engine, userHandler *handler.UserHandler) {
+func setupRoutes(
+	router *gin.Engine,
+	userHandler *handler.UserHandler,
+	authHandler *handler.AuthHandler,
+	webhookHandler *handler.WebhookHandler,
+	streamHandler *handler.EventStreamHandler,
+	privacyHandler *handler.PrivacyHandler,
+	authenticate gin.HandlerFunc,
+	limiter *middleware.RateLimiter,
+) {
 	router.GET("/swagger", func(c *gin.Context) {
 		c.Redirect(http.StatusMovedPermanently, "/swagger/index.html")
 	})
 
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
+	usersRead := middleware.RequireScope(domain.ScopeUsersRead)
+	usersWrite := middleware.RequireScope(domain.ScopeUsersWrite)
+	// Пользователь по сессии без выданных прав видит только свою запись
+	selfOrUsersRead := middleware.RequireSelfOrScope(domain.ScopeUsersRead)
+	selfOrUsersWrite := middleware.RequireSelfOrScope(domain.ScopeUsersWrite)
+	selfOrAdmin := middleware.RequireSelfOrScope(domain.ScopeAdmin)
+	adminOnly := middleware.RequireScope(domain.ScopeAdmin)
+
 	group := router.Group("/api/v1")
 	{
-		users := group.Group("/users")
-		users.GET("", userHandler.GetUsers)
-		users.POST("", userHandler.CreateUser)
-		users.GET("/:id", userHandler.GetUser)
-		users.PUT("/:id", userHandler.UpdateUser)
-		users.PATCH("/:id", userHandler.UpdateUserPartial)
-		users.DELETE("/:id", userHandler.DeleteUser)
-		users.GET("/:id/map", userHandler.GetUserMap)
+		// Проверка логина доступна без авторизации, чтобы форма регистрации могла проверять его при вводе
+		group.GET("/users/availability", limiter.Limit("users_availability"), userHandler.CheckLoginAvailability)
+
+		group.GET("/social-networks", limiter.Limit(middleware.DefaultRateLimitKey), userHandler.ListSocialNetworks)
+
+		users := group.Group("/users", authenticate)
+		// Карта запрашивает тайлы у OpenStreetMap, поэтому её лимит строже и проверяется первым
+		users.GET("/:id/map", limiter.Limit("users_map"), middleware.RequireSelfOrScope(domain.ScopeMapsRead), userHandler.GetUserMap)
+		users.Use(limiter.Limit(middleware.DefaultRateLimitKey))
+		users.GET("", usersRead, userHandler.GetUsers)
+		users.GET("/attributes/schema", usersRead, userHandler.GetAttributeSchema)
+		users.GET("/encrypted-fields", usersRead, userHandler.ListEncryptedFields)
+		users.GET("/events", usersRead, streamHandler.StreamUserEvents)
+		users.POST("", usersWrite, userHandler.CreateUser)
+		users.GET("/:id", selfOrUsersRead, userHandler.GetUser)
+		users.GET("/:id/history", selfOrUsersRead, userHandler.GetUserHistory)
+		users.GET("/:id/export", selfOrAdmin, privacyHandler.ExportUser)
+		users.POST("/:id/erasure", adminOnly, privacyHandler.RequestErasure)
+		users.PUT("/:id", usersWrite, userHandler.UpdateUser)
+		users.PATCH("/:id", usersWrite, userHandler.UpdateUserPartial)
+		users.DELETE("/:id", usersWrite, userHandler.DeleteUser)
+		users.POST("/:id/activate", usersWrite, userHandler.ActivateUser)
+		users.POST("/:id/suspend", usersWrite, userHandler.SuspendUser)
+		users.POST("/:id/ban", usersWrite, userHandler.BanUser)
+		users.POST("/:id/restore", usersWrite, userHandler.RestoreUser)
+		users.POST("/:id/revert", usersWrite, userHandler.RevertUser)
+		users.POST("/:id/password", selfOrUsersWrite, userHandler.ChangePassword)
+		users.POST("/:id/2fa/totp", selfOrUsersWrite, authHandler.BeginTOTP)
+		users.POST("/:id/2fa/totp/confirm", selfOrUsersWrite, authHandler.ConfirmTOTP)
+		users.DELETE("/:id/2fa/totp", selfOrUsersWrite, authHandler.DisableTOTP)
+		users.POST("/:id/2fa/recovery-codes", selfOrUsersWrite, authHandler.RegenerateRecoveryCodes)
+
+		passwordReset := group.Group("/password-reset", limiter.Limit("password_reset"))
+		passwordReset.POST("", userHandler.RequestPasswordReset)
+		passwordReset.POST("/confirm", userHandler.ConfirmPasswordReset)
+
+		auth := group.Group("/auth")
+		auth.POST("/login", limiter.Limit("auth_login"), authHandler.Login)
+		auth.POST("/login/2fa", limiter.Limit("auth_login"), authHandler.LoginSecondFactor)
+		auth.POST("/logout", authHandler.Logout)
+
+		admin := group.Group("/admin", authenticate, limiter.Limit(middleware.DefaultRateLimitKey), middleware.RequireScope(domain.ScopeAdmin))
+		admin.POST("/lockouts/unlock", authHandler.Unlock)
+		admin.GET("/logins/blocklist", userHandler.ListLoginBlockRules)
+		admin.POST("/logins/blocklist", userHandler.CreateLoginBlockRule)
+		admin.DELETE("/logins/blocklist/:id", userHandler.DeleteLoginBlockRule)
+		admin.PUT("/attributes/schema", userHandler.UpdateAttributeSchema)
+		admin.POST("/api-keys", authHandler.CreateAPIKey)
+		admin.GET("/api-keys", authHandler.ListAPIKeys)
+		admin.DELETE("/api-keys/:id", authHandler.RevokeAPIKey)
+		admin.GET("/erasures/:id", privacyHandler.GetErasure)
+		admin.POST("/erasures/:id/resume", privacyHandler.ResumeErasure)
+
+		webhooks := group.Group("/webhooks", authenticate, limiter.Limit(middleware.DefaultRateLimitKey), middleware.RequireScope(domain.ScopeWebhooks))
+		webhooks.GET("", webhookHandler.ListWebhooks)
+		webhooks.POST("", webhookHandler.CreateWebhook)
+		webhooks.GET("/:id", webhookHandler.GetWebhook)
+		webhooks.PATCH("/:id", webhookHandler.UpdateWebhook)
+		webhooks.DELETE("/:id", webhookHandler.DeleteWebhook)
+		webhooks.GET("/:id/deliveries", webhookHandler.ListWebhookDeliveries)
+		webhooks.POST("/:id/deliveries/:delivery_id/replay", webhookHandler.ReplayWebhookDelivery)
 	}
 	router.GET("/health", healthCheck)
 
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/satrunjis/user-service/internal/domain"
+)
+
+const (
+	apiKeyPrefix      = "usk_"
+	apiKeySecretBytes = 32
+	// Отметка об использовании ключа пишется не чаще раза в минуту
+	apiKeyTouchInterval = time.Minute
+)
+
+type AccessOptions struct {
+	// BootstrapKey статический ключ со всеми правами для первичной настройки
+	BootstrapKey  string
+	SessionScopes []string
+}
+
+type CreatedAPIKey struct {
+	Key    string
+	APIKey *domain.APIKey
+}
+
+func (s *AuthService) CreateAPIKey(ctx context.Context, name string, scopes []string, expiresAt *time.Time) (*CreatedAPIKey, error) {
+	const op = "AuthService.CreateAPIKey"
+
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 100 {
+		return nil, NewMessageError(ErrCodeInvalidInput, MsgAPIKeyName)
+	}
+	if len(scopes) == 0 {
+		return nil, NewMessageError(ErrCodeInvalidInput, MsgScopesRequired)
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(domain.KnownScopes, scope) {
+			return nil, NewMessageError(ErrCodeInvalidInput, MsgUnknownScope, "scope", scope)
+		}
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, NewMessageError(ErrCodeInvalidInput, MsgAPIKeyExpiration)
+	}
+
+	secret, err := generateToken(apiKeySecretBytes)
+	if err != nil {
+		return nil, NewMessageError(ErrCodeInternal, MsgAPIKeyFailed)
+	}
+
+	key := &domain.APIKey{
+		ID:        uuid.New().String(),
+		Name:      name,
+		Hash:      hashToken(secret),
+		Scopes:    slices.Compact(slices.Sorted(slices.Values(scopes))),
+		CreatedAt: time.Now().UTC(),
+		CreatedBy: domain.PrincipalFrom(ctx).Actor(),
+		ExpiresAt: expiresAt,
+	}
+	if err := s.apiKeys.Create(ctx, key); err != nil {
+		return nil, mapRepositoryError(err, "create api key")
+	}
+
+	s.logger.InfoContext(ctx, "api key created", "operation", op, "key_id", key.ID, "scopes", key.Scopes, "actor", key.CreatedBy)
+
+	key.Hash = ""
+	return &CreatedAPIKey{Key: apiKeyPrefix + key.ID + "_" + secret, APIKey: key}, nil
+}
+
+func (s *AuthService) ListAPIKeys(ctx context.Context) ([]*domain.APIKey, error) {
+	keys, err := s.apiKeys.List(ctx)
+	if err != nil {
+		return nil, mapRepositoryError(err, "list api keys")
+	}
+	for _, key := range keys {
+		key.Hash = ""
+	}
+	return keys, nil
+}
+
+func (s *AuthService) RevokeAPIKey(ctx context.Context, id string) error {
+	const op = "AuthService.RevokeAPIKey"
+
+	if err := validationID(&id); err != nil {
+		return err
+	}
+	if err := s.apiKeys.Revoke(ctx, id, time.Now().UTC()); err != nil {
+		var serviceErr *ServiceError
+		if errors.As(err, &serviceErr) && serviceErr.Code == ErrCodeNotFound {
+			return NewMessageError(ErrCodeNotFound, MsgAPIKeyNotFound)
+		}
+		return mapRepositoryError(err, "revoke api key")
+	}
+
+	s.logger.InfoContext(ctx, "api key revoked", "operation", op, "key_id", id, "actor", domain.PrincipalFrom(ctx).Actor())
+	return nil
+}
+
+// Authenticate определяет вызывающего по API-ключу или токену сессии
+func (s *AuthService) Authenticate(ctx context.Context, apiKey, bearer string) (*domain.Principal, error) {
+	switch {
+	case apiKey != "":
+		return s.authenticateAPIKey(ctx, apiKey)
+	case bearer != "":
+		return s.authenticateSession(ctx, bearer)
+	default:
+		return nil, NewMessageError(ErrCodeUnauthorized, MsgAuthenticationRequired)
+	}
+}
+
+func (s *AuthService) authenticateAPIKey(ctx context.Context, raw string) (*domain.Principal, error) {
+	if s.access.BootstrapKey != "" && subtle.ConstantTimeCompare([]byte(raw), []byte(s.access.BootstrapKey)) == 1 {
+		return &domain.Principal{Type: domain.PrincipalBootstrap, ID: "bootstrap", Scopes: []string{domain.ScopeAdmin}}, nil
+	}
+
+	invalid := NewMessageError(ErrCodeUnauthorized, MsgAPIKeyInvalid)
+
+	id, secret, ok := strings.Cut(strings.TrimPrefix(raw, apiKeyPrefix), "_")
+	if !ok || !strings.HasPrefix(raw, apiKeyPrefix) || uuid.Validate(id) != nil {
+		return nil, invalid
+	}
+
+	key, err := s.apiKeys.GetByID(ctx, id)
+	if err != nil {
+		var serviceErr *ServiceError
+		if errors.As(err, &serviceErr) && serviceErr.Code == ErrCodeNotFound {
+			return nil, invalid
+		}
+		return nil, mapRepositoryError(err, "authenticate")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(key.Hash), []byte(hashToken(secret))) != 1 {
+		return nil, invalid
+	}
+	now := time.Now()
+	if key.RevokedAt != nil {
+		return nil, NewMessageError(ErrCodeUnauthorized, MsgAPIKeyRevoked)
+	}
+	if key.ExpiresAt != nil && now.After(*key.ExpiresAt) {
+		return nil, NewMessageError(ErrCodeUnauthorized, MsgAPIKeyExpired)
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > apiKeyTouchInterval {
+		if err := s.apiKeys.TouchLastUsed(ctx, key.ID, now.UTC()); err != nil {
+			s.logger.WarnContext(ctx, "failed to update api key usage", "key_id", key.ID, "error", err)
+		}
+	}
+
+	return &domain.Principal{Type: domain.PrincipalAPIKey, ID: key.ID, Scopes: key.Scopes}, nil
+}
+
+func (s *AuthService) authenticateSession(ctx context.Context, token string) (*domain.Principal, error) {
+	session, err := s.sessions.Get(ctx, hashToken(token))
+	if err != nil {
+		var serviceErr *ServiceError
+		if errors.As(err, &serviceErr) && serviceErr.Code == ErrCodeNotFound {
+			return nil, NewMessageError(ErrCodeUnauthorized, MsgSessionInvalid)
+		}
+		return nil, mapRepositoryError(err, "authenticate")
+	}
+
+	return &domain.Principal{
+		Type:   domain.PrincipalSession,
+		ID:     hashToken(token)[:16],
+		UserID: session.UserID,
+		Scopes: s.access.SessionScopes,
+	}, nil
+}
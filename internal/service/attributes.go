@@ -0,0 +1,339 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/satrunjis/user-service/internal/domain"
+)
+
+const (
+	maxAttributes = 100
+	// Схема перечитывается не реже этого интервала, как и список запретов логинов
+	attributeSchemaRefreshInterval = 30 * time.Second
+)
+
+var attributeNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+var attributeTypes = []string{
+	domain.AttributeString,
+	domain.AttributeInteger,
+	domain.AttributeNumber,
+	domain.AttributeBoolean,
+	domain.AttributeDate,
+}
+
+// attributeSchemaCache кеширует схему атрибутов между запросами
+type attributeSchemaCache struct {
+	mu       sync.Mutex
+	schema   *domain.AttributeSchema
+	loadedAt time.Time
+}
+
+func (s *UserService) GetAttributeSchema(ctx context.Context) (*domain.AttributeSchema, error) {
+	return s.attributeSchema(ctx)
+}
+
+// UpdateAttributeSchema заменяет схему целиком. Тип существующего атрибута поменять нельзя:
+// в индексе уже есть значения старого типа.
+func (s *UserService) UpdateAttributeSchema(ctx context.Context, schema *domain.AttributeSchema) (*domain.AttributeSchema, error) {
+	const op = "UserService.UpdateAttributeSchema"
+
+	if schema.Properties == nil {
+		schema.Properties = map[string]domain.AttributeProperty{}
+	}
+	if errs := schemaProblems(schema); len(errs) != 0 {
+		return nil, NewValidationError(errs...)
+	}
+
+	current, err := s.loadAttributeSchema(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for name, prop := range schema.Properties {
+		if old, ok := current.Properties[name]; ok && old.Type != prop.Type {
+			return nil, NewMessageError(ErrCodeConflict, MsgAttributeTypeChanged, "name", name, "from", old.Type, "to", prop.Type)
+		}
+	}
+
+	now := time.Now().UTC()
+	schema.Required = slices.Compact(slices.Sorted(slices.Values(schema.Required)))
+	schema.Version = current.Version + 1
+	schema.UpdatedAt = &now
+	schema.UpdatedBy = domain.PrincipalFrom(ctx).Actor()
+
+	if err := s.attributes.Save(ctx, schema); err != nil {
+		if HasCode(err, ErrCodeConflict) {
+			return nil, err
+		}
+		return nil, mapRepositoryError(err, "save attribute schema")
+	}
+	s.invalidateAttributeSchema()
+
+	s.logger.InfoContext(ctx, "attribute schema updated", "operation", op, "version", schema.Version, "attributes", len(schema.Properties), "actor", schema.UpdatedBy)
+	return schema, nil
+}
+
+// attributeSchema возвращает актуальную схему. Если хранилище недоступно,
+// используется последняя загруженная схема.
+func (s *UserService) attributeSchema(ctx context.Context) (*domain.AttributeSchema, error) {
+	c := s.attrCache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.schema != nil && time.Since(c.loadedAt) < attributeSchemaRefreshInterval {
+		return c.schema, nil
+	}
+
+	schema, err := s.loadAttributeSchema(ctx)
+	if err != nil {
+		if c.schema != nil {
+			s.logger.WarnContext(ctx, "failed to refresh attribute schema, using cached schema", "error", err)
+			return c.schema, nil
+		}
+		return nil, err
+	}
+
+	c.schema = schema
+	c.loadedAt = time.Now()
+	return schema, nil
+}
+
+// loadAttributeSchema читает схему из хранилища, пока схема не задана, она пустая
+func (s *UserService) loadAttributeSchema(ctx context.Context) (*domain.AttributeSchema, error) {
+	schema, err := s.attributes.Get(ctx)
+	if err != nil {
+		if HasCode(err, ErrCodeNotFound) {
+			return &domain.AttributeSchema{Properties: map[string]domain.AttributeProperty{}}, nil
+		}
+		return nil, mapRepositoryError(err, "load attribute schema")
+	}
+	if schema.Properties == nil {
+		schema.Properties = map[string]domain.AttributeProperty{}
+	}
+	return schema, nil
+}
+
+func (s *UserService) invalidateAttributeSchema() {
+	s.attrCache.mu.Lock()
+	s.attrCache.loadedAt = time.Time{}
+	s.attrCache.mu.Unlock()
+}
+
+func schemaProblems(schema *domain.AttributeSchema) []FieldError {
+	var errs []FieldError
+
+	if len(schema.Properties) > maxAttributes {
+		errs = append(errs, fieldError("properties", ValidationTooMany, fmt.Sprintf("no more than %d attributes are allowed", maxAttributes), "max", maxAttributes))
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(schema.Properties)) {
+		prop := schema.Properties[name]
+		field := "properties." + name
+
+		if !attributeNamePattern.MatchString(name) {
+			errs = append(errs, fieldError(field, ValidationFormat, "attribute name must start with a-z and contain only a-z, 0-9 and _ (up to 50 characters)"))
+		}
+		if !slices.Contains(attributeTypes, prop.Type) {
+			errs = append(errs, fieldError(field+".type", ValidationUnsupported, "unsupported attribute type "+prop.Type))
+			continue
+		}
+
+		numeric := prop.Type == domain.AttributeInteger || prop.Type == domain.AttributeNumber
+		if (prop.Minimum != nil || prop.Maximum != nil) && !numeric {
+			errs = append(errs, fieldError(field, ValidationUnsupported, "minimum and maximum apply only to integer and number attributes"))
+		}
+		if prop.Minimum != nil && prop.Maximum != nil && *prop.Minimum > *prop.Maximum {
+			errs = append(errs, fieldError(field+".minimum", ValidationRange, "minimum must not exceed maximum"))
+		}
+
+		if (prop.MinLength != nil || prop.MaxLength != nil) && prop.Type != domain.AttributeString {
+			errs = append(errs, fieldError(field, ValidationUnsupported, "min_length and max_length apply only to string attributes"))
+		}
+		if (prop.MinLength != nil && *prop.MinLength < 0) || (prop.MaxLength != nil && *prop.MaxLength < 0) ||
+			(prop.MinLength != nil && prop.MaxLength != nil && *prop.MinLength > *prop.MaxLength) {
+			errs = append(errs, fieldError(field+".min_length", ValidationRange, "length limits must be non-negative and min_length must not exceed max_length"))
+		}
+
+		if len(prop.Enum) > 0 {
+			if prop.Type == domain.AttributeBoolean || prop.Type == domain.AttributeDate {
+				errs = append(errs, fieldError(field+".enum", ValidationUnsupported, "enum is not supported for "+prop.Type+" attributes"))
+				continue
+			}
+			for i, v := range prop.Enum {
+				if _, ok := attributeValue(prop, v); !ok {
+					errs = append(errs, fieldError(fmt.Sprintf("%s.enum[%d]", field, i), ValidationType, "enum value does not match attribute type", "expected", prop.Type))
+				}
+			}
+		}
+	}
+
+	for _, name := range schema.Required {
+		if _, ok := schema.Properties[name]; !ok {
+			errs = append(errs, fieldError("required", ValidationUnknown, "required attribute "+name+" is not defined"))
+		}
+	}
+	return errs
+}
+
+// attributeProblems проверяет значения по схеме и приводит их к хранимому виду:
+// целые числа к int64, даты к RFC3339 в UTC. При частичном обновлении
+// обязательность не проверяется, а null удаляет значение.
+func attributeProblems(attrs map[string]any, schema *domain.AttributeSchema, partial bool) []FieldError {
+	if schema == nil {
+		return nil
+	}
+	var errs []FieldError
+
+	for _, name := range slices.Sorted(maps.Keys(attrs)) {
+		field := "attributes." + name
+		value := attrs[name]
+
+		prop, ok := schema.Properties[name]
+		if !ok {
+			errs = append(errs, fieldError(field, ValidationUnknown, "attribute "+name+" is not defined in the schema"))
+			continue
+		}
+		if value == nil {
+			if !partial {
+				delete(attrs, name)
+			}
+			continue
+		}
+
+		normalized, ok := attributeValue(prop, value)
+		if !ok {
+			errs = append(errs, fieldError(field, ValidationType, "attribute "+name+" must be of type "+prop.Type, "expected", prop.Type))
+			continue
+		}
+		errs = append(errs, attributeConstraints(field, prop, normalized)...)
+		attrs[name] = normalized
+	}
+
+	if !partial {
+		for _, name := range schema.Required {
+			if attrs[name] == nil {
+				errs = append(errs, fieldError("attributes."+name, ValidationRequired, "attribute "+name+" is required"))
+			}
+		}
+	}
+	return errs
+}
+
+// attributeValue приводит значение из JSON к типу атрибута
+func attributeValue(prop domain.AttributeProperty, value any) (any, bool) {
+	switch prop.Type {
+	case domain.AttributeString:
+		v, ok := value.(string)
+		return v, ok
+	case domain.AttributeInteger:
+		v, ok := value.(float64)
+		if !ok || v != math.Trunc(v) || math.Abs(v) > 1<<53 {
+			return nil, false
+		}
+		return int64(v), true
+	case domain.AttributeNumber:
+		v, ok := value.(float64)
+		return v, ok
+	case domain.AttributeBoolean:
+		v, ok := value.(bool)
+		return v, ok
+	case domain.AttributeDate:
+		v, ok := value.(string)
+		if !ok {
+			return nil, false
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, false
+		}
+		return t.UTC().Format(time.RFC3339), true
+	}
+	return nil, false
+}
+
+func attributeConstraints(field string, prop domain.AttributeProperty, value any) []FieldError {
+	var errs []FieldError
+
+	if len(prop.Enum) > 0 && !slices.ContainsFunc(prop.Enum, func(e any) bool { return fmt.Sprint(e) == fmt.Sprint(value) }) {
+		errs = append(errs, fieldError(field, ValidationUnsupported, field+" must be one of the allowed values", "allowed", prop.Enum))
+	}
+
+	switch v := value.(type) {
+	case string:
+		length := utf8.RuneCountInString(v)
+		if prop.MinLength != nil && length < *prop.MinLength {
+			errs = append(errs, fieldError(field, ValidationMinLength, fmt.Sprintf("%s must be at least %d characters", field, *prop.MinLength), "min", *prop.MinLength))
+		}
+		if prop.MaxLength != nil && length > *prop.MaxLength {
+			errs = append(errs, fieldError(field, ValidationMaxLength, fmt.Sprintf("%s exceeds %d character limit", field, *prop.MaxLength), "max", *prop.MaxLength))
+		}
+	case int64:
+		errs = append(errs, numberConstraints(field, prop, float64(v))...)
+	case float64:
+		errs = append(errs, numberConstraints(field, prop, v)...)
+	}
+	return errs
+}
+
+func numberConstraints(field string, prop domain.AttributeProperty, v float64) []FieldError {
+	var errs []FieldError
+	if prop.Minimum != nil && v < *prop.Minimum {
+		errs = append(errs, fieldError(field, ValidationMinValue, fmt.Sprintf("%s must be at least %v", field, *prop.Minimum), "min", *prop.Minimum))
+	}
+	if prop.Maximum != nil && v > *prop.Maximum {
+		errs = append(errs, fieldError(field, ValidationMaxValue, fmt.Sprintf("%s must be at most %v", field, *prop.Maximum), "max", *prop.Maximum))
+	}
+	return errs
+}
+
+// attributeFilterProblems проверяет фильтры поиска: атрибут должен быть в схеме,
+// значение соответствовать типу, а сравнение gte/lte допустимо только для чисел и дат
+func attributeFilterProblems(filters []domain.AttributeFilter, schema *domain.AttributeSchema) []FieldError {
+	var errs []FieldError
+	for i := range filters {
+		f := &filters[i]
+		field := "attr." + f.Name
+
+		prop, ok := schema.Properties[f.Name]
+		if !ok {
+			errs = append(errs, fieldError(field, ValidationUnknown, "attribute "+f.Name+" is not defined in the schema"))
+			continue
+		}
+		if f.Op != "eq" && prop.Type != domain.AttributeInteger && prop.Type != domain.AttributeNumber && prop.Type != domain.AttributeDate {
+			errs = append(errs, fieldError(field+"."+f.Op, ValidationUnsupported, "range filters apply only to integer, number and date attributes"))
+			continue
+		}
+
+		var valid bool
+		switch prop.Type {
+		case domain.AttributeInteger:
+			_, err := strconv.ParseInt(f.Value, 10, 64)
+			valid = err == nil
+		case domain.AttributeNumber:
+			_, err := strconv.ParseFloat(f.Value, 64)
+			valid = err == nil
+		case domain.AttributeBoolean:
+			valid = f.Value == "true" || f.Value == "false"
+		case domain.AttributeDate:
+			t, err := time.Parse(time.RFC3339, f.Value)
+			if valid = err == nil; valid {
+				f.Value = t.UTC().Format(time.RFC3339)
+			}
+		default:
+			valid = true
+		}
+		if !valid {
+			errs = append(errs, fieldError(field, ValidationType, "filter value for "+f.Name+" must be of type "+prop.Type, "expected", prop.Type))
+		}
+	}
+	return errs
+}
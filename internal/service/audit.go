@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"reflect"
+	"slices"
+
+	"github.com/satrunjis/user-service/internal/domain"
+)
+
+const (
+	defaultHistorySize = 20
+	maxHistorySize     = 100
+	// Elasticsearch не отдаёт результаты глубже 10000 записей без scroll
+	maxHistoryWindow = 10000
+)
+
+// auditSecretFields значения этих полей в журнал не попадают
+var auditSecretFields = []string{"password", "two_factor"}
+
+// secretFields поля, значения которых не попадают в журнал и события: секреты
+// и поля, которые хранятся зашифрованными
+func (s *UserService) secretFields() []string {
+	fields := slices.Clone(auditSecretFields)
+	for _, f := range s.userRepo.EncryptedFields() {
+		fields = append(fields, f.Field)
+	}
+	return fields
+}
+
+func (s *UserService) UserHistory(ctx context.Context, id *string, page, size int) (*domain.AuditPage, error) {
+	if err := validationID(id); err != nil {
+		return nil, err
+	}
+	if page <= 0 {
+		page = 1
+	}
+	if size <= 0 || size > maxHistorySize {
+		size = defaultHistorySize
+	}
+	if page*size > maxHistoryWindow {
+		return nil, NewValidationError(fieldError("page", ValidationRange, "page is too deep, at most 10000 entries can be listed", "min", 1, "max", maxHistoryWindow/size))
+	}
+
+	history, err := s.audit.History(ctx, *id, page, size)
+	if err != nil {
+		return nil, mapRepositoryError(err, "user history")
+	}
+	return history, nil
+}
+
+// auditOperations операция журнала для каждого типа события. Смена геолокации
+// уже отражена в основном событии и отдельно не записывается.
+var auditOperations = map[string]string{
+	domain.EventUserCreated:         domain.AuditCreate,
+	domain.EventUserReplaced:        domain.AuditReplace,
+	domain.EventUserPatched:         domain.AuditUpdate,
+	domain.EventUserDeleted:         domain.AuditDelete,
+	domain.EventUserRestored:        domain.AuditRestore,
+	domain.EventUserStatusChanged:   domain.AuditStatus,
+	domain.EventUserPasswordChanged: domain.AuditPassword,
+	domain.EventUserPurged:          domain.AuditPurge,
+}
+
+// RecordAudit подписчик шины событий, сохраняет запись журнала. Журнал пропускает
+// уже записанные события, поэтому после ошибки событие можно доставить повторно.
+func (s *UserService) RecordAudit(ctx context.Context, event domain.UserEvent) error {
+	operation, ok := auditOperations[event.Type]
+	if !ok {
+		return nil
+	}
+	changes := event.Changes
+	if changes == nil {
+		changes = []domain.FieldChange{}
+	}
+
+	entry := &domain.AuditEntry{
+		UserID:    event.UserID,
+		Operation: operation,
+		Actor:     event.Actor,
+		RequestID: event.RequestID,
+		EventID:   event.ID,
+		At:        event.OccurredAt,
+		Changes:   changes,
+	}
+	if err := s.audit.Append(ctx, entry); err != nil {
+		s.logger.ErrorContext(ctx, "failed to record audit entry", "user_id", event.UserID, "audit_operation", operation, "event_id", event.ID, "error", err)
+		return err
+	}
+	return nil
+}
+
+// userDoc представляет пользователя так же, как он хранится в индексе, без ID
+func userDoc(u *domain.User) map[string]any {
+	doc := map[string]any{}
+	if u == nil {
+		return doc
+	}
+	data, err := json.Marshal(u)
+	if err != nil {
+		return doc
+	}
+	_ = json.Unmarshal(data, &doc)
+	delete(doc, "id")
+	return doc
+}
+
+// patchedDoc применяет частичное обновление так же, как Elasticsearch:
+// вложенные объекты объединяются, остальные значения заменяются
+func patchedDoc(before map[string]any, patch *domain.User) map[string]any {
+	return mergeDoc(maps.Clone(before), userDoc(patch))
+}
+
+func mergeDoc(dst, src map[string]any) map[string]any {
+	for k, v := range src {
+		srcMap, srcOK := v.(map[string]any)
+		dstMap, dstOK := dst[k].(map[string]any)
+		if srcOK && dstOK {
+			dst[k] = mergeDoc(maps.Clone(dstMap), srcMap)
+			continue
+		}
+		dst[k] = v
+	}
+	return dst
+}
+
+// docDiff возвращает изменённые поля. Значения полей из secret заменяются на AuditRedacted.
+func docDiff(before, after map[string]any, secret []string) []domain.FieldChange {
+	flatBefore, flatAfter := map[string]any{}, map[string]any{}
+	flattenDoc("", before, flatBefore, secret)
+	flattenDoc("", after, flatAfter, secret)
+
+	fields := slices.Collect(maps.Keys(flatBefore))
+	for field := range flatAfter {
+		if _, ok := flatBefore[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+	slices.Sort(fields)
+
+	var changes []domain.FieldChange
+	for _, field := range fields {
+		b, a := flatBefore[field], flatAfter[field]
+		if reflect.DeepEqual(b, a) {
+			continue
+		}
+		if slices.Contains(secret, field) {
+			b, a = redact(b), redact(a)
+		}
+		changes = append(changes, domain.FieldChange{Field: field, Before: b, After: a})
+	}
+	return changes
+}
+
+func flattenDoc(prefix string, doc map[string]any, out map[string]any, secret []string) {
+	for k, v := range doc {
+		path := prefix + k
+		if nested, ok := v.(map[string]any); ok && len(nested) > 0 && !slices.Contains(secret, path) {
+			flattenDoc(path+".", nested, out, secret)
+			continue
+		}
+		out[path] = v
+	}
+}
+
+func redact(v any) any {
+	if v == nil {
+		return nil
+	}
+	return domain.AuditRedacted
+}
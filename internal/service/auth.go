@@ -0,0 +1,331 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/satrunjis/user-service/internal/domain"
+	"github.com/satrunjis/user-service/internal/logins"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const sessionTokenBytes = 32
+
+type LockoutPolicy struct {
+	Window       time.Duration
+	BackoffAfter int64
+	BackoffBase  time.Duration
+	BackoffMax   time.Duration
+	LockAfter    int64
+	LockDuration time.Duration
+	IPLockAfter  int64
+}
+
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// LoginResult содержит либо сессию, либо токен для второго шага входа (MFARequired)
+type LoginResult struct {
+	Token   string
+	Session *domain.Session
+
+	MFARequired  bool
+	MFAToken     string
+	MFAExpiresAt time.Time
+}
+
+type AuthService struct {
+	userRepo   domain.UserRepository
+	sessions   domain.SessionStore
+	attempts   domain.LoginAttemptStore
+	challenges domain.MFAChallengeStore
+	apiKeys    domain.APIKeyRepository
+	cipher     SecretCipher
+	policy     LockoutPolicy
+	twoFactor  TwoFactorOptions
+	access     AccessOptions
+	sessionTTL time.Duration
+	logger     *slog.Logger
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+func NewAuthService(
+	repo domain.UserRepository,
+	sessions domain.SessionStore,
+	attempts domain.LoginAttemptStore,
+	challenges domain.MFAChallengeStore,
+	apiKeys domain.APIKeyRepository,
+	cipher SecretCipher,
+	policy LockoutPolicy,
+	twoFactor TwoFactorOptions,
+	access AccessOptions,
+	sessionTTL time.Duration,
+	logger *slog.Logger,
+) *AuthService {
+	return &AuthService{
+		userRepo:   repo,
+		sessions:   sessions,
+		attempts:   attempts,
+		challenges: challenges,
+		apiKeys:    apiKeys,
+		cipher:     cipher,
+		policy:     policy,
+		twoFactor:  twoFactor,
+		access:     access,
+		sessionTTL: sessionTTL,
+		logger:     logger,
+	}
+}
+
+func (s *AuthService) Login(ctx context.Context, login, password string, client ClientInfo) (*LoginResult, error) {
+	const op = "AuthService.Login"
+	log := s.logger.With("operation", op, "client_ip", client.IP)
+
+	login = strings.TrimSpace(login)
+	if login == "" || password == "" {
+		return nil, NewMessageError(ErrCodeInvalidInput, MsgCredentialsRequired)
+	}
+
+	if err := s.checkThrottle(ctx, loginSubject(login), ipSubject(client.IP)); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByLogin(ctx, &login)
+	if err != nil {
+		var serviceErr *ServiceError
+		if !errors.As(err, &serviceErr) || serviceErr.Code != ErrCodeNotFound {
+			return nil, mapRepositoryError(err, "login")
+		}
+		// Сравнение с фиктивным хешем выравнивает время ответа для несуществующих логинов
+		_ = bcrypt.CompareHashAndPassword(getDummyHash(), []byte(password))
+		s.registerFailure(ctx, login, client.IP)
+		log.InfoContext(ctx, "login failed: unknown login")
+		return nil, NewMessageError(ErrCodeUnauthorized, MsgInvalidCredentials)
+	}
+
+	if user.Password == nil || bcrypt.CompareHashAndPassword([]byte(*user.Password), []byte(password)) != nil {
+		s.registerFailure(ctx, login, client.IP)
+		log.InfoContext(ctx, "login failed: wrong password", "user_id", *user.ID)
+		return nil, NewMessageError(ErrCodeUnauthorized, MsgInvalidCredentials)
+	}
+
+	if err := loginStatusError(user); err != nil {
+		log.InfoContext(ctx, "login rejected by account status", "user_id", *user.ID, "status", user.CurrentStatus())
+		return nil, err
+	}
+
+	if user.TwoFactor != nil && user.TwoFactor.Enabled {
+		log.InfoContext(ctx, "password accepted, second factor required", "user_id", *user.ID)
+		return s.startChallenge(ctx, user, login, client)
+	}
+
+	if err := s.attempts.ResetFailures(ctx, loginSubject(login)); err != nil {
+		log.WarnContext(ctx, "failed to reset login failures", "error", err)
+	}
+
+	result, err := s.createSession(ctx, *user.ID, client)
+	if err != nil {
+		return nil, err
+	}
+
+	log.InfoContext(ctx, "user logged in", "user_id", *user.ID)
+	return result, nil
+}
+
+// loginStatusError проверяет, что статус учётной записи допускает вход.
+// Статус сообщается только после проверки пароля.
+func loginStatusError(user *domain.User) error {
+	switch user.CurrentStatus() {
+	case domain.StatusSuspended:
+		return NewMessageError(ErrCodeForbidden, MsgAccountSuspended)
+	case domain.StatusBanned:
+		return NewMessageError(ErrCodeForbidden, MsgAccountBanned)
+	case domain.StatusDeleted:
+		return NewMessageError(ErrCodeUnauthorized, MsgInvalidCredentials)
+	}
+	return nil
+}
+
+func (s *AuthService) Logout(ctx context.Context, token string) error {
+	if token == "" {
+		return NewMessageError(ErrCodeUnauthorized, MsgSessionTokenRequired)
+	}
+
+	if err := s.sessions.Delete(ctx, hashToken(token)); err != nil {
+		var serviceErr *ServiceError
+		if errors.As(err, &serviceErr) && serviceErr.Code == ErrCodeNotFound {
+			return NewMessageError(ErrCodeUnauthorized, MsgSessionInvalid)
+		}
+		return mapRepositoryError(err, "logout")
+	}
+	return nil
+}
+
+// Unlock снимает блокировку и обнуляет счётчики для логина и/или IP-адреса
+func (s *AuthService) Unlock(ctx context.Context, login, ip string) (bool, error) {
+	const op = "AuthService.Unlock"
+	log := s.logger.With("operation", op)
+
+	login = strings.TrimSpace(login)
+	ip = strings.TrimSpace(ip)
+	if login == "" && ip == "" {
+		return false, NewMessageError(ErrCodeInvalidInput, MsgUnlockSubjectRequired)
+	}
+
+	var subjects []string
+	if login != "" {
+		subjects = append(subjects, loginSubject(login))
+	}
+	if ip != "" {
+		subjects = append(subjects, ipSubject(ip))
+	}
+
+	unlocked := false
+	for _, subject := range subjects {
+		ok, err := s.attempts.Unlock(ctx, subject)
+		if err != nil {
+			return false, mapRepositoryError(err, "unlock")
+		}
+		if ok {
+			unlocked = true
+			log.InfoContext(ctx, "login lockout removed", "subject", subject)
+		}
+	}
+	return unlocked, nil
+}
+
+func (s *AuthService) checkThrottle(ctx context.Context, subjects ...string) error {
+	for _, subject := range subjects {
+		lockout, err := s.attempts.GetLock(ctx, subject)
+		if err != nil {
+			return mapRepositoryError(err, "login")
+		}
+		if lockout != nil {
+			err := NewMessageError(ErrCodeRateLimited, MsgLoginLocked)
+			err.RetryAfter = time.Until(lockout.Until)
+			return err
+		}
+
+		wait, err := s.attempts.Backoff(ctx, subject)
+		if err != nil {
+			return mapRepositoryError(err, "login")
+		}
+		if wait > 0 {
+			err := NewMessageError(ErrCodeRateLimited, MsgLoginBackoff)
+			err.RetryAfter = wait
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *AuthService) registerFailure(ctx context.Context, login, ip string) {
+	log := s.logger.With("operation", "AuthService.registerFailure", "client_ip", ip)
+
+	subject := loginSubject(login)
+	failures, err := s.attempts.RegisterFailure(ctx, subject, s.policy.Window)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to register login failure", "error", err)
+	} else {
+		switch {
+		case failures >= s.policy.LockAfter:
+			s.lock(ctx, subject, failures, ip)
+		case failures >= s.policy.BackoffAfter:
+			delay := backoffDelay(s.policy, failures)
+			if err := s.attempts.SetBackoff(ctx, subject, delay); err != nil {
+				log.ErrorContext(ctx, "failed to set login backoff", "error", err)
+			}
+		}
+	}
+
+	if ip == "" {
+		return
+	}
+	subject = ipSubject(ip)
+	failures, err = s.attempts.RegisterFailure(ctx, subject, s.policy.Window)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to register login failure", "error", err)
+		return
+	}
+	if failures >= s.policy.IPLockAfter {
+		s.lock(ctx, subject, failures, ip)
+	}
+}
+
+func (s *AuthService) lock(ctx context.Context, subject string, failures int64, ip string) {
+	now := time.Now().UTC()
+	lockout := &domain.Lockout{
+		Subject:  subject,
+		Failures: failures,
+		LockedAt: now,
+		Until:    now.Add(s.policy.LockDuration),
+	}
+	if err := s.attempts.Lock(ctx, lockout); err != nil {
+		s.logger.ErrorContext(ctx, "failed to lock", "subject", subject, "error", err)
+		return
+	}
+	s.logger.WarnContext(ctx, "login lockout applied",
+		"subject", subject,
+		"failures", failures,
+		"client_ip", ip,
+		"until", lockout.Until)
+}
+
+func (s *AuthService) createSession(ctx context.Context, userID string, client ClientInfo) (*LoginResult, error) {
+	token, err := generateToken(sessionTokenBytes)
+	if err != nil {
+		return nil, NewMessageError(ErrCodeInternal, MsgSessionTokenFailed)
+	}
+
+	now := time.Now().UTC()
+	session := &domain.Session{
+		UserID:    userID,
+		ClientIP:  client.IP,
+		UserAgent: client.UserAgent,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.sessionTTL),
+	}
+	if err := s.sessions.Create(ctx, hashToken(token), session); err != nil {
+		return nil, mapRepositoryError(err, "login")
+	}
+
+	return &LoginResult{Token: token, Session: session}, nil
+}
+
+// backoffDelay растёт экспоненциально: base, 2*base, 4*base... но не больше BackoffMax
+func backoffDelay(p LockoutPolicy, failures int64) time.Duration {
+	exp := float64(failures - p.BackoffAfter)
+	delay := time.Duration(float64(p.BackoffBase) * math.Pow(2, exp))
+	if delay <= 0 || delay > p.BackoffMax {
+		return p.BackoffMax
+	}
+	return delay
+}
+
+// loginSubject ключ счётчика неудачных входов. Вход находит пользователя по канонической
+// форме логина, поэтому и счётчик общий для всех написаний, которые ведут к одной учётной записи.
+func loginSubject(login string) string {
+	return "login:" + logins.Normalize(login)
+}
+
+func ipSubject(ip string) string {
+	return "ip:" + ip
+}
+
+func getDummyHash() []byte {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password-for-timing"), cost)
+	})
+	return dummyHash
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/satrunjis/user-service/internal/domain"
+	"github.com/satrunjis/user-service/internal/logins"
+)
+
+// Правила перечитываются из хранилища не реже этого интервала, чтобы изменения
+// с других экземпляров сервиса применялись без перезапуска
+const blocklistRefreshInterval = 30 * time.Second
+
+// loginRules скомпилированный список запретов
+type loginRules struct {
+	reserved map[string]bool
+	patterns []*blockPattern
+}
+
+// blockPattern шаблон в том виде, как его ввели, и с нормализованными буквальными частями.
+// Первый проверяет значение в нижнем регистре, второй - каноническую форму логина.
+type blockPattern struct {
+	raw        *regexp.Regexp
+	normalized *regexp.Regexp
+}
+
+func (p *blockPattern) match(lowered, normalized string) bool {
+	return p.raw.MatchString(lowered) || p.normalized.MatchString(normalized)
+}
+
+// problems проверяет значение поля field и возвращает описания нарушений
+func (r *loginRules) problems(field, value string) []FieldError {
+	if r == nil {
+		return nil
+	}
+	normalized := logins.Normalize(value)
+	lowered := strings.ToLower(value)
+
+	var errs []FieldError
+	if r.reserved[normalized] {
+		errs = append(errs, fieldError(field, ValidationReserved, field+" is reserved"))
+	}
+	for _, p := range r.patterns {
+		if p.match(lowered, normalized) {
+			errs = append(errs, fieldError(field, ValidationBlocked, field+" contains blocked words"))
+			break
+		}
+	}
+	return errs
+}
+
+// blocklistCache кеширует правила между запросами
+type blocklistCache struct {
+	mu       sync.Mutex
+	rules    *loginRules
+	loadedAt time.Time
+}
+
+func (s *UserService) AddLoginBlockRule(ctx context.Context, kind, value string, comment *string) (*domain.LoginBlockRule, error) {
+	const op = "UserService.AddLoginBlockRule"
+
+	value = strings.TrimSpace(value)
+	if value == "" || len(value) > 200 {
+		return nil, NewMessageError(ErrCodeInvalidInput, MsgBlockRuleValue)
+	}
+
+	switch kind {
+	case domain.BlockReserved:
+		value = logins.Normalize(value)
+	case domain.BlockPattern:
+		if _, err := compileBlockPattern(value); err != nil {
+			return nil, NewMessageError(ErrCodeInvalidInput, MsgInvalidPattern, "error", err.Error())
+		}
+	default:
+		return nil, NewMessageError(ErrCodeInvalidInput, MsgBlockRuleKind)
+	}
+
+	rule := &domain.LoginBlockRule{
+		ID:        uuid.New().String(),
+		Kind:      kind,
+		Value:     value,
+		Comment:   comment,
+		CreatedAt: time.Now().UTC(),
+		CreatedBy: domain.PrincipalFrom(ctx).Actor(),
+	}
+	if err := s.blocklist.Create(ctx, rule); err != nil {
+		return nil, mapRepositoryError(err, "create login block rule")
+	}
+	s.invalidateLoginRules()
+
+	s.logger.InfoContext(ctx, "login block rule added", "operation", op, "rule_id", rule.ID, "kind", kind, "actor", rule.CreatedBy)
+	return rule, nil
+}
+
+func (s *UserService) ListLoginBlockRules(ctx context.Context) ([]*domain.LoginBlockRule, error) {
+	rules, err := s.blocklist.List(ctx)
+	if err != nil {
+		return nil, mapRepositoryError(err, "list login block rules")
+	}
+	return rules, nil
+}
+
+func (s *UserService) DeleteLoginBlockRule(ctx context.Context, id string) error {
+	const op = "UserService.DeleteLoginBlockRule"
+
+	if err := s.blocklist.Delete(ctx, id); err != nil {
+		var serviceErr *ServiceError
+		if errors.As(err, &serviceErr) && serviceErr.Code == ErrCodeNotFound {
+			return NewMessageError(ErrCodeNotFound, MsgBlockRuleNotFound)
+		}
+		return mapRepositoryError(err, "delete login block rule")
+	}
+	s.invalidateLoginRules()
+
+	s.logger.InfoContext(ctx, "login block rule deleted", "operation", op, "rule_id", id, "actor", domain.PrincipalFrom(ctx).Actor())
+	return nil
+}
+
+// loginRules возвращает актуальные правила. Если хранилище недоступно,
+// используются последние загруженные правила, а без них запись пользователей отклоняется.
+func (s *UserService) loginRules(ctx context.Context) (*loginRules, error) {
+	c := s.blockCache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.rules != nil && time.Since(c.loadedAt) < blocklistRefreshInterval {
+		return c.rules, nil
+	}
+
+	list, err := s.blocklist.List(ctx)
+	if err != nil {
+		if c.rules != nil {
+			s.logger.WarnContext(ctx, "failed to refresh login blocklist, using cached rules", "error", err)
+			return c.rules, nil
+		}
+		return nil, mapRepositoryError(err, "load login blocklist")
+	}
+
+	rules := &loginRules{reserved: make(map[string]bool)}
+	for _, rule := range list {
+		switch rule.Kind {
+		case domain.BlockReserved:
+			rules.reserved[rule.Value] = true
+		case domain.BlockPattern:
+			p, err := compileBlockPattern(rule.Value)
+			if err != nil {
+				s.logger.WarnContext(ctx, "skipping invalid login block pattern", "rule_id", rule.ID, "error", err)
+				continue
+			}
+			rules.patterns = append(rules.patterns, p)
+		}
+	}
+
+	c.rules = rules
+	c.loadedAt = time.Now()
+	return rules, nil
+}
+
+func (s *UserService) invalidateLoginRules() {
+	s.blockCache.mu.Lock()
+	s.blockCache.loadedAt = time.Time{}
+	s.blockCache.mu.Unlock()
+}
+
+// compileBlockPattern регистр не учитывается. Normalize заменяет часть букв (i и 1 на l, rn на m),
+// поэтому к канонической форме логина применяется шаблон с так же нормализованными буквальными частями.
+func compileBlockPattern(pattern string) (*blockPattern, error) {
+	raw, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return nil, err
+	}
+	folded, err := logins.NormalizePattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	normalized, err := regexp.Compile("(?i)" + folded)
+	if err != nil {
+		return nil, err
+	}
+	return &blockPattern{raw: raw, normalized: normalized}, nil
+}
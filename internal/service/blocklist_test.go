@@ -0,0 +1,28 @@
+package service
+
+import "testing"
+
+func TestBlockPatternMatchesNormalizedLogins(t *testing.T) {
+	rules := &loginRules{reserved: map[string]bool{}}
+	for _, pattern := range []string{"porn", "bitch", `^admin\d*$`, "[iu]diot"} {
+		p, err := compileBlockPattern(pattern)
+		if err != nil {
+			t.Fatalf("compileBlockPattern(%q) error = %v", pattern, err)
+		}
+		rules.patterns = append(rules.patterns, p)
+	}
+
+	blocked := []string{"porn", "PORN_star", "p0rn", "bitch", "b1tch", "Admin42", "аdmin", "adm1n", "idiot", "1diot", "udiot"}
+	for _, login := range blocked {
+		if errs := rules.problems("login", login); len(errs) == 0 {
+			t.Errorf("problems(%q) = none, want blocked", login)
+		}
+	}
+
+	allowed := []string{"popcorn_fan", "john_doe", "administrator"}
+	for _, login := range allowed {
+		if errs := rules.problems("login", login); len(errs) != 0 {
+			t.Errorf("problems(%q) = %v, want none", login, errs)
+		}
+	}
+}
@@ -2,9 +2,19 @@ package service
 
 import (
 	"context"
+
+	"github.com/satrunjis/user-service/internal/domain"
 )
 
 type CacheService interface {
 	Get(ctx context.Context, key *string) (*[]byte, error)
 	Set(ctx context.Context, key *string, value *[]byte) error
 }
+
+// TileCache кеш тайлов карты, построенных по геолокации пользователей
+type TileCache interface {
+	// Stat возвращает существующие ключи из keys с размером и сроком хранения
+	Stat(ctx context.Context, keys []string) ([]domain.CacheEntry, error)
+	// Delete удаляет ключи и возвращает число удалённых
+	Delete(ctx context.Context, keys []string) (int64, error)
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/satrunjis/user-service/internal/domain"
+	"github.com/satrunjis/user-service/internal/social"
+	"github.com/satrunjis/user-service/pkg/cdc"
+)
+
+// CDCService публикует изменения пользователей в поток для внешних потребителей (аналитика).
+// Формат записей и помощник для чтения группой потребителей - в пакете pkg/cdc.
+type CDCService struct {
+	stream  domain.ChangeStream
+	users   domain.UserRepository
+	socials *social.Registry
+	logger  *slog.Logger
+}
+
+func NewCDCService(stream domain.ChangeStream, users domain.UserRepository, socials *social.Registry, logger *slog.Logger) *CDCService {
+	return &CDCService{
+		stream:  stream,
+		users:   users,
+		socials: socials,
+		logger:  logger,
+	}
+}
+
+// Record подписчик шины событий. Поток пропускает уже опубликованные события, поэтому повтор безопасен.
+func (s *CDCService) Record(ctx context.Context, event domain.UserEvent) error {
+	const op = "CDCService.Record"
+	log := s.logger.With("operation", op, "event_id", event.ID, "event_type", event.Type)
+
+	published, err := s.stream.Publish(ctx, event)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to publish change", "error", err)
+		return StorageError(err)
+	}
+	if !published {
+		log.DebugContext(ctx, "change already published")
+	}
+	return nil
+}
+
+// Replay публикует текущее состояние каждого пользователя событием cdc.TypeSnapshot,
+// чтобы потребитель мог восстановить данные без истории изменений. Возвращает число опубликованных.
+func (s *CDCService) Replay(ctx context.Context, batch int) (int, error) {
+	const op = "CDCService.Replay"
+	log := s.logger.With("operation", op)
+	start := time.Now()
+
+	if batch <= 0 {
+		batch = 500
+	}
+
+	published := 0
+	cursor := ""
+	for {
+		users, next, err := s.users.Scan(ctx, cursor, batch)
+		if err != nil {
+			log.ErrorContext(ctx, "failed to read users", "error", err)
+			return published, err
+		}
+
+		for _, user := range users {
+			if _, err := s.stream.Publish(ctx, s.snapshot(ctx, user)); err != nil {
+				log.ErrorContext(ctx, "failed to publish snapshot", "error", err, "user_id", *user.ID)
+				return published, StorageError(err)
+			}
+			published++
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+		log.InfoContext(ctx, "replay in progress", "published", published)
+	}
+
+	log.InfoContext(ctx, "replay finished", "published", published, "duration", time.Since(start))
+	return published, nil
+}
+
+func (s *CDCService) snapshot(ctx context.Context, user *domain.User) domain.UserEvent {
+	hideSecrets(user)
+	hideEncrypted(user, s.users.EncryptedFields())
+	for i := range user.SocialProfiles {
+		user.SocialProfiles[i].URL = s.socials.ProfileURL(user.SocialProfiles[i])
+	}
+	return domain.UserEvent{
+		ID:         uuid.New().String(),
+		Type:       cdc.TypeSnapshot,
+		UserID:     *user.ID,
+		Actor:      domain.PrincipalFrom(ctx).Actor(),
+		OccurredAt: time.Now().UTC(),
+		User:       user,
+	}
+}
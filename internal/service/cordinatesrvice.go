@@ -1,19 +1,70 @@
 package service
 
 import (
+	"log/slog"
+	"time"
+
 	"github.com/satrunjis/user-service/internal/domain"
+	"github.com/satrunjis/user-service/internal/social"
 )
 
 type UserService struct {
-	userRepo   domain.UserRepository
-	mapCache   CacheService
-	mapService MapService
+	userRepo    domain.UserRepository
+	logins      domain.LoginReservationRepository
+	blocklist   domain.LoginBlocklistRepository
+	blockCache  *blocklistCache
+	socials     *social.Registry
+	attributes  domain.AttributeSchemaRepository
+	attrCache   *attributeSchemaCache
+	audit       domain.AuditRepository
+	relay       EventRelay
+	mapCache    CacheService
+	mapService  MapService
+	resetTokens domain.PasswordResetStore
+	sessions    domain.SessionStore
+	notifier    Notifier
+	reset       PasswordResetOptions
+	logger      *slog.Logger
+}
+
+type PasswordResetOptions struct {
+	TokenTTL time.Duration
+	// URL шаблон ссылки для сброса, токен подставляется вместо {token}
+	URL string
 }
 
-func NewUserService(repo domain.UserRepository, cache CacheService, maps MapService) *UserService {
+func NewUserService(
+	repo domain.UserRepository,
+	logins domain.LoginReservationRepository,
+	blocklist domain.LoginBlocklistRepository,
+	socials *social.Registry,
+	attributes domain.AttributeSchemaRepository,
+	audit domain.AuditRepository,
+	relay EventRelay,
+	cache CacheService,
+	maps MapService,
+	resetTokens domain.PasswordResetStore,
+	sessions domain.SessionStore,
+	notifier Notifier,
+	reset PasswordResetOptions,
+	logger *slog.Logger,
+) *UserService {
 	return &UserService{
-		userRepo:   repo,
-		mapCache:   cache,
-		mapService: maps,
+		userRepo:    repo,
+		logins:      logins,
+		blocklist:   blocklist,
+		blockCache:  &blocklistCache{},
+		socials:     socials,
+		attributes:  attributes,
+		attrCache:   &attributeSchemaCache{},
+		audit:       audit,
+		relay:       relay,
+		mapCache:    cache,
+		mapService:  maps,
+		resetTokens: resetTokens,
+		sessions:    sessions,
+		notifier:    notifier,
+		reset:       reset,
+		logger:      logger,
 	}
 }
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/satrunjis/user-service/internal/domain"
+)
+
+const purgeBatchSize = 100
+
+type DeletionOptions struct {
+	// Retention сколько хранится удалённый пользователь, пока его можно восстановить
+	Retention time.Duration
+	// PurgeInterval как часто удаляются пользователи с истёкшим сроком хранения
+	PurgeInterval time.Duration
+}
+
+// getUser возвращает пользователя по ID, помеченные удалёнными считаются отсутствующими
+func (s *UserService) getUser(ctx context.Context, id *string, operation string) (*domain.User, error) {
+	user, err := s.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, mapRepositoryError(err, operation)
+	}
+	if user.CurrentStatus() == domain.StatusDeleted {
+		return nil, NewMessageError(ErrCodeNotFound, MsgUserNotFound)
+	}
+	return user, nil
+}
+
+// checkIfMatch сверяет версию из If-Match с текущей версией пользователя
+func checkIfMatch(ctx context.Context, existing *domain.User) error {
+	if want := domain.IfMatchFrom(ctx); want != "" && want != existing.Revision {
+		return NewMessageError(ErrCodePreconditionFailed, MsgUserVersionMismatch)
+	}
+	return nil
+}
+
+// writeError ошибка записи, проверенной по версии: если клиент передал If-Match,
+// конфликт версий означает, что его условие не выполнено
+func writeError(ctx context.Context, err error, operation string) error {
+	err = mapRepositoryError(err, operation)
+	if HasCode(err, ErrCodeConflict) && domain.IfMatchFrom(ctx) != "" {
+		return WrapMessageError(ErrCodePreconditionFailed, err, MsgUserVersionMismatch)
+	}
+	return err
+}
+
+// DeleteUser помечает пользователя удалённым. Логин остаётся закреплённым за ним
+// до окончательного удаления, чтобы пользователя можно было восстановить.
+func (s *UserService) DeleteUser(ctx context.Context, id *string) error {
+	const op = "UserService.DeleteUser"
+
+	if err := validationID(id); err != nil {
+		return err
+	}
+
+	existing, err := s.getUser(ctx, id, "delete")
+	if err != nil {
+		return err
+	}
+	if err := checkIfMatch(ctx, existing); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	status := domain.StatusDeleted
+	change := &domain.StatusChange{
+		From:  existing.CurrentStatus(),
+		Actor: domain.PrincipalFrom(ctx).Actor(),
+		At:    now,
+	}
+	update := &domain.User{ID: id, Status: &status, StatusChange: change, DeletedAt: &now, Revision: existing.Revision}
+	before := userDoc(existing)
+	events := s.userEvents(ctx, domain.EventUserDeleted, *id, before, patchedDoc(before, update))
+	if err := s.userRepo.UpdatePartial(ctx, update, events...); err != nil {
+		return writeError(ctx, err, "delete")
+	}
+	s.relay.Wake()
+
+	if err := s.sessions.DeleteAllForUser(ctx, *id); err != nil {
+		s.logger.WarnContext(ctx, "failed to revoke sessions", "operation", op, "user_id", *id, "error", err)
+	}
+
+	s.logger.InfoContext(ctx, "user marked as deleted", "operation", op, "user_id", *id, "actor", change.Actor)
+	return nil
+}
+
+// RestoreUser возвращает удалённому пользователю статус, который был до удаления
+func (s *UserService) RestoreUser(ctx context.Context, id *string) (*domain.User, error) {
+	const op = "UserService.RestoreUser"
+
+	if err := validationID(id); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, mapRepositoryError(err, "restore")
+	}
+	if user.CurrentStatus() != domain.StatusDeleted {
+		return nil, NewMessageError(ErrCodeConflict, MsgUserNotDeleted)
+	}
+
+	before := userDoc(user)
+	status := domain.StatusActive
+	if user.StatusChange != nil && user.StatusChange.From != "" && user.StatusChange.From != domain.StatusDeleted {
+		status = user.StatusChange.From
+	}
+	user.ID = id
+	user.Status = &status
+	user.StatusChange = &domain.StatusChange{
+		From:  domain.StatusDeleted,
+		Actor: domain.PrincipalFrom(ctx).Actor(),
+		At:    time.Now().UTC(),
+	}
+	// Поле нельзя очистить частичным обновлением, поэтому документ перезаписывается целиком
+	user.DeletedAt = nil
+	events := s.userEvents(ctx, domain.EventUserRestored, *id, before, userDoc(user))
+	if err := s.userRepo.Replace(ctx, user, events...); err != nil {
+		return nil, mapRepositoryError(err, "restore")
+	}
+	s.relay.Wake()
+
+	s.logger.InfoContext(ctx, "user restored", "operation", op, "user_id", *id, "status", status, "actor", user.StatusChange.Actor)
+
+	hideSecrets(user)
+	s.fillProfileURLs(user)
+	return user, nil
+}
+
+// PurgeDeleted окончательно удаляет пользователей, помеченных удалёнными раньше retention назад
+func (s *UserService) PurgeDeleted(ctx context.Context, retention time.Duration) (int, error) {
+	const op = "UserService.PurgeDeleted"
+	log := s.logger.With("operation", op)
+	before := time.Now().Add(-retention)
+
+	purged := 0
+	for {
+		users, err := s.userRepo.ListDeleted(ctx, before, purgeBatchSize)
+		if err != nil {
+			return purged, mapRepositoryError(err, "purge")
+		}
+
+		batch := 0
+		for _, u := range users {
+			// Пользователя могли восстановить после выборки
+			current, err := s.userRepo.GetByID(ctx, u.ID)
+			if err != nil {
+				if HasCode(err, ErrCodeNotFound) {
+					continue
+				}
+				return purged, mapRepositoryError(err, "purge")
+			}
+			if current.CurrentStatus() != domain.StatusDeleted || current.DeletedAt == nil || current.DeletedAt.After(before) {
+				continue
+			}
+
+			// Данные пользователя в событие не копируются, фиксируется только факт удаления
+			event := s.userEvent(ctx, domain.EventUserPurged, *u.ID, nil, nil)
+			if err := s.userRepo.Delete(ctx, u.ID, event); err != nil && !HasCode(err, ErrCodeNotFound) {
+				return purged, mapRepositoryError(err, "purge")
+			}
+			s.relay.Wake()
+			s.releaseLogin(ctx, *u.ID, current.Login)
+			log.InfoContext(ctx, "deleted user purged", "user_id", *u.ID, "deleted_at", current.DeletedAt)
+			batch++
+		}
+		purged += batch
+
+		if len(users) < purgeBatchSize || batch == 0 {
+			return purged, nil
+		}
+	}
+}
+
+// RunPurge периодически удаляет пользователей с истёкшим сроком хранения, пока не отменён ctx
+func (s *UserService) RunPurge(ctx context.Context, opts DeletionOptions) {
+	const op = "UserService.RunPurge"
+	log := s.logger.With("operation", op)
+
+	if opts.PurgeInterval <= 0 {
+		log.Warn("purge of deleted users is disabled")
+		return
+	}
+
+	ctx = domain.WithPrincipal(ctx, &domain.Principal{Type: domain.PrincipalSystem, ID: "purge"})
+
+	ticker := time.NewTicker(opts.PurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := s.PurgeDeleted(ctx, opts.Retention)
+			if err != nil {
+				log.ErrorContext(ctx, "purge of deleted users failed", "error", err, "purged", purged)
+				continue
+			}
+			if purged > 0 {
+				log.InfoContext(ctx, "purge of deleted users completed", "purged", purged)
+			}
+		}
+	}
+}
+
+// EraseUser окончательно удаляет пользователя по запросу субъекта данных, в том числе не помеченного удалённым.
+// Отсутствующий пользователь считается уже удалённым, поэтому шаг можно повторять.
+func (s *UserService) EraseUser(ctx context.Context, id string) error {
+	const op = "UserService.EraseUser"
+
+	current, err := s.userRepo.GetByID(ctx, &id)
+	if err != nil {
+		if HasCode(err, ErrCodeNotFound) {
+			return nil
+		}
+		return mapRepositoryError(err, "erase")
+	}
+
+	event := s.userEvent(ctx, domain.EventUserErased, id, nil, nil)
+	if err := s.userRepo.Delete(ctx, &id, event); err != nil && !HasCode(err, ErrCodeNotFound) {
+		return mapRepositoryError(err, "erase")
+	}
+	s.relay.Wake()
+	s.releaseLogin(ctx, id, current.Login)
+
+	s.logger.InfoContext(ctx, "user erased", "operation", op, "user_id", id, "actor", domain.PrincipalFrom(ctx).Actor())
+	return nil
+}
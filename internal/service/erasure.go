@@ -0,0 +1,371 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/satrunjis/user-service/internal/domain"
+)
+
+const (
+	// Аренда задачи удаления, чтобы её выполнял один экземпляр сервиса
+	erasureLeasePrefix = "erasure:"
+	erasureBatchSize   = 20
+	erasedPrefix       = "erased-"
+)
+
+// ReceiptSigner подписывает квитанции об удалении
+type ReceiptSigner interface {
+	Algorithm() string
+	KeyID() string
+	PublicKey() string
+	Sign(data []byte) string
+}
+
+// errStepNotReady шаг пока нельзя выполнить, задача продолжится при следующей проверке
+var errStepNotReady = errors.New("erasure step is not ready")
+
+// UserEraser окончательно удаляет документ пользователя
+type UserEraser interface {
+	EraseUser(ctx context.Context, id string) error
+}
+
+type ErasureOptions struct {
+	// PollInterval как часто проверяются незавершённые задачи
+	PollInterval time.Duration
+	// LeaseTTL если экземпляр не продлил аренду задачи за это время, её выполняет другой
+	LeaseTTL time.Duration
+}
+
+// RequestErasure ставит задачу удаления всех данных пользователя. Задача выполняется
+// в фоне, ход выполнения и квитанция доступны через GetErasure.
+func (s *PrivacyService) RequestErasure(ctx context.Context, id *string) (*domain.ErasureJob, error) {
+	const op = "PrivacyService.RequestErasure"
+	if s.signer == nil {
+		return nil, erasureUnavailable()
+	}
+	if err := validationID(id); err != nil {
+		return nil, err
+	}
+	log := s.logger.With("operation", op, "user_id", *id)
+
+	active, err := s.jobs.ActiveForUser(ctx, *id)
+	if err == nil {
+		return nil, NewMessageError(ErrCodeConflict, MsgErasureInProgress, "job_id", active.ID)
+	}
+	if !HasCode(err, ErrCodeNotFound) {
+		log.ErrorContext(ctx, "failed to look up erasure jobs", "error", err)
+		return nil, StorageError(err)
+	}
+
+	if _, err := s.users.GetByID(ctx, id); err != nil {
+		return nil, mapRepositoryError(err, "erasure")
+	}
+
+	now := time.Now().UTC()
+	job := &domain.ErasureJob{
+		ID:          uuid.New().String(),
+		UserID:      *id,
+		Pseudonym:   erasedPrefix + uuid.New().String(),
+		Status:      domain.ErasurePending,
+		Steps:       make([]domain.ErasureStep, len(domain.ErasureSteps)),
+		RequestedBy: domain.PrincipalFrom(ctx).Actor(),
+		RequestID:   domain.RequestIDFrom(ctx),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	for i, name := range domain.ErasureSteps {
+		job.Steps[i] = domain.ErasureStep{Name: name, Status: domain.ErasurePending}
+	}
+	if err := s.jobs.Create(ctx, job); err != nil {
+		log.ErrorContext(ctx, "failed to create erasure job", "error", err)
+		return nil, StorageError(err)
+	}
+	s.wakeErasures()
+
+	log.InfoContext(ctx, "user erasure requested", "job_id", job.ID, "actor", job.RequestedBy)
+	return job, nil
+}
+
+func (s *PrivacyService) GetErasure(ctx context.Context, jobID string) (*domain.ErasureJob, error) {
+	job, err := s.jobs.GetByID(ctx, jobID)
+	if err != nil {
+		if HasCode(err, ErrCodeNotFound) {
+			return nil, NewMessageError(ErrCodeNotFound, MsgErasureNotFound)
+		}
+		return nil, StorageError(err)
+	}
+	return job, nil
+}
+
+// ResumeErasure возобновляет задачу, остановленную ошибкой. Выполненные шаги не повторяются.
+func (s *PrivacyService) ResumeErasure(ctx context.Context, jobID string) (*domain.ErasureJob, error) {
+	const op = "PrivacyService.ResumeErasure"
+	if s.signer == nil {
+		return nil, erasureUnavailable()
+	}
+
+	job, err := s.GetErasure(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != domain.ErasureFailed {
+		return nil, NewMessageError(ErrCodeConflict, MsgErasureNotResumable)
+	}
+
+	for i := range job.Steps {
+		if job.Steps[i].Status != domain.ErasureCompleted {
+			job.Steps[i].Status = domain.ErasurePending
+		}
+	}
+	job.Status = domain.ErasurePending
+	job.UpdatedAt = time.Now().UTC()
+	if err := s.jobs.Save(ctx, job); err != nil {
+		s.logger.ErrorContext(ctx, "failed to save erasure job", "operation", op, "job_id", jobID, "error", err)
+		return nil, StorageError(err)
+	}
+	s.wakeErasures()
+
+	s.logger.InfoContext(ctx, "user erasure resumed", "operation", op, "job_id", jobID, "actor", domain.PrincipalFrom(ctx).Actor())
+	return job, nil
+}
+
+func (s *PrivacyService) wakeErasures() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// RunErasures выполняет задачи удаления, пока не отменён ctx. Задачу, брошенную
+// остановленным экземпляром, подхватывает другой после истечения аренды.
+func (s *PrivacyService) RunErasures(ctx context.Context) {
+	const op = "PrivacyService.RunErasures"
+	log := s.logger.With("operation", op)
+
+	if s.signer == nil || s.erasure.PollInterval <= 0 {
+		log.Warn("user erasure is disabled")
+		return
+	}
+
+	ctx = domain.WithPrincipal(ctx, &domain.Principal{Type: domain.PrincipalSystem, ID: "erasure"})
+
+	ticker := time.NewTicker(s.erasure.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		jobs, err := s.jobs.Runnable(ctx, erasureBatchSize)
+		if err != nil {
+			log.ErrorContext(ctx, "failed to list erasure jobs", "error", err)
+		}
+		for _, job := range jobs {
+			if ctx.Err() != nil {
+				return
+			}
+			s.runErasure(ctx, job)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+func (s *PrivacyService) runErasure(ctx context.Context, job *domain.ErasureJob) {
+	const op = "PrivacyService.runErasure"
+	log := s.logger.With("operation", op, "job_id", job.ID, "user_id", job.UserID)
+
+	lease := erasureLeasePrefix + job.ID
+	if acquired, err := s.leases.Acquire(ctx, lease, s.owner, s.erasure.LeaseTTL); err != nil || !acquired {
+		if err != nil {
+			log.WarnContext(ctx, "failed to acquire erasure lease", "error", err)
+		}
+		return
+	}
+	defer func() {
+		if err := s.leases.Release(context.WithoutCancel(ctx), lease, s.owner); err != nil {
+			log.Warn("failed to release erasure lease", "error", err)
+		}
+	}()
+
+	// Пока задача ждала аренды, её мог завершить другой экземпляр
+	current, err := s.jobs.GetByID(ctx, job.ID)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to reload erasure job", "error", err)
+		return
+	}
+	job = current
+	if job.Status != domain.ErasurePending && job.Status != domain.ErasureRunning {
+		return
+	}
+
+	job.Status = domain.ErasureRunning
+	for i := range job.Steps {
+		step := &job.Steps[i]
+		if step.Status == domain.ErasureCompleted {
+			continue
+		}
+		if acquired, err := s.leases.Acquire(ctx, lease, s.owner, s.erasure.LeaseTTL); err != nil || !acquired {
+			log.WarnContext(ctx, "erasure lease lost", "step", step.Name, "error", err)
+			return
+		}
+
+		step.Status = domain.ErasureRunning
+		step.Attempts++
+		step.Error = ""
+		job.UpdatedAt = time.Now().UTC()
+		if err := s.jobs.Save(ctx, job); err != nil {
+			log.ErrorContext(ctx, "failed to save erasure job", "error", err)
+			return
+		}
+
+		count, err := s.eraseStep(ctx, job, step.Name)
+		now := time.Now().UTC()
+		job.UpdatedAt = now
+		if errors.Is(err, errStepNotReady) {
+			log.InfoContext(ctx, "erasure step postponed", "step", step.Name, "reason", err)
+			step.Status = domain.ErasurePending
+			step.Attempts--
+			if err := s.jobs.Save(context.WithoutCancel(ctx), job); err != nil {
+				log.ErrorContext(ctx, "failed to save erasure job", "error", err)
+			}
+			return
+		}
+		if err != nil {
+			log.ErrorContext(ctx, "erasure step failed", "step", step.Name, "attempt", step.Attempts, "error", err)
+			step.Status = domain.ErasureFailed
+			step.Error = err.Error()
+			job.Status = domain.ErasureFailed
+			if err := s.jobs.Save(context.WithoutCancel(ctx), job); err != nil {
+				log.ErrorContext(ctx, "failed to save erasure job", "error", err)
+			}
+			return
+		}
+		step.Status = domain.ErasureCompleted
+		step.Count = count
+		step.CompletedAt = &now
+		log.InfoContext(ctx, "erasure step completed", "step", step.Name, "count", count)
+	}
+
+	receipt, err := s.erasureReceipt(job)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to sign erasure receipt", "error", err)
+		return
+	}
+	job.Receipt = receipt
+	job.Status = domain.ErasureCompleted
+	job.CompletedAt = &receipt.CompletedAt
+	job.UpdatedAt = receipt.CompletedAt
+	if err := s.jobs.Save(context.WithoutCancel(ctx), job); err != nil {
+		log.ErrorContext(ctx, "failed to save erasure job", "error", err)
+		return
+	}
+	log.InfoContext(ctx, "user erasure completed", "requested_by", job.RequestedBy, "duration", receipt.CompletedAt.Sub(job.CreatedAt))
+}
+
+// eraseStep выполняет шаг удаления и возвращает число затронутых записей. Каждый шаг можно повторять.
+func (s *PrivacyService) eraseStep(ctx context.Context, job *domain.ErasureJob, step string) (int64, error) {
+	switch step {
+	case domain.ErasureStepSessions:
+		sessions, err := s.sessions.ListForUser(ctx, job.UserID)
+		if err != nil {
+			return 0, err
+		}
+		if err := s.sessions.DeleteAllForUser(ctx, job.UserID); err != nil {
+			return 0, err
+		}
+		return int64(len(sessions)), nil
+
+	case domain.ErasureStepMapTiles:
+		var current *domain.Location
+		user, err := s.users.GetByID(ctx, &job.UserID)
+		switch {
+		case err == nil:
+			current = user.Location
+		case !HasCode(err, ErrCodeNotFound):
+			return 0, err
+		}
+		entries, err := s.audit.Replay(ctx, job.UserID, time.Time{}, 0)
+		if err != nil {
+			return 0, err
+		}
+		locations := userLocations(current, entries)
+		keys := make([]string, 0, len(locations)*(maxTileZoom+1))
+		for _, loc := range locations {
+			for zoom := 0; zoom <= maxTileZoom; zoom++ {
+				keys = append(keys, tileCacheKey(loc, zoom))
+			}
+		}
+		return s.tiles.Delete(ctx, keys)
+
+	case domain.ErasureStepWebhookDeliveries:
+		return s.deliveries.DeleteByUser(ctx, job.UserID)
+
+	case domain.ErasureStepProfile:
+		if err := s.eraser.EraseUser(ctx, job.UserID); err != nil {
+			return 0, err
+		}
+		return 1, nil
+
+	case domain.ErasureStepOutbox:
+		// Событие, ещё не прошедшее через relay, попадёт в потоки после их очистки
+		pending, err := s.outbox.PendingForUser(ctx, job.UserID)
+		if err != nil {
+			return 0, err
+		}
+		if pending > 0 {
+			return 0, fmt.Errorf("%w: %d events of the user are not published yet", errStepNotReady, pending)
+		}
+		return s.outbox.DeleteByUser(ctx, job.UserID)
+
+	case domain.ErasureStepEventStream:
+		return s.events.DeleteByUser(ctx, job.UserID)
+
+	case domain.ErasureStepChangeStream:
+		return s.changes.DeleteByUser(ctx, job.UserID)
+
+	case domain.ErasureStepAudit:
+		count, err := s.audit.Pseudonymize(ctx, job.UserID, job.Pseudonym)
+		return int64(count), err
+	}
+	return 0, fmt.Errorf("unknown erasure step %q", step)
+}
+
+// erasureReceipt подписывает JSON квитанции без поля signature
+func (s *PrivacyService) erasureReceipt(job *domain.ErasureJob) (*domain.ErasureReceipt, error) {
+	receipt := &domain.ErasureReceipt{
+		JobID:       job.ID,
+		UserID:      job.UserID,
+		RequestedBy: job.RequestedBy,
+		RequestedAt: job.CreatedAt,
+		CompletedAt: time.Now().UTC(),
+		Steps:       make([]domain.ErasureReceiptStep, len(job.Steps)),
+		Algorithm:   s.signer.Algorithm(),
+		KeyID:       s.signer.KeyID(),
+		PublicKey:   s.signer.PublicKey(),
+	}
+	for i, step := range job.Steps {
+		receipt.Steps[i] = domain.ErasureReceiptStep{Name: step.Name, Count: step.Count}
+		if step.CompletedAt != nil {
+			receipt.Steps[i].CompletedAt = *step.CompletedAt
+		}
+	}
+
+	payload, err := json.Marshal(receipt)
+	if err != nil {
+		return nil, err
+	}
+	receipt.Signature = s.signer.Sign(payload)
+	return receipt, nil
+}
+
+func erasureUnavailable() *ServiceError {
+	return NewMessageError(ErrCodeInternal, MsgErasureUnavailable)
+}
@@ -1,8 +1,12 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"net"
 	"strings"
+	"time"
 )
 
 type ErrorCode string
@@ -12,17 +16,47 @@ const (
 	ErrCodeInvalidInput  ErrorCode = "INVALID_INPUT"
 	ErrCodeAlreadyExists ErrorCode = "ALREADY_EXISTS"
 	ErrCodeInternal      ErrorCode = "INTERNAL_ERROR"
+	ErrCodeUnauthorized  ErrorCode = "UNAUTHORIZED"
+	ErrCodeRateLimited   ErrorCode = "RATE_LIMITED"
+	ErrCodeForbidden     ErrorCode = "FORBIDDEN"
+	// ErrCodeUnavailable хранилище или внешний сервис недоступны
+	ErrCodeUnavailable ErrorCode = "UNAVAILABLE"
+	// ErrCodeTimeout хранилище или внешний сервис не ответили вовремя
+	ErrCodeTimeout ErrorCode = "TIMEOUT"
+	// ErrCodeConflict документ изменён параллельным запросом
+	ErrCodeConflict ErrorCode = "CONFLICT"
+	// ErrCodePreconditionFailed не выполнено условие запроса (например, If-Match)
+	ErrCodePreconditionFailed ErrorCode = "PRECONDITION_FAILED"
 )
 
 type ServiceError struct {
 	Code    ErrorCode
 	Message string
+	// Key ключ сообщения в каталоге переводов, Params значения для подстановки в перевод
+	Key    string
+	Params map[string]any
+	// Details ошибки отдельных полей запроса
+	Details []FieldError
+	// RetryAfter подсказка клиенту, через сколько повторить запрос
+	RetryAfter time.Duration
+	// Cause исходная ошибка, клиенту не показывается
+	Cause error
 }
 
 func (e *ServiceError) Error() string {
+	if e.Cause != nil {
+		if e.Message == "" {
+			return e.Cause.Error()
+		}
+		return e.Message + ": " + e.Cause.Error()
+	}
 	return e.Message
 }
 
+func (e *ServiceError) Unwrap() error {
+	return e.Cause
+}
+
 func NewServiceError(code ErrorCode, messages ...string) *ServiceError {
 	message := strings.Join(messages, " ")
 	return &ServiceError{
@@ -31,21 +65,72 @@ func NewServiceError(code ErrorCode, messages ...string) *ServiceError {
 	}
 }
 
-func mapRepositoryError(err error, operation string) error {
-	_ = operation
+// WrapError создаёт ошибку сервиса, сохраняя исходную ошибку для errors.Is/As и логов
+func WrapError(code ErrorCode, cause error, messages ...string) *ServiceError {
+	err := NewServiceError(code, messages...)
+	err.Cause = cause
+	return err
+}
+
+// StorageError классифицирует ошибку обращения к хранилищу или внешнему сервису:
+// истёкший дедлайн и сетевой таймаут дают TIMEOUT, прочие сетевые ошибки - UNAVAILABLE
+func StorageError(err error) *ServiceError {
 	var serviceErr *ServiceError
 	if errors.As(err, &serviceErr) {
-		switch serviceErr.Code {
-		case ErrCodeNotFound:
-			return NewServiceError(ErrCodeNotFound, "User not found")
-		case ErrCodeAlreadyExists:
-			return NewServiceError(ErrCodeAlreadyExists, "User already exists")
-		case ErrCodeInternal:
-			return NewServiceError(ErrCodeInternal, "Database error occurred")
-		default:
-			return NewServiceError(ErrCodeInternal, "Unexpected error occurred")
+		return serviceErr
+	}
+
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return WrapError(ErrCodeTimeout, err)
+	case errors.As(err, &netErr):
+		if netErr.Timeout() {
+			return WrapError(ErrCodeTimeout, err)
+		}
+		return WrapError(ErrCodeUnavailable, err)
+	}
+	return WrapError(ErrCodeInternal, err)
+}
+
+// HasCode проверяет код ошибки сервиса в цепочке err
+func HasCode(err error, code ErrorCode) bool {
+	var serviceErr *ServiceError
+	return errors.As(err, &serviceErr) && serviceErr.Code == code
+}
+
+func mapRepositoryError(err error, operation string) error {
+	cause := fmt.Errorf("%s: %w", operation, err)
+
+	var serviceErr *ServiceError
+	if !errors.As(err, &serviceErr) {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return WrapMessageError(ErrCodeTimeout, cause, MsgRequestTimeout)
 		}
+		return WrapMessageError(ErrCodeInternal, cause, MsgSystemError)
 	}
 
-	return NewServiceError(ErrCodeInternal, "System error occurred")
+	switch serviceErr.Code {
+	case ErrCodeNotFound:
+		return WrapMessageError(ErrCodeNotFound, cause, MsgUserNotFound)
+	case ErrCodeAlreadyExists:
+		return WrapMessageError(ErrCodeAlreadyExists, cause, MsgUserExists)
+	case ErrCodeConflict:
+		return WrapMessageError(ErrCodeConflict, cause, MsgUserModified)
+	case ErrCodePreconditionFailed:
+		return WrapMessageError(ErrCodePreconditionFailed, cause, MsgUserVersionMismatch)
+	case ErrCodeUnavailable:
+		err := WrapMessageError(ErrCodeUnavailable, cause, MsgStorageUnavailable)
+		err.RetryAfter = serviceErr.RetryAfter
+		return err
+	case ErrCodeTimeout:
+		return WrapMessageError(ErrCodeTimeout, cause, MsgStorageTimeout)
+	case ErrCodeRateLimited, ErrCodeForbidden, ErrCodeUnauthorized, ErrCodeInvalidInput:
+		// Эти ошибки адресованы клиенту и передаются как есть
+		return serviceErr
+	case ErrCodeInternal:
+		return WrapMessageError(ErrCodeInternal, cause, MsgDatabaseError)
+	default:
+		return WrapMessageError(ErrCodeInternal, cause, MsgUnexpectedError)
+	}
 }
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/satrunjis/user-service/internal/domain"
+)
+
+// EventRelay публикует события, сохранённые в outbox вместе с изменением пользователя
+type EventRelay interface {
+	// Wake сообщает о новой записи, чтобы событие не ждало следующего опроса outbox
+	Wake()
+}
+
+// userEvents сравнивает состояния до и после записи и возвращает событие eventType для outbox.
+// Если изменилась геолокация, добавляется EventLocationChanged. Замена и частичное обновление
+// без изменений событий не порождают.
+func (s *UserService) userEvents(ctx context.Context, eventType, userID string, before, after map[string]any) []domain.UserEvent {
+	changes := docDiff(before, after, s.secretFields())
+	if len(changes) == 0 && (eventType == domain.EventUserReplaced || eventType == domain.EventUserPatched) {
+		return nil
+	}
+	event := s.userEvent(ctx, eventType, userID, changes, after)
+	events := []domain.UserEvent{event}
+
+	var locationChanges []domain.FieldChange
+	for _, change := range changes {
+		if change.Field == "location" || strings.HasPrefix(change.Field, "location.") {
+			locationChanges = append(locationChanges, change)
+		}
+	}
+	if len(locationChanges) == 0 || eventType == domain.EventUserDeleted {
+		return events
+	}
+
+	located := event
+	located.ID = uuid.New().String()
+	located.Type = domain.EventLocationChanged
+	located.Changes = locationChanges
+	if previous := userFromDoc(before); previous != nil {
+		hideEncrypted(previous, s.userRepo.EncryptedFields())
+		located.PreviousLocation = previous.Location
+	}
+	return append(events, located)
+}
+
+// userEvent событие с готовым списком изменений. ID события служит ключом дедупликации у подписчиков.
+func (s *UserService) userEvent(ctx context.Context, eventType, userID string, changes []domain.FieldChange, after map[string]any) domain.UserEvent {
+	event := domain.UserEvent{
+		ID:         uuid.New().String(),
+		Type:       eventType,
+		UserID:     userID,
+		Actor:      domain.PrincipalFrom(ctx).Actor(),
+		RequestID:  domain.RequestIDFrom(ctx),
+		OccurredAt: time.Now().UTC(),
+		Changes:    changes,
+	}
+	if user := userFromDoc(after); user != nil {
+		user.ID = &userID
+		hideSecrets(user)
+		hideEncrypted(user, s.userRepo.EncryptedFields())
+		s.fillProfileURLs(user)
+		event.User = user
+	}
+	return event
+}
+
+// hideEncrypted убирает поля, которые хранятся зашифрованными: событие попадает в outbox,
+// вебхуки и потоки, где открытое значение хранилось бы без шифрования
+func hideEncrypted(user *domain.User, fields []domain.EncryptedField) {
+	for _, f := range fields {
+		switch f.Field {
+		case "comment":
+			user.Comment = nil
+		case "location":
+			user.Location = nil
+		}
+	}
+}
+
+// userFromDoc обратное к userDoc, для пустого документа nil
+func userFromDoc(doc map[string]any) *domain.User {
+	if len(doc) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil
+	}
+	var user domain.User
+	if err := json.Unmarshal(data, &user); err != nil {
+		return nil
+	}
+	return &user
+}
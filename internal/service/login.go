@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"time"
+
+	"github.com/satrunjis/user-service/internal/domain"
+	"github.com/satrunjis/user-service/internal/logins"
+)
+
+// CheckLoginAvailability проверяет логин так же, как при создании пользователя, но ничего не резервирует
+func (s *UserService) CheckLoginAvailability(ctx context.Context, login string) (*domain.LoginAvailability, error) {
+	result := &domain.LoginAvailability{Login: login}
+
+	if problems := loginProblems(login); len(problems) != 0 {
+		result.Reason = "invalid"
+		result.Message = NewValidationError(problems...).Message
+		return result, nil
+	}
+
+	rules, err := s.loginRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if problems := rules.problems("login", login); len(problems) != 0 {
+		result.Reason = "reserved"
+		result.Message = NewValidationError(problems...).Message
+		return result, nil
+	}
+
+	_, err = s.logins.Get(ctx, logins.Normalize(login))
+	if err == nil {
+		result.Reason = "taken"
+		result.Message = MsgLoginTaken.Text
+		return result, nil
+	}
+
+	var serviceErr *ServiceError
+	if errors.As(err, &serviceErr) && serviceErr.Code == ErrCodeNotFound {
+		result.Available = true
+		return result, nil
+	}
+	return nil, mapRepositoryError(err, "check login")
+}
+
+// reserveLogin закрепляет логин за пользователем до записи документа пользователя
+func (s *UserService) reserveLogin(ctx context.Context, userID, login string) error {
+	err := s.logins.Reserve(ctx, &domain.LoginReservation{
+		Normalized: logins.Normalize(login),
+		Login:      login,
+		UserID:     userID,
+		ReservedAt: time.Now().UTC(),
+	})
+	if err == nil {
+		return nil
+	}
+
+	var serviceErr *ServiceError
+	if errors.As(err, &serviceErr) && serviceErr.Code == ErrCodeAlreadyExists {
+		err := NewMessageError(ErrCodeAlreadyExists, MsgLoginTaken)
+		err.Details = []FieldError{fieldError("login", ValidationTaken, MsgLoginTaken.Text)}
+		return err
+	}
+	return mapRepositoryError(err, "reserve login")
+}
+
+// releaseLogin снимает резерв. Ошибка только логируется: висящий резерв не ломает данные,
+// а лишь не даёт другим занять логин.
+func (s *UserService) releaseLogin(ctx context.Context, userID string, login *string) {
+	if login == nil {
+		return
+	}
+	if err := s.logins.Release(ctx, logins.Normalize(*login), userID); err != nil {
+		s.logger.WarnContext(ctx, "failed to release login reservation", "error", err, "user_id", userID)
+	}
+}
+
+// rollbackLogin снимает резерв после неудачной записи, если сохранённый пользователь не использует этот логин
+func (s *UserService) rollbackLogin(ctx context.Context, userID string, login *string) {
+	if login == nil {
+		return
+	}
+	stored, err := s.userRepo.GetByID(ctx, &userID)
+	if err == nil && sameLogin(stored.Login, login) {
+		return
+	}
+	s.releaseLogin(ctx, userID, login)
+}
+
+func sameLogin(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return logins.Normalize(*a) == logins.Normalize(*b)
+}
+
+func loginProblems(login string) []FieldError {
+	var errs []FieldError
+	if len(login) < 5 || len(login) > 20 {
+		errs = append(errs, fieldError("login", ValidationLength, "login must be 5-20 characters", "min", 5, "max", 20))
+	}
+	if !regexp.MustCompile(validCharactersPattern).MatchString(login) {
+		errs = append(errs, fieldError("login", ValidationCharacters, "login "+msgInvalidCharacters))
+	}
+	return errs
+}
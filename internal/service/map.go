@@ -3,7 +3,8 @@ package service
 import (
 	"context"
 	"fmt"
-	"log"
+
+	"github.com/satrunjis/user-service/internal/domain"
 )
 
 type MapService interface {
@@ -11,16 +12,16 @@ type MapService interface {
 }
 
 func (s *UserService) GetMapTile(ctx context.Context, userID *string, zoom int) (*[]byte, error) {
-	user, err := s.userRepo.GetByID(ctx, userID)
+	user, err := s.getUser(ctx, userID, "getmaptile")
 	if err != nil {
-		return nil, mapRepositoryError(err, "getmaptile")
+		return nil, err
 	}
 
 	if user.Location == nil {
-		return nil, NewServiceError(ErrCodeInvalidInput, "User location is required")
+		return nil, NewMessageError(ErrCodeInvalidInput, MsgUserLocationRequired)
 	}
 
-	cacheKey := fmt.Sprintf("tile_%f_%f_%d", user.Location.Lat, user.Location.Lon, zoom)
+	cacheKey := tileCacheKey(*user.Location, zoom)
 
 	if tile, err := s.mapCache.Get(ctx, &cacheKey); err == nil {
 		return tile, nil
@@ -28,12 +29,24 @@ func (s *UserService) GetMapTile(ctx context.Context, userID *string, zoom int)
 
 	tileData, err := s.mapService.GetMapTile(ctx, user.Location.Lat, user.Location.Lon, zoom)
 	if err != nil {
-		return nil, NewServiceError(ErrCodeInternal, "Failed to get map tile from map service")
+		switch cause := StorageError(err); cause.Code {
+		case ErrCodeTimeout:
+			return nil, WrapMessageError(ErrCodeTimeout, err, MsgMapTimeout)
+		case ErrCodeUnavailable:
+			return nil, WrapMessageError(ErrCodeUnavailable, err, MsgMapUnavailable)
+		default:
+			return nil, WrapMessageError(ErrCodeInternal, err, MsgMapFailed)
+		}
 	}
 
 	if err := s.mapCache.Set(ctx, &cacheKey, tileData); err != nil {
-		log.Printf("Cache set failed: %v", err)
+		s.logger.WarnContext(ctx, "cache set failed", "error", err, "key", cacheKey)
 	}
 
 	return tileData, nil
 }
+
+// tileCacheKey ключ тайла в кеше, по нему же находятся тайлы пользователя при экспорте данных
+func tileCacheKey(loc domain.Location, zoom int) string {
+	return fmt.Sprintf("tile_%f_%f_%d", loc.Lat, loc.Lon, zoom)
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Message сообщение об ошибке для клиента. Переводы ищутся по Key, поэтому правка
+// английского текста их не теряет. {имя} в тексте заменяется параметром ошибки.
+type Message struct {
+	Key  string
+	Text string
+}
+
+var (
+	MsgUserNotFound              = Message{"user_not_found", "User not found"}
+	MsgUserExists                = Message{"user_exists", "User already exists"}
+	MsgUserModified              = Message{"user_modified", "User was modified by another request"}
+	MsgUserVersionMismatch       = Message{"user_version_mismatch", "User version does not match"}
+	MsgUserIDRequired            = Message{"user_id_required", "User ID is required"}
+	MsgUserLocationRequired      = Message{"user_location_required", "User location is required"}
+	MsgDatabaseError             = Message{"database_error", "Database error occurred"}
+	MsgSystemError               = Message{"system_error", "System error occurred"}
+	MsgUnexpectedError           = Message{"unexpected_error", "Unexpected error occurred"}
+	MsgStorageUnavailable        = Message{"storage_unavailable", "Storage is temporarily unavailable"}
+	MsgStorageTimeout            = Message{"storage_timeout", "Storage did not respond in time"}
+	MsgRequestTimeout            = Message{"request_timeout", "Request timed out"}
+	MsgMapUnavailable            = Message{"map_unavailable", "Map service is unavailable"}
+	MsgMapTimeout                = Message{"map_timeout", "Map service did not respond in time"}
+	MsgMapFailed                 = Message{"map_failed", "Failed to get map tile from map service"}
+	MsgMapEmpty                  = Message{"map_empty", "Empty map data"}
+	MsgRateLimited               = Message{"rate_limited", "Rate limit exceeded"}
+	MsgLoginTaken                = Message{"login_taken", "login is already taken"}
+	MsgProtectedFields           = Message{"protected_fields", "Cannot update protected fields (registration date)"}
+	MsgPasswordEndpoint          = Message{"password_endpoint", "password cannot be changed here, use the password change or reset endpoints"}
+	MsgTwoFactorEndpoint         = Message{"two_factor_endpoint", "two-factor settings cannot be changed here, use the two-factor endpoints"}
+	MsgStatusEndpoint            = Message{"status_endpoint", "status cannot be changed here, use the status endpoints"}
+	MsgInvalidCredentials        = Message{"invalid_credentials", "Invalid login or password"}
+	MsgCredentialsRequired       = Message{"credentials_required", "Login and password are required"}
+	MsgLoginRequired             = Message{"login_required", "Login is required"}
+	MsgUnlockSubjectRequired     = Message{"unlock_subject_required", "Login or IP address is required"}
+	MsgLoginBackoff              = Message{"login_backoff", "Too many failed login attempts, retry later"}
+	MsgLoginLocked               = Message{"login_locked", "Too many failed login attempts, access is temporarily locked"}
+	MsgAuthenticationRequired    = Message{"authentication_required", "Authentication required"}
+	MsgSessionTokenRequired      = Message{"session_token_required", "Session token is required"}
+	MsgSessionInvalid            = Message{"session_invalid", "Session is invalid or expired"}
+	MsgOldPasswordIncorrect      = Message{"old_password_incorrect", "Old password is incorrect"}
+	MsgPasswordUnchanged         = Message{"password_unchanged", "New password must differ from the old one"}
+	MsgResetTokenRequired        = Message{"reset_token_required", "Reset token is required"}
+	MsgResetTokenInvalid         = Message{"reset_token_invalid", "Reset token is invalid or expired"}
+	MsgPasswordHashFailed        = Message{"password_hash_failed", "Failed to hash password"}
+	MsgResetTokenFailed          = Message{"reset_token_failed", "Failed to generate reset token"}
+	MsgTwoFactorEnabled          = Message{"two_factor_enabled", "Two-factor authentication is already enabled"}
+	MsgTwoFactorDisabled         = Message{"two_factor_disabled", "Two-factor authentication is not enabled"}
+	MsgTwoFactorUnavailable      = Message{"two_factor_unavailable", "Two-factor authentication is not configured"}
+	MsgTwoFactorNotStarted       = Message{"two_factor_not_started", "Two-factor enrollment has not been started"}
+	MsgTwoFactorInputRequired    = Message{"two_factor_input_required", "Two-factor token and code are required"}
+	MsgTwoFactorChallengeInvalid = Message{"two_factor_challenge_invalid", "Two-factor challenge is invalid or expired"}
+	MsgTwoFactorCodeInvalid      = Message{"two_factor_code_invalid", "Invalid two-factor code"}
+	MsgTOTPSecretFailed          = Message{"totp_secret_failed", "Failed to generate TOTP secret"}
+	MsgTOTPEncryptFailed         = Message{"totp_encrypt_failed", "Failed to encrypt TOTP secret"}
+	MsgTOTPDecryptFailed         = Message{"totp_decrypt_failed", "Failed to decrypt TOTP secret"}
+	MsgRecoveryCodesFailed       = Message{"recovery_codes_failed", "Failed to generate recovery codes"}
+	MsgSessionTokenFailed        = Message{"session_token_failed", "Failed to generate session token"}
+	MsgTwoFactorTokenFailed      = Message{"two_factor_token_failed", "Failed to generate two-factor token"}
+	MsgAPIKeyInvalid             = Message{"api_key_invalid", "Invalid API key"}
+	MsgAPIKeyRevoked             = Message{"api_key_revoked", "API key has been revoked"}
+	MsgAPIKeyExpired             = Message{"api_key_expired", "API key has expired"}
+	MsgAPIKeyNotFound            = Message{"api_key_not_found", "API key not found"}
+	MsgAPIKeyName                = Message{"api_key_name", "API key name must be 1-100 characters"}
+	MsgAPIKeyExpiration          = Message{"api_key_expiration", "API key expiration must be in the future"}
+	MsgScopesRequired            = Message{"scopes_required", "At least one scope is required"}
+	MsgAPIKeyFailed              = Message{"api_key_failed", "Failed to generate API key"}
+	MsgVersionNotFound           = Message{"version_not_found", "Version not found"}
+	MsgUserNotExisted            = Message{"user_not_existed", "User did not exist at the requested time"}
+	MsgHistoryIncomplete         = Message{"history_incomplete", "User history is incomplete, the state cannot be reconstructed"}
+	MsgUserNotDeleted            = Message{"user_not_deleted", "User is not deleted"}
+	MsgAccountSuspended          = Message{"account_suspended", "Account is suspended"}
+	MsgAccountBanned             = Message{"account_banned", "Account is banned"}
+	MsgBlockRuleNotFound         = Message{"block_rule_not_found", "Login block rule not found"}
+	MsgAttributeMappingConflict  = Message{"attribute_mapping_conflict", "Attribute type conflicts with the existing index mapping"}
+	MsgBlockRuleKind             = Message{"block_rule_kind", "kind must be one of: reserved, pattern"}
+	MsgBlockRuleValue            = Message{"block_rule_value", "value must be 1-200 characters"}
+	MsgWebhookNotFound           = Message{"webhook_not_found", "Webhook not found"}
+	MsgWebhookExists             = Message{"webhook_exists", "Webhook already exists"}
+	MsgWebhookInactive           = Message{"webhook_inactive", "Webhook is inactive"}
+	MsgWebhookSecretFailed       = Message{"webhook_secret_failed", "Failed to generate webhook secret"}
+	MsgDeliveryNotFound          = Message{"delivery_not_found", "Webhook delivery not found"}
+	MsgDeliveryExists            = Message{"delivery_exists", "Webhook delivery already exists"}
+	MsgDeliveryInProgress        = Message{"delivery_in_progress", "Delivery is still in progress"}
+	MsgErasureNotFound           = Message{"erasure_not_found", "Erasure job not found"}
+	MsgErasureNotResumable       = Message{"erasure_not_resumable", "Only failed erasure jobs can be resumed"}
+	MsgErasureUnavailable        = Message{"erasure_unavailable", "User erasure is not configured"}
+	MsgEventStreamClosing        = Message{"event_stream_closing", "Event stream is shutting down"}
+	MsgInvalidScanCursor         = Message{"invalid_scan_cursor", "Invalid scan cursor"}
+	MsgUnknownScope              = Message{"unknown_scope", "unknown scope: {scope}"}
+	MsgMissingScope              = Message{"missing_scope", "missing required scope: {scope}"}
+	MsgInvalidPattern            = Message{"invalid_pattern", "invalid pattern: {error}"}
+	MsgStatusUnchanged           = Message{"status_unchanged", "user already has status {status}"}
+	MsgStatusTransition          = Message{"status_transition", "status transition is not allowed: {from} -> {to}"}
+	MsgErasureInProgress         = Message{"erasure_in_progress", "Erasure job {job_id} is already in progress for this user"}
+	MsgAttributeTypeChanged      = Message{"attribute_type_changed", "attribute type cannot be changed: {name} {from} -> {to}"}
+)
+
+// NewMessageError создаёт ошибку с сообщением из каталога; params - пары имя, значение, как в fieldError
+func NewMessageError(code ErrorCode, msg Message, params ...any) *ServiceError {
+	err := &ServiceError{Code: code, Key: msg.Key}
+	replacements := make([]string, 0, len(params))
+	for i := 0; i+1 < len(params); i += 2 {
+		name := params[i].(string)
+		if err.Params == nil {
+			err.Params = make(map[string]any, len(params)/2)
+		}
+		err.Params[name] = params[i+1]
+		replacements = append(replacements, "{"+name+"}", fmt.Sprint(params[i+1]))
+	}
+	err.Message = strings.NewReplacer(replacements...).Replace(msg.Text)
+	return err
+}
+
+// WrapMessageError то же, что NewMessageError, с сохранением исходной ошибки
+func WrapMessageError(code ErrorCode, cause error, msg Message, params ...any) *ServiceError {
+	err := NewMessageError(code, msg, params...)
+	err.Cause = cause
+	return err
+}
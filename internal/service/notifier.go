@@ -0,0 +1,11 @@
+package service
+
+import (
+	"context"
+
+	"github.com/satrunjis/user-service/internal/domain"
+)
+
+type Notifier interface {
+	SendPasswordReset(ctx context.Context, msg *domain.PasswordResetNotification) error
+}
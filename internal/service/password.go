@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/satrunjis/user-service/internal/domain"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	resetTokenBytes  = 32
+	resetURLTokenTag = "{token}"
+)
+
+func (s *UserService) ChangePassword(ctx context.Context, id *string, oldPassword, newPassword string) error {
+	if err := validationID(id); err != nil {
+		return err
+	}
+
+	user, err := s.getUser(ctx, id, "change password")
+	if err != nil {
+		return err
+	}
+
+	if user.Password == nil || bcrypt.CompareHashAndPassword([]byte(*user.Password), []byte(oldPassword)) != nil {
+		return NewMessageError(ErrCodeInvalidInput, MsgOldPasswordIncorrect)
+	}
+
+	if oldPassword == newPassword {
+		return NewMessageError(ErrCodeInvalidInput, MsgPasswordUnchanged)
+	}
+
+	return s.setPassword(ctx, *id, newPassword, "change password")
+}
+
+func (s *UserService) RequestPasswordReset(ctx context.Context, login string) error {
+	const op = "UserService.RequestPasswordReset"
+	log := s.logger.With("operation", op)
+
+	login = strings.TrimSpace(login)
+	if login == "" {
+		return NewMessageError(ErrCodeInvalidInput, MsgLoginRequired)
+	}
+
+	user, err := s.userRepo.GetByLogin(ctx, &login)
+	if err != nil {
+		var serviceErr *ServiceError
+		if errors.As(err, &serviceErr) && serviceErr.Code == ErrCodeNotFound {
+			// Не раскрываем, существует ли пользователь с таким логином
+			log.InfoContext(ctx, "password reset requested for unknown login")
+			return nil
+		}
+		return mapRepositoryError(err, "password reset")
+	}
+	if user.CurrentStatus() == domain.StatusDeleted {
+		log.InfoContext(ctx, "password reset requested for deleted user")
+		return nil
+	}
+
+	token, err := generateToken(resetTokenBytes)
+	if err != nil {
+		return NewMessageError(ErrCodeInternal, MsgResetTokenFailed)
+	}
+
+	if err := s.resetTokens.Save(ctx, hashToken(token), *user.ID, s.reset.TokenTTL); err != nil {
+		return mapRepositoryError(err, "password reset")
+	}
+
+	msg := &domain.PasswordResetNotification{
+		UserID:    *user.ID,
+		Email:     user.Email,
+		Token:     token,
+		ExpiresAt: time.Now().UTC().Add(s.reset.TokenTTL),
+	}
+	if user.Login != nil {
+		msg.Login = *user.Login
+	}
+	if s.reset.URL != "" {
+		resetURL := strings.ReplaceAll(s.reset.URL, resetURLTokenTag, token)
+		msg.ResetURL = &resetURL
+	}
+
+	// Ответ не зависит от того, удалось ли отправить уведомление: иначе по ошибке
+	// можно было бы отличить существующую учётную запись от неизвестного логина
+	if err := s.notifier.SendPasswordReset(ctx, msg); err != nil {
+		log.ErrorContext(ctx, "failed to send password reset notification", "error", err, "user_id", *user.ID)
+		return nil
+	}
+
+	log.InfoContext(ctx, "password reset token issued", "user_id", *user.ID)
+	return nil
+}
+
+func (s *UserService) ConfirmPasswordReset(ctx context.Context, token, newPassword string) error {
+	if token == "" {
+		return NewMessageError(ErrCodeInvalidInput, MsgResetTokenRequired)
+	}
+
+	// Проверяем пароль до использования токена, чтобы опечатка не сжигала токен
+	if errs := passwordProblems(newPassword); len(errs) != 0 {
+		return NewValidationError(errs...)
+	}
+
+	userID, err := s.resetTokens.Consume(ctx, hashToken(token))
+	if err != nil {
+		var serviceErr *ServiceError
+		if errors.As(err, &serviceErr) && serviceErr.Code == ErrCodeNotFound {
+			return NewMessageError(ErrCodeInvalidInput, MsgResetTokenInvalid)
+		}
+		return mapRepositoryError(err, "confirm password reset")
+	}
+
+	return s.setPassword(ctx, userID, newPassword, "confirm password reset")
+}
+
+func (s *UserService) setPassword(ctx context.Context, userID, pwd, operation string) error {
+	if errs := passwordProblems(pwd); len(errs) != 0 {
+		return NewValidationError(errs...)
+	}
+
+	hashedPwd, err := hashPassword(pwd)
+	if err != nil {
+		return err
+	}
+
+	event := s.userEvent(ctx, domain.EventUserPasswordChanged, userID, []domain.FieldChange{
+		{Field: "password", Before: domain.AuditRedacted, After: domain.AuditRedacted},
+	}, nil)
+	if err := s.userRepo.UpdatePartial(ctx, &domain.User{ID: &userID, Password: &hashedPwd}, event); err != nil {
+		return mapRepositoryError(err, operation)
+	}
+	s.relay.Wake()
+
+	s.logger.InfoContext(ctx, "password updated", "operation", operation, "user_id", userID)
+
+	// После смены пароля все активные сессии становятся недействительными
+	if err := s.sessions.DeleteAllForUser(ctx, userID); err != nil {
+		s.logger.ErrorContext(ctx, "failed to revoke sessions", "error", err, "user_id", userID)
+	}
+	return nil
+}
+
+func generateToken(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/satrunjis/user-service/internal/domain"
+	"github.com/satrunjis/user-service/internal/social"
+)
+
+const (
+	// Тайлы кешируются для уровней масштаба OpenStreetMap 0-19
+	maxTileZoom = 19
+	// exportDeliveriesLimit доставок вебхуков в выгрузке, больше Elasticsearch не отдаёт одним запросом
+	exportDeliveriesLimit = 10000
+)
+
+// PrivacyService обработка запросов субъектов персональных данных
+type PrivacyService struct {
+	users      domain.UserRepository
+	audit      domain.AuditRepository
+	sessions   domain.SessionStore
+	tiles      TileCache
+	deliveries domain.WebhookDeliveryRepository
+	outbox     domain.OutboxRepository
+	events     domain.EventStream
+	changes    domain.ChangeStream
+	socials    *social.Registry
+	jobs       domain.ErasureJobRepository
+	eraser     UserEraser
+	leases     domain.LeaseStore
+	signer     ReceiptSigner
+	erasure    ErasureOptions
+	owner      string
+	wake       chan struct{}
+	logger     *slog.Logger
+}
+
+func NewPrivacyService(
+	users domain.UserRepository,
+	audit domain.AuditRepository,
+	sessions domain.SessionStore,
+	tiles TileCache,
+	deliveries domain.WebhookDeliveryRepository,
+	outbox domain.OutboxRepository,
+	events domain.EventStream,
+	changes domain.ChangeStream,
+	socials *social.Registry,
+	jobs domain.ErasureJobRepository,
+	eraser UserEraser,
+	leases domain.LeaseStore,
+	signer ReceiptSigner,
+	erasure ErasureOptions,
+	logger *slog.Logger,
+) *PrivacyService {
+	return &PrivacyService{
+		users:      users,
+		audit:      audit,
+		sessions:   sessions,
+		tiles:      tiles,
+		deliveries: deliveries,
+		outbox:     outbox,
+		events:     events,
+		changes:    changes,
+		socials:    socials,
+		jobs:       jobs,
+		eraser:     eraser,
+		leases:     leases,
+		signer:     signer,
+		erasure:    erasure,
+		owner:      uuid.New().String(),
+		wake:       make(chan struct{}, 1),
+		logger:     logger,
+	}
+}
+
+// ExportUser собирает всё, что сервис хранит о пользователе: профиль, журнал изменений,
+// сессии, закешированные тайлы по всем его геолокациям и доставки вебхуков с его событиями.
+// Помеченные удалёнными пользователи тоже выгружаются, их данные ещё хранятся.
+func (s *PrivacyService) ExportUser(ctx context.Context, id *string) (*domain.UserExport, error) {
+	const op = "PrivacyService.ExportUser"
+	if err := validationID(id); err != nil {
+		return nil, err
+	}
+	log := s.logger.With("operation", op, "user_id", *id)
+
+	user, err := s.users.GetByID(ctx, id)
+	if err != nil {
+		return nil, mapRepositoryError(err, "export")
+	}
+
+	export := &domain.UserExport{
+		UserID:      *id,
+		GeneratedAt: time.Now().UTC(),
+		RequestedBy: domain.PrincipalFrom(ctx).Actor(),
+		RequestID:   domain.RequestIDFrom(ctx),
+	}
+	if user.Password != nil {
+		export.Notes = append(export.Notes, "password is stored only as a bcrypt hash, the hash is not exported")
+	}
+	if user.TwoFactor != nil && user.TwoFactor.Enabled {
+		export.Notes = append(export.Notes, "two-factor authentication secret and recovery codes are stored encrypted and are not exported")
+	}
+
+	export.Audit, err = s.audit.Replay(ctx, *id, time.Time{}, 0)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to read audit history", "error", err)
+		return nil, mapRepositoryError(err, "export")
+	}
+	if export.Audit == nil {
+		export.Audit = []*domain.AuditEntry{}
+	}
+
+	export.Sessions, err = s.sessions.ListForUser(ctx, *id)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to list sessions", "error", err)
+		return nil, StorageError(err)
+	}
+
+	export.MapTiles, err = s.cachedTiles(ctx, userLocations(user.Location, export.Audit))
+	if err != nil {
+		log.ErrorContext(ctx, "failed to inspect tile cache", "error", err)
+		return nil, StorageError(err)
+	}
+
+	export.WebhookDeliveries, err = s.deliveries.ListByUser(ctx, *id, exportDeliveriesLimit)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to list webhook deliveries", "error", err)
+		return nil, StorageError(err)
+	}
+	if len(export.WebhookDeliveries) == exportDeliveriesLimit {
+		export.Notes = append(export.Notes, "only the latest 10000 webhook deliveries are included")
+	}
+
+	hideSecrets(user)
+	for i := range user.SocialProfiles {
+		user.SocialProfiles[i].URL = s.socials.ProfileURL(user.SocialProfiles[i])
+	}
+	export.Profile = user
+
+	log.InfoContext(ctx, "user data exported",
+		"actor", export.RequestedBy,
+		"audit_entries", len(export.Audit),
+		"sessions", len(export.Sessions),
+		"map_tiles", len(export.MapTiles),
+		"webhook_deliveries", len(export.WebhookDeliveries))
+	return export, nil
+}
+
+// cachedTiles находит в кеше тайлы всех масштабов для каждой геолокации
+func (s *PrivacyService) cachedTiles(ctx context.Context, locations []domain.Location) ([]*domain.CachedTile, error) {
+	type tileKey struct {
+		location domain.Location
+		zoom     int
+	}
+	keys := make([]string, 0, len(locations)*(maxTileZoom+1))
+	byKey := make(map[string]tileKey, cap(keys))
+	for _, loc := range locations {
+		for zoom := 0; zoom <= maxTileZoom; zoom++ {
+			key := tileCacheKey(loc, zoom)
+			keys = append(keys, key)
+			byKey[key] = tileKey{location: loc, zoom: zoom}
+		}
+	}
+
+	entries, err := s.tiles.Stat(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	tiles := make([]*domain.CachedTile, len(entries))
+	for i, entry := range entries {
+		k := byKey[entry.Key]
+		tiles[i] = &domain.CachedTile{CacheEntry: entry, Location: k.location, Zoom: k.zoom}
+	}
+	return tiles, nil
+}
+
+// userLocations текущая и все прежние геолокации пользователя по журналу изменений без повторов
+func userLocations(current *domain.Location, entries []*domain.AuditEntry) []domain.Location {
+	var locations []domain.Location
+	add := func(loc domain.Location) {
+		for _, l := range locations {
+			if l == loc {
+				return
+			}
+		}
+		locations = append(locations, loc)
+	}
+
+	if current != nil {
+		add(*current)
+	}
+	var lat, lon *float64
+	for _, entry := range entries {
+		for _, change := range entry.Changes {
+			value, ok := change.After.(float64)
+			switch change.Field {
+			case "location.lat":
+				lat = nil
+				if ok {
+					lat = &value
+				}
+			case "location.lon":
+				lon = nil
+				if ok {
+					lon = &value
+				}
+			}
+		}
+		if lat != nil && lon != nil {
+			add(domain.Location{Lat: *lat, Lon: *lon})
+		}
+	}
+	return locations
+}
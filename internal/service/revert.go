@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/satrunjis/user-service/internal/domain"
+)
+
+// GetUserAsOf восстанавливает пользователя на момент asOf по журналу изменений
+func (s *UserService) GetUserAsOf(ctx context.Context, id *string, asOf time.Time) (*domain.User, error) {
+	if err := validationID(id); err != nil {
+		return nil, err
+	}
+
+	entries, err := s.audit.Replay(ctx, *id, asOf, 0)
+	if err != nil {
+		return nil, mapRepositoryError(err, "get as of")
+	}
+	user, err := reconstructUser(entries)
+	if err != nil {
+		return nil, err
+	}
+
+	user.ID = id
+	hideSecrets(user)
+	s.fillProfileURLs(user)
+	return user, nil
+}
+
+// RevertUser возвращает пользователя к версии из журнала. Запись идёт через Replace,
+// поэтому старые данные проверяются по текущим правилам. Пароль, 2FA, статус и зашифрованные
+// поля не откатываются: значений зашифрованных полей в журнале нет.
+func (s *UserService) RevertUser(ctx context.Context, id *string, version int64) (*domain.User, error) {
+	const op = "UserService.RevertUser"
+
+	if err := validationID(id); err != nil {
+		return nil, err
+	}
+	if version <= 0 {
+		return nil, NewValidationError(fieldError("version", ValidationRequired, "version must be a positive number"))
+	}
+
+	entries, err := s.audit.Replay(ctx, *id, time.Time{}, version)
+	if err != nil {
+		return nil, mapRepositoryError(err, "revert")
+	}
+	if len(entries) == 0 || entries[len(entries)-1].Version != version {
+		return nil, NewMessageError(ErrCodeNotFound, MsgVersionNotFound)
+	}
+	user, err := reconstructUser(entries)
+	if err != nil {
+		return nil, err
+	}
+
+	if encrypted := s.userRepo.EncryptedFields(); len(encrypted) > 0 {
+		current, err := s.userRepo.GetByID(ctx, id)
+		if err != nil {
+			return nil, mapRepositoryError(err, "revert")
+		}
+		doc, currentDoc := userDoc(user), userDoc(current)
+		for _, f := range encrypted {
+			if value, ok := currentDoc[f.Field]; ok {
+				doc[f.Field] = value
+			} else {
+				delete(doc, f.Field)
+			}
+		}
+		user = userFromDoc(doc)
+	}
+
+	user.ID = id
+	user.Password = nil
+	user.TwoFactor = nil
+	user.Status = nil
+	if err := s.Replace(ctx, user); err != nil {
+		return nil, err
+	}
+
+	s.logger.InfoContext(ctx, "user reverted", "operation", op, "user_id", *id, "version", version, "actor", domain.PrincipalFrom(ctx).Actor())
+
+	reverted, err := s.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return reverted, nil
+}
+
+// reconstructUser последовательно применяет изменения из журнала, начиная с создания
+func reconstructUser(entries []*domain.AuditEntry) (*domain.User, error) {
+	if len(entries) == 0 {
+		return nil, NewMessageError(ErrCodeNotFound, MsgUserNotExisted)
+	}
+	if entries[0].Operation != domain.AuditCreate {
+		// Пользователь создан до появления журнала
+		return nil, NewMessageError(ErrCodeConflict, MsgHistoryIncomplete)
+	}
+	if entries[len(entries)-1].Operation == domain.AuditPurge {
+		return nil, NewMessageError(ErrCodeNotFound, MsgUserNotExisted)
+	}
+
+	flat := map[string]any{}
+	for _, entry := range entries {
+		for _, change := range entry.Changes {
+			if change.After == nil {
+				delete(flat, change.Field)
+				continue
+			}
+			flat[change.Field] = change.After
+		}
+	}
+	for _, field := range auditSecretFields {
+		delete(flat, field)
+	}
+	// Зашифрованные поля записаны в журнал без значений
+	for field, value := range flat {
+		if value == domain.AuditRedacted {
+			delete(flat, field)
+		}
+	}
+
+	data, err := json.Marshal(unflattenDoc(flat))
+	if err != nil {
+		return nil, WrapError(ErrCodeInternal, err, "Failed to reconstruct user")
+	}
+	var user domain.User
+	if err := json.Unmarshal(data, &user); err != nil {
+		return nil, WrapError(ErrCodeInternal, err, "Failed to reconstruct user")
+	}
+	return &user, nil
+}
+
+// unflattenDoc собирает вложенные объекты из путей через точку, обратное к flattenDoc
+func unflattenDoc(flat map[string]any) map[string]any {
+	doc := map[string]any{}
+	for path, value := range flat {
+		parts := strings.Split(path, ".")
+		node := doc
+		for _, part := range parts[:len(parts)-1] {
+			child, ok := node[part].(map[string]any)
+			if !ok {
+				child = map[string]any{}
+				node[part] = child
+			}
+			node = child
+		}
+		node[parts[len(parts)-1]] = value
+	}
+	return doc
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/satrunjis/user-service/internal/domain"
+	"github.com/satrunjis/user-service/internal/social"
+)
+
+const maxSocialProfiles = 20
+
+func (s *UserService) SocialNetworks() []domain.SocialNetwork {
+	return s.socials.Networks()
+}
+
+// normalizeSocialProfiles переносит устаревшее social_net в список профилей и приводит ники к единому виду
+func normalizeSocialProfiles(user *domain.User) {
+	for i := range user.SocialProfiles {
+		p := &user.SocialProfiles[i]
+		p.Network = social.NormalizeID(p.Network)
+		p.Handle = social.NormalizeHandle(p.Handle)
+		// Ссылка вычисляется при чтении и не хранится
+		p.URL = ""
+	}
+
+	if user.SocialNet == nil {
+		return
+	}
+	network := social.NormalizeID(*user.SocialNet)
+	user.SocialNet = nil
+	for _, p := range user.SocialProfiles {
+		if p.Network == network {
+			return
+		}
+	}
+	user.SocialProfiles = append(user.SocialProfiles, domain.SocialProfile{Network: network})
+}
+
+func socialProblems(profiles []domain.SocialProfile, socials *social.Registry) []FieldError {
+	var errs []FieldError
+	if len(profiles) > maxSocialProfiles {
+		errs = append(errs, fieldError("social_profiles", ValidationTooMany,
+			fmt.Sprintf("no more than %d social profiles allowed", maxSocialProfiles), "max", maxSocialProfiles))
+	}
+
+	seen := make(map[domain.SocialProfile]bool, len(profiles))
+	for i, p := range profiles {
+		field := fmt.Sprintf("social_profiles[%d]", i)
+		if !socials.Has(p.Network) {
+			errs = append(errs, fieldError(field+".network", ValidationUnsupported, "invalid social network specified"))
+			continue
+		}
+		if p.Handle != "" && !socials.ValidHandle(p.Network, p.Handle) {
+			errs = append(errs, fieldError(field+".handle", ValidationFormat, "handle is not valid for "+p.Network))
+		}
+		if seen[p] {
+			errs = append(errs, fieldError(field, ValidationDuplicate, "social profile is listed twice"))
+		}
+		seen[p] = true
+	}
+	return errs
+}
+
+func (s *UserService) fillProfileURLs(user *domain.User) {
+	for i := range user.SocialProfiles {
+		user.SocialProfiles[i].URL = s.socials.ProfileURL(user.SocialProfiles[i])
+	}
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/satrunjis/user-service/internal/domain"
+)
+
+const maxStatusReasonLength = 500
+
+// ChangeStatus переводит пользователя в статус target, если переход разрешён.
+// Для блокировок причина обязательна, сессии пользователя при этом завершаются.
+func (s *UserService) ChangeStatus(ctx context.Context, id *string, target, reason string) (*domain.User, error) {
+	const op = "UserService.ChangeStatus"
+
+	if err := validationID(id); err != nil {
+		return nil, err
+	}
+
+	reason = strings.TrimSpace(reason)
+	if reason == "" && (target == domain.StatusSuspended || target == domain.StatusBanned) {
+		return nil, NewValidationError(fieldError("reason", ValidationRequired, "reason is required"))
+	}
+	if utf8.RuneCountInString(reason) > maxStatusReasonLength {
+		return nil, NewValidationError(fieldError("reason", ValidationMaxLength, "reason exceeds 500 character limit", "max", maxStatusReasonLength))
+	}
+
+	// Удалённого пользователя возвращают через восстановление
+	user, err := s.getUser(ctx, id, "change status")
+	if err != nil {
+		return nil, err
+	}
+
+	from := user.CurrentStatus()
+	if from == target {
+		return nil, NewMessageError(ErrCodeConflict, MsgStatusUnchanged, "status", target)
+	}
+	if !domain.CanTransition(from, target) {
+		return nil, NewMessageError(ErrCodeConflict, MsgStatusTransition, "from", from, "to", target)
+	}
+
+	change := &domain.StatusChange{
+		From:   from,
+		Reason: reason,
+		Actor:  domain.PrincipalFrom(ctx).Actor(),
+		At:     time.Now().UTC(),
+	}
+	update := &domain.User{ID: id, Status: &target, StatusChange: change}
+	before := userDoc(user)
+	events := s.userEvents(ctx, domain.EventUserStatusChanged, *id, before, patchedDoc(before, update))
+	if err := s.userRepo.UpdatePartial(ctx, update, events...); err != nil {
+		return nil, mapRepositoryError(err, "change status")
+	}
+	s.relay.Wake()
+
+	if target != domain.StatusActive && target != domain.StatusPending {
+		if err := s.sessions.DeleteAllForUser(ctx, *id); err != nil {
+			s.logger.WarnContext(ctx, "failed to revoke sessions", "operation", op, "user_id", *id, "error", err)
+		}
+	}
+
+	s.logger.InfoContext(ctx, "user status changed", "operation", op, "user_id", *id, "from", from, "to", target, "actor", change.Actor)
+
+	user.ID = id
+	user.Status = &target
+	user.StatusChange = change
+	hideSecrets(user)
+	s.fillProfileURLs(user)
+	return user, nil
+}
+
+// initialStatus при создании разрешены только pending и active, по умолчанию active
+func initialStatus(user *domain.User) error {
+	if user.Status == nil {
+		status := domain.StatusActive
+		user.Status = &status
+		return nil
+	}
+	if *user.Status != domain.StatusPending && *user.Status != domain.StatusActive {
+		return NewValidationError(fieldError("status", ValidationUnsupported, "new user status must be pending or active", "allowed", []string{domain.StatusPending, domain.StatusActive}))
+	}
+	return nil
+}
+
+// normalizeStatusFilter приводит список статусов к нижнему регистру и проверяет значения
+func normalizeStatusFilter(statuses []string) ([]string, error) {
+	var result []string
+	for _, value := range statuses {
+		for _, status := range strings.Split(value, ",") {
+			status = strings.ToLower(strings.TrimSpace(status))
+			if status == "" {
+				continue
+			}
+			if !slices.Contains(domain.Statuses, status) {
+				return nil, NewValidationError(fieldError("status", ValidationUnsupported, "unknown status "+status, "allowed", domain.Statuses))
+			}
+			result = append(result, status)
+		}
+	}
+	return result, nil
+}
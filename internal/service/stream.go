@@ -0,0 +1,316 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/satrunjis/user-service/internal/domain"
+)
+
+const (
+	streamReadBatch = 100
+	// streamRetryDelay пауза после ошибки чтения журнала
+	streamRetryDelay = time.Second
+)
+
+type EventStreamOptions struct {
+	// Buffer очередь подписчика. Переполнивший её подписчик отключается и переподключается с Last-Event-ID.
+	Buffer int
+	// Block сколько ждать новых событий за одно чтение журнала
+	Block time.Duration
+	// Heartbeat период комментариев, которые не дают прокси закрыть простаивающее соединение
+	Heartbeat time.Duration
+}
+
+// EventStreamService раздаёт события пользователей подписчикам потока (SSE).
+// События попадают в общий журнал в Redis из шины экземпляра, который публикует outbox,
+// а каждый экземпляр читает журнал одним запросом и раздаёт события своим подписчикам.
+// Журнал ограничен, поэтому возобновить чтение можно только в пределах последних событий.
+type EventStreamService struct {
+	stream domain.EventStream
+	opts   EventStreamOptions
+	mu     sync.Mutex
+	subs   map[*streamSubscriber]struct{}
+	closed bool
+	logger *slog.Logger
+}
+
+type streamSubscriber struct {
+	filter domain.EventFilter
+	live   chan domain.StreamedEvent
+}
+
+func NewEventStreamService(stream domain.EventStream, opts EventStreamOptions, logger *slog.Logger) *EventStreamService {
+	if opts.Buffer <= 0 {
+		opts.Buffer = 256
+	}
+	if opts.Block <= 0 {
+		opts.Block = 5 * time.Second
+	}
+	if opts.Heartbeat <= 0 {
+		opts.Heartbeat = 15 * time.Second
+	}
+	return &EventStreamService{
+		stream: stream,
+		opts:   opts,
+		subs:   make(map[*streamSubscriber]struct{}),
+		logger: logger,
+	}
+}
+
+func (s *EventStreamService) Heartbeat() time.Duration {
+	return s.opts.Heartbeat
+}
+
+// Record подписчик шины событий, добавляет событие в журнал
+func (s *EventStreamService) Record(ctx context.Context, event domain.UserEvent) error {
+	const op = "EventStreamService.Record"
+	log := s.logger.With("operation", op, "event_id", event.ID, "event_type", event.Type)
+
+	added, err := s.stream.Append(ctx, event)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to append event to stream", "error", err)
+		return StorageError(err)
+	}
+	if !added {
+		log.DebugContext(ctx, "event already in stream")
+	}
+	return nil
+}
+
+// Run читает журнал и раздаёт новые события подписчикам, пока не отменён ctx.
+// После остановки потоки подписчиков закрываются.
+func (s *EventStreamService) Run(ctx context.Context) {
+	const op = "EventStreamService.Run"
+	log := s.logger.With("operation", op)
+	defer s.close()
+
+	var last string
+	for ctx.Err() == nil {
+		if last == "" {
+			id, err := s.stream.LastID(ctx)
+			if err != nil {
+				log.ErrorContext(ctx, "failed to read event stream position", "error", err)
+				sleepCtx(ctx, streamRetryDelay)
+				continue
+			}
+			last = id
+		}
+
+		events, err := s.stream.Read(ctx, last, streamReadBatch, s.opts.Block)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.ErrorContext(ctx, "failed to read event stream", "error", err)
+				sleepCtx(ctx, streamRetryDelay)
+			}
+			continue
+		}
+		for _, event := range events {
+			s.broadcast(event)
+			last = event.ID
+		}
+	}
+}
+
+// Subscribe возвращает события, подходящие под filter. Если задан lastEventID, сначала
+// отдаются события журнала после него. Канал закрывается при отмене ctx, остановке сервиса
+// или если подписчик не успевает читать события.
+func (s *EventStreamService) Subscribe(ctx context.Context, lastEventID string, filter domain.EventFilter) (<-chan domain.StreamedEvent, error) {
+	if lastEventID != "" {
+		if _, _, ok := parseStreamID(lastEventID); !ok {
+			return nil, NewValidationError(fieldError("Last-Event-ID", ValidationFormat, "Last-Event-ID must be an event id from this stream"))
+		}
+	}
+
+	// Подписчик регистрируется до чтения журнала, чтобы не пропустить события между чтением и подпиской
+	sub := &streamSubscriber{filter: filter, live: make(chan domain.StreamedEvent, s.opts.Buffer)}
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil, NewMessageError(ErrCodeUnavailable, MsgEventStreamClosing)
+	}
+	s.subs[sub] = struct{}{}
+	s.mu.Unlock()
+
+	var backlog []domain.StreamedEvent
+	last := lastEventID
+	for last != "" {
+		events, err := s.stream.Range(ctx, last, streamReadBatch)
+		if err != nil {
+			s.unsubscribe(sub)
+			return nil, StorageError(err)
+		}
+		for _, event := range events {
+			if filter.Matches(event.Event) {
+				backlog = append(backlog, event)
+			}
+			last = event.ID
+		}
+		if len(events) < streamReadBatch {
+			break
+		}
+	}
+
+	out := make(chan domain.StreamedEvent)
+	go func() {
+		defer close(out)
+		defer s.unsubscribe(sub)
+
+		send := func(event domain.StreamedEvent) bool {
+			select {
+			case out <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		for _, event := range backlog {
+			if !send(event) {
+				return
+			}
+		}
+		for {
+			select {
+			case event, ok := <-sub.live:
+				if !ok {
+					return
+				}
+				// Событие могло попасть и в журнал, и в очередь подписчика
+				if last != "" && !streamIDAfter(event.ID, last) {
+					continue
+				}
+				if !send(event) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (s *EventStreamService) broadcast(event domain.StreamedEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for sub := range s.subs {
+		if !sub.filter.Matches(event.Event) {
+			continue
+		}
+		select {
+		case sub.live <- event:
+		default:
+			// Отстающий подписчик отключается, клиент переподключится и дочитает журнал
+			delete(s.subs, sub)
+			close(sub.live)
+			s.logger.Warn("event stream subscriber is too slow, disconnecting", "event_id", event.ID)
+		}
+	}
+}
+
+func (s *EventStreamService) unsubscribe(sub *streamSubscriber) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.subs[sub]; ok {
+		delete(s.subs, sub)
+		close(sub.live)
+	}
+}
+
+func (s *EventStreamService) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for sub := range s.subs {
+		delete(s.subs, sub)
+		close(sub.live)
+	}
+}
+
+// NewEventFilter проверяет условия отбора: ID пользователей, типы событий
+// и область "minLon,minLat,maxLon,maxLat"
+func NewEventFilter(userIDs, types []string, bbox string) (domain.EventFilter, error) {
+	filter := domain.EventFilter{UserIDs: userIDs}
+	var errs []FieldError
+
+	for _, t := range types {
+		if !slices.Contains(domain.EventTypes, t) {
+			errs = append(errs, fieldError("type", ValidationUnsupported, "unknown event type "+t, "allowed", domain.EventTypes))
+			continue
+		}
+		filter.Types = append(filter.Types, t)
+	}
+
+	if bbox != "" {
+		box, err := parseBoundingBox(bbox)
+		if err != nil {
+			errs = append(errs, *err)
+		} else {
+			filter.BBox = box
+		}
+	}
+
+	if len(errs) > 0 {
+		return domain.EventFilter{}, NewValidationError(errs...)
+	}
+	return filter, nil
+}
+
+func parseBoundingBox(value string) (*domain.BoundingBox, *FieldError) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 4 {
+		fe := fieldError("bbox", ValidationFormat, "bbox must be minLon,minLat,maxLon,maxLat")
+		return nil, &fe
+	}
+	coords := make([]float64, 4)
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			fe := fieldError("bbox", ValidationFormat, "bbox must be minLon,minLat,maxLon,maxLat")
+			return nil, &fe
+		}
+		coords[i] = v
+	}
+	box := &domain.BoundingBox{MinLon: coords[0], MinLat: coords[1], MaxLon: coords[2], MaxLat: coords[3]}
+	if box.MinLon < -180 || box.MaxLon > 180 || box.MinLat < -90 || box.MaxLat > 90 ||
+		box.MinLon > box.MaxLon || box.MinLat > box.MaxLat {
+		fe := fieldError("bbox", ValidationRange, "bbox must have min before max, longitude within -180..180 and latitude within -90..90")
+		return nil, &fe
+	}
+	return box, nil
+}
+
+// parseStreamID разбирает ID записи журнала вида "<миллисекунды>-<номер>"
+func parseStreamID(id string) (ms, seq uint64, ok bool) {
+	msPart, seqPart, found := strings.Cut(id, "-")
+	if !found {
+		return 0, 0, false
+	}
+	ms, err := strconv.ParseUint(msPart, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	seq, err = strconv.ParseUint(seqPart, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return ms, seq, true
+}
+
+func streamIDAfter(id, other string) bool {
+	ms, seq, _ := parseStreamID(id)
+	otherMs, otherSeq, _ := parseStreamID(other)
+	return ms > otherMs || (ms == otherMs && seq > otherSeq)
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
@@ -0,0 +1,337 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/satrunjis/user-service/internal/domain"
+	"github.com/satrunjis/user-service/internal/totp"
+)
+
+const (
+	recoveryCodeCount = 10
+	recoveryCodeBytes = 5
+)
+
+type SecretCipher interface {
+	Encrypt(plaintext string) (string, error)
+	Decrypt(ciphertext string) (string, error)
+}
+
+type TwoFactorOptions struct {
+	Issuer       string
+	ChallengeTTL time.Duration
+}
+
+type TOTPEnrollment struct {
+	Secret string
+	URI    string
+}
+
+func (s *AuthService) BeginTOTPEnrollment(ctx context.Context, userID *string) (*TOTPEnrollment, error) {
+	const op = "AuthService.BeginTOTPEnrollment"
+
+	user, err := s.twoFactorUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactor != nil && user.TwoFactor.Enabled {
+		return nil, NewMessageError(ErrCodeAlreadyExists, MsgTwoFactorEnabled)
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, NewMessageError(ErrCodeInternal, MsgTOTPSecretFailed)
+	}
+	encrypted, err := s.cipher.Encrypt(secret)
+	if err != nil {
+		return nil, NewMessageError(ErrCodeInternal, MsgTOTPEncryptFailed)
+	}
+
+	user.TwoFactor = &domain.TwoFactor{PendingSecret: encrypted}
+	if err := s.userRepo.Replace(ctx, user); err != nil {
+		return nil, mapRepositoryError(err, "totp enrollment")
+	}
+
+	account := *user.ID
+	if user.Login != nil {
+		account = *user.Login
+	}
+
+	s.logger.InfoContext(ctx, "totp enrollment started", "operation", op, "user_id", *user.ID)
+	return &TOTPEnrollment{
+		Secret: secret,
+		URI:    totp.URI(s.twoFactor.Issuer, account, secret),
+	}, nil
+}
+
+func (s *AuthService) ConfirmTOTPEnrollment(ctx context.Context, userID *string, code string) ([]string, error) {
+	const op = "AuthService.ConfirmTOTPEnrollment"
+
+	user, err := s.twoFactorUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactor == nil || user.TwoFactor.PendingSecret == "" {
+		return nil, NewMessageError(ErrCodeInvalidInput, MsgTwoFactorNotStarted)
+	}
+
+	secret, err := s.cipher.Decrypt(user.TwoFactor.PendingSecret)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to decrypt pending secret", "operation", op, "error", err)
+		return nil, NewMessageError(ErrCodeInternal, MsgTOTPDecryptFailed)
+	}
+
+	step, ok := totp.Validate(secret, code, time.Now(), 0)
+	if !ok {
+		return nil, NewMessageError(ErrCodeInvalidInput, MsgTwoFactorCodeInvalid)
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, NewMessageError(ErrCodeInternal, MsgRecoveryCodesFailed)
+	}
+
+	now := time.Now().UTC()
+	user.TwoFactor = &domain.TwoFactor{
+		Enabled:       true,
+		Secret:        user.TwoFactor.PendingSecret,
+		RecoveryCodes: hashes,
+		LastUsedStep:  step,
+		EnabledAt:     &now,
+	}
+	if err := s.userRepo.Replace(ctx, user); err != nil {
+		return nil, mapRepositoryError(err, "totp confirm")
+	}
+
+	s.logger.InfoContext(ctx, "totp enabled", "operation", op, "user_id", *user.ID)
+	return codes, nil
+}
+
+func (s *AuthService) DisableTOTP(ctx context.Context, userID *string, code string) error {
+	const op = "AuthService.DisableTOTP"
+
+	user, err := s.twoFactorUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.TwoFactor == nil || !user.TwoFactor.Enabled {
+		return NewMessageError(ErrCodeInvalidInput, MsgTwoFactorDisabled)
+	}
+
+	ok, err := s.verifySecondFactor(ctx, user, code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return NewMessageError(ErrCodeInvalidInput, MsgTwoFactorCodeInvalid)
+	}
+
+	user.TwoFactor = nil
+	if err := s.userRepo.Replace(ctx, user); err != nil {
+		return mapRepositoryError(err, "totp disable")
+	}
+
+	s.logger.InfoContext(ctx, "totp disabled", "operation", op, "user_id", *user.ID)
+	return nil
+}
+
+func (s *AuthService) RegenerateRecoveryCodes(ctx context.Context, userID *string, code string) ([]string, error) {
+	const op = "AuthService.RegenerateRecoveryCodes"
+
+	user, err := s.twoFactorUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactor == nil || !user.TwoFactor.Enabled {
+		return nil, NewMessageError(ErrCodeInvalidInput, MsgTwoFactorDisabled)
+	}
+
+	ok, err := s.verifySecondFactor(ctx, user, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, NewMessageError(ErrCodeInvalidInput, MsgTwoFactorCodeInvalid)
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, NewMessageError(ErrCodeInternal, MsgRecoveryCodesFailed)
+	}
+	user.TwoFactor.RecoveryCodes = hashes
+
+	if err := s.userRepo.Replace(ctx, user); err != nil {
+		return nil, mapRepositoryError(err, "recovery codes")
+	}
+
+	s.logger.InfoContext(ctx, "recovery codes regenerated", "operation", op, "user_id", *user.ID)
+	return codes, nil
+}
+
+// CompleteLogin второй шаг входа: проверка TOTP-кода или кода восстановления по токену первого шага
+func (s *AuthService) CompleteLogin(ctx context.Context, mfaToken, code string, client ClientInfo) (*LoginResult, error) {
+	const op = "AuthService.CompleteLogin"
+	log := s.logger.With("operation", op, "client_ip", client.IP)
+
+	if mfaToken == "" || code == "" {
+		return nil, NewMessageError(ErrCodeInvalidInput, MsgTwoFactorInputRequired)
+	}
+	if s.cipher == nil {
+		return nil, twoFactorUnavailable()
+	}
+
+	tokenHash := hashToken(mfaToken)
+	challenge, err := s.challenges.Get(ctx, tokenHash)
+	if err != nil {
+		var serviceErr *ServiceError
+		if errors.As(err, &serviceErr) && serviceErr.Code == ErrCodeNotFound {
+			return nil, NewMessageError(ErrCodeUnauthorized, MsgTwoFactorChallengeInvalid)
+		}
+		return nil, mapRepositoryError(err, "login")
+	}
+
+	if err := s.checkThrottle(ctx, loginSubject(challenge.Login), ipSubject(client.IP)); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, &challenge.UserID)
+	if err != nil {
+		return nil, mapRepositoryError(err, "login")
+	}
+	user.ID = &challenge.UserID
+
+	// Статус мог измениться, пока пользователь вводил код
+	if err := loginStatusError(user); err != nil {
+		return nil, err
+	}
+
+	ok, err := s.verifySecondFactor(ctx, user, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		s.registerFailure(ctx, challenge.Login, client.IP)
+		log.InfoContext(ctx, "login failed: wrong second factor", "user_id", challenge.UserID)
+		return nil, NewMessageError(ErrCodeUnauthorized, MsgTwoFactorCodeInvalid)
+	}
+
+	if err := s.challenges.Delete(ctx, tokenHash); err != nil {
+		log.WarnContext(ctx, "failed to delete challenge", "error", err)
+	}
+	if err := s.attempts.ResetFailures(ctx, loginSubject(challenge.Login)); err != nil {
+		log.WarnContext(ctx, "failed to reset login failures", "error", err)
+	}
+
+	result, err := s.createSession(ctx, challenge.UserID, client)
+	if err != nil {
+		return nil, err
+	}
+
+	log.InfoContext(ctx, "user logged in with second factor", "user_id", challenge.UserID)
+	return result, nil
+}
+
+func (s *AuthService) startChallenge(ctx context.Context, user *domain.User, login string, client ClientInfo) (*LoginResult, error) {
+	token, err := generateToken(sessionTokenBytes)
+	if err != nil {
+		return nil, NewMessageError(ErrCodeInternal, MsgTwoFactorTokenFailed)
+	}
+
+	challenge := &domain.MFAChallenge{
+		UserID:    *user.ID,
+		Login:     login,
+		ClientIP:  client.IP,
+		ExpiresAt: time.Now().UTC().Add(s.twoFactor.ChallengeTTL),
+	}
+	if err := s.challenges.Save(ctx, hashToken(token), challenge); err != nil {
+		return nil, mapRepositoryError(err, "login")
+	}
+
+	return &LoginResult{MFARequired: true, MFAToken: token, MFAExpiresAt: challenge.ExpiresAt}, nil
+}
+
+// verifySecondFactor принимает TOTP-код или одноразовый код восстановления.
+// При успехе состояние второго фактора сохраняется, чтобы код нельзя было использовать повторно.
+func (s *AuthService) verifySecondFactor(ctx context.Context, user *domain.User, code string) (bool, error) {
+	tf := user.TwoFactor
+	if tf == nil || !tf.Enabled {
+		return false, nil
+	}
+
+	secret, err := s.cipher.Decrypt(tf.Secret)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to decrypt totp secret", "user_id", *user.ID, "error", err)
+		return false, NewMessageError(ErrCodeInternal, MsgTOTPDecryptFailed)
+	}
+
+	matched := false
+	if step, ok := totp.Validate(secret, code, time.Now(), tf.LastUsedStep); ok {
+		tf.LastUsedStep = step
+		matched = true
+	} else if idx := findRecoveryCode(tf.RecoveryCodes, code); idx >= 0 {
+		tf.RecoveryCodes = append(tf.RecoveryCodes[:idx], tf.RecoveryCodes[idx+1:]...)
+		s.logger.InfoContext(ctx, "recovery code used", "user_id", *user.ID, "remaining", len(tf.RecoveryCodes))
+		matched = true
+	}
+	if !matched {
+		return false, nil
+	}
+
+	if err := s.userRepo.Replace(ctx, user); err != nil {
+		return false, mapRepositoryError(err, "second factor")
+	}
+	return true, nil
+}
+
+func (s *AuthService) twoFactorUser(ctx context.Context, userID *string) (*domain.User, error) {
+	if s.cipher == nil {
+		return nil, twoFactorUnavailable()
+	}
+	if err := validationID(userID); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, mapRepositoryError(err, "two-factor")
+	}
+	user.ID = userID
+	return user, nil
+}
+
+func twoFactorUnavailable() *ServiceError {
+	return NewMessageError(ErrCodeInternal, MsgTwoFactorUnavailable)
+}
+
+func generateRecoveryCodes() ([]string, []string, error) {
+	enc := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	buf := make([]byte, recoveryCodeBytes)
+	for i := range codes {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		raw := enc.EncodeToString(buf)
+		codes[i] = raw[:4] + "-" + raw[4:]
+		hashes[i] = hashToken(raw)
+	}
+	return codes, hashes, nil
+}
+
+func findRecoveryCode(hashes []string, code string) int {
+	normalized := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	candidate := hashToken(normalized)
+	for i, h := range hashes {
+		if subtle.ConstantTimeCompare([]byte(h), []byte(candidate)) == 1 {
+			return i
+		}
+	}
+	return -1
+}
@@ -2,8 +2,11 @@ package service
 
 import (
 	"context"
+	"github.com/google/uuid"
 	"github.com/satrunjis/user-service/internal/domain"
+	"github.com/satrunjis/user-service/internal/social"
 	"golang.org/x/crypto/bcrypt"
+	"net/mail"
 	"regexp"
 	"strings"
 	"time"
@@ -19,7 +22,15 @@ const (
 func (s *UserService) CreateUser(ctx context.Context, user *domain.User) error {
 	normalizeUserFields(user)
 
-	if err := prepareUserForCreation(user); err != nil {
+	if user.TwoFactor != nil {
+		return NewMessageError(ErrCodeInvalidInput, MsgTwoFactorEndpoint)
+	}
+
+	if err := initialStatus(user); err != nil {
+		return err
+	}
+
+	if err := s.prepareUserForCreation(ctx, user, false); err != nil {
 		return err
 	}
 
@@ -28,10 +39,24 @@ func (s *UserService) CreateUser(ctx context.Context, user *domain.User) error {
 		user.RegDate = &now
 	}
 
-	err := s.userRepo.Create(ctx, user)
+	// ID нужен заранее, чтобы закрепить за ним логин
+	if user.ID == nil {
+		id := uuid.New().String()
+		user.ID = &id
+	}
+	if user.Login != nil {
+		if err := s.reserveLogin(ctx, *user.ID, *user.Login); err != nil {
+			return err
+		}
+	}
+
+	events := s.userEvents(ctx, domain.EventUserCreated, *user.ID, nil, userDoc(user))
+	err := s.userRepo.Create(ctx, user, events...)
 	if err != nil {
+		s.rollbackLogin(ctx, *user.ID, user.Login)
 		return mapRepositoryError(err, "create")
 	}
+	s.relay.Wake()
 
 	return nil
 }
@@ -41,12 +66,13 @@ func (s *UserService) GetUserByID(ctx context.Context, id *string) (*domain.User
 		return nil, err
 	}
 
-	user, err := s.userRepo.GetByID(ctx, id)
+	user, err := s.getUser(ctx, id, "get")
 	if err != nil {
-		return nil, mapRepositoryError(err, "get")
+		return nil, err
 	}
 
-	user.Password = nil
+	hideSecrets(user)
+	s.fillProfileURLs(user)
 
 	return user, nil
 }
@@ -56,31 +82,116 @@ func (s *UserService) SearchUsers(ctx context.Context, filters *domain.UserFilte
 		*filters.Size = 50
 	}
 	
+	if filters != nil {
+		if filters.SocialType != nil {
+			network := social.NormalizeID(*filters.SocialType)
+			filters.SocialType = &network
+		}
+		if filters.SocialHandle != nil {
+			handle := social.NormalizeHandle(*filters.SocialHandle)
+			filters.SocialHandle = &handle
+		}
+		statuses, err := normalizeStatusFilter(filters.Status)
+		if err != nil {
+			return nil, err
+		}
+		filters.Status = statuses
+		if err := encryptedFilterProblems(filters, s.userRepo.EncryptedFields()); err != nil {
+			return nil, err
+		}
+		if len(filters.Attributes) > 0 {
+			schema, err := s.attributeSchema(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if errs := attributeFilterProblems(filters.Attributes, schema); len(errs) != 0 {
+				return nil, NewValidationError(errs...)
+			}
+		}
+	}
+
 	users, err := s.userRepo.Search(ctx, filters)
 	if err != nil {
 		return nil, mapRepositoryError(err, "search")
 	}
 
 	for i := range users {
-		users[i].Password = nil
+		hideSecrets(users[i])
+		s.fillProfileURLs(users[i])
 	}
 	return users, nil
 }
 
+// EncryptedFields поля, которые хранятся зашифрованными, с режимом поиска по ним
+func (s *UserService) EncryptedFields() []domain.EncryptedField {
+	return s.userRepo.EncryptedFields()
+}
+
+// encryptedFilterProblems отклоняет фильтры по зашифрованным полям, которые хранилище не может выполнить
+func encryptedFilterProblems(filters *domain.UserFilter, fields []domain.EncryptedField) error {
+	for _, f := range fields {
+		if f.Field == "location" && (filters.Lat != nil || filters.Lon != nil || filters.Distance != nil) {
+			return NewValidationError(fieldError("radius", ValidationUnsupported, "location is stored encrypted, search by distance is not available"))
+		}
+	}
+	return nil
+}
+
 func (s *UserService) Replace(ctx context.Context, user *domain.User) error {
 	if user.ID == nil {
-		return NewServiceError(ErrCodeInvalidInput, "User ID is required")
+		return NewMessageError(ErrCodeInvalidInput, MsgUserIDRequired)
 	}
 
 	normalizeUserFields(user)
 
-	if err := prepareUserForCreation(user); err != nil {
+	if user.Password != nil {
+		return NewMessageError(ErrCodeInvalidInput, MsgPasswordEndpoint)
+	}
+	if user.TwoFactor != nil {
+		return NewMessageError(ErrCodeInvalidInput, MsgTwoFactorEndpoint)
+	}
+
+	if err := s.prepareUserForCreation(ctx, user, false); err != nil {
+		return err
+	}
+
+	existing, err := s.getUser(ctx, user.ID, "replace")
+	if err != nil {
+		return err
+	}
+	if err := checkIfMatch(ctx, existing); err != nil {
 		return err
 	}
+	// Статус можно передать только без изменений, например при отправке ранее полученного документа
+	if user.Status != nil && *user.Status != existing.CurrentStatus() {
+		return NewMessageError(ErrCodeInvalidInput, MsgStatusEndpoint)
+	}
 
-	err := s.userRepo.Replace(ctx, user)
+	if user.Login != nil {
+		if err := s.reserveLogin(ctx, *user.ID, *user.Login); err != nil {
+			return err
+		}
+	}
+
+	// Учётные данные не приходят в запросе и переносятся из текущего документа
+	stored := *user
+	stored.Password = existing.Password
+	stored.TwoFactor = existing.TwoFactor
+	stored.Status = existing.Status
+	stored.StatusChange = existing.StatusChange
+	stored.DeletedAt = existing.DeletedAt
+	stored.Revision = existing.Revision
+
+	events := s.userEvents(ctx, domain.EventUserReplaced, *user.ID, userDoc(existing), userDoc(&stored))
+	err = s.userRepo.Replace(ctx, &stored, events...)
 	if err != nil {
-		return mapRepositoryError(err, "replace")
+		s.rollbackLogin(ctx, *user.ID, user.Login)
+		return writeError(ctx, err, "replace")
+	}
+	s.relay.Wake()
+
+	if !sameLogin(existing.Login, user.Login) {
+		s.releaseLogin(ctx, *user.ID, existing.Login)
 	}
 
 	return nil
@@ -88,31 +199,56 @@ func (s *UserService) Replace(ctx context.Context, user *domain.User) error {
 
 func (s *UserService) UpdatePartial(ctx context.Context, user *domain.User) error {
 	if user.RegDate != nil {
-		return NewServiceError(ErrCodeInvalidInput, "Cannot update protected fields (registration date)")
+		return NewMessageError(ErrCodeInvalidInput, MsgProtectedFields)
 	}
 
 	normalizeUserFields(user)
 
-	if err := prepareUserForCreation(user); err != nil {
+	if user.Password != nil {
+		return NewMessageError(ErrCodeInvalidInput, MsgPasswordEndpoint)
+	}
+	if user.TwoFactor != nil {
+		return NewMessageError(ErrCodeInvalidInput, MsgTwoFactorEndpoint)
+	}
+	if user.Status != nil {
+		return NewMessageError(ErrCodeInvalidInput, MsgStatusEndpoint)
+	}
+
+	if err := s.prepareUserForCreation(ctx, user, true); err != nil {
 		return err
 	}
 
-	err := s.userRepo.UpdatePartial(ctx, user)
+	// UpdatePartial обнуляет ID в переданной структуре, поэтому ID и логин сохранены заранее
+	id := *user.ID
+	login := user.Login
+	existing, err := s.getUser(ctx, &id, "update")
 	if err != nil {
-		return mapRepositoryError(err, "update")
+		return err
 	}
-
-	return nil
-}
-
-func (s *UserService) DeleteUser(ctx context.Context, id *string) error {
-	if err := validationID(id); err != nil {
+	if err := checkIfMatch(ctx, existing); err != nil {
 		return err
 	}
+	user.Revision = existing.Revision
+
+	if login != nil {
+		if err := s.reserveLogin(ctx, id, *login); err != nil {
+			return err
+		}
+	}
 
-	err := s.userRepo.Delete(ctx, id)
+	before := userDoc(existing)
+	events := s.userEvents(ctx, domain.EventUserPatched, id, before, patchedDoc(before, user))
+	err = s.userRepo.UpdatePartial(ctx, user, events...)
 	if err != nil {
-		return mapRepositoryError(err, "delete")
+		if login != nil {
+			s.rollbackLogin(ctx, id, login)
+		}
+		return writeError(ctx, err, "update")
+	}
+	s.relay.Wake()
+
+	if login != nil && !sameLogin(existing.Login, login) {
+		s.releaseLogin(ctx, id, existing.Login)
 	}
 
 	return nil
@@ -131,6 +267,14 @@ func normalizeUserFields(user *domain.User) {
 	if user.Password != nil && *user.Password == "" {
 		user.Password = nil
 	}
+	if user.Email != nil {
+		email := strings.ToLower(strings.TrimSpace(*user.Email))
+		if email == "" {
+			user.Email = nil
+		} else {
+			user.Email = &email
+		}
+	}
 	if user.Description != nil && *user.Description == "" {
 		user.Description = nil
 	}
@@ -140,18 +284,51 @@ func normalizeUserFields(user *domain.User) {
 	if user.SocialNet != nil && *user.SocialNet == "" {
 		user.SocialNet = nil
 	}
+	normalizeSocialProfiles(user)
+	// История смены статуса и отметка удаления только для чтения
+	user.StatusChange = nil
+	user.DeletedAt = nil
+}
+
+// hideSecrets убирает из ответа хеш пароля и секреты второго фактора
+func hideSecrets(user *domain.User) {
+	user.Password = nil
+	if user.TwoFactor != nil {
+		user.TwoFactor = &domain.TwoFactor{
+			Enabled:   user.TwoFactor.Enabled,
+			EnabledAt: user.TwoFactor.EnabledAt,
+		}
+	}
 }
 
 func hashPassword(pwd string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(pwd), cost)
 	if err != nil {
-		return "", NewServiceError(ErrCodeInternal, "Failed to hash password")
+		return "", NewMessageError(ErrCodeInternal, MsgPasswordHashFailed)
 	}
 	return string(bytes), nil
 }
 
-func prepareUserForCreation(user *domain.User) error {
-	if err := validateUser(user); err != nil {
+// prepareUserForCreation при partial проверяет только переданные поля
+func (s *UserService) prepareUserForCreation(ctx context.Context, user *domain.User, partial bool) error {
+	var rules *loginRules
+	if user.Login != nil || user.Username != nil {
+		var err error
+		if rules, err = s.loginRules(ctx); err != nil {
+			return err
+		}
+	}
+
+	// Без атрибутов схема нужна только для проверки обязательных
+	var schema *domain.AttributeSchema
+	if user.Attributes != nil || !partial {
+		var err error
+		if schema, err = s.attributeSchema(ctx); err != nil {
+			return err
+		}
+	}
+
+	if err := validateUser(user, rules, s.socials, schema, partial); err != nil {
 		return err
 	}
 
@@ -165,99 +342,95 @@ func prepareUserForCreation(user *domain.User) error {
 	return nil
 }
 
+func passwordProblems(pwd string) []FieldError {
+	var errs []FieldError
+	if len(pwd) < 8 || len(pwd) > 64 {
+		errs = append(errs, fieldError("password", ValidationLength, "password must be 8-64 characters", "min", 8, "max", 64))
+	}
+	if !regexp.MustCompile(validCharactersPattern).MatchString(pwd) {
+		errs = append(errs, fieldError("password", ValidationCharacters, "password "+msgInvalidCharacters))
+	}
+	return errs
+}
+
 func validationID(id *string) error {
 	if id == nil || *id == "" {
-		return NewServiceError(ErrCodeInvalidInput, "ID is required")
+		return NewValidationError(fieldError("id", ValidationRequired, "ID is required"))
 	}
 
 	if len(*id) > 36 {
-		return NewServiceError(ErrCodeInvalidInput, "ID must be less than 36 characters")
+		return NewValidationError(fieldError("id", ValidationMaxLength, "ID must be less than 36 characters", "max", 36))
 	}
 
 	if !regexp.MustCompile(validCharactersPattern).MatchString(*id) {
-		return NewServiceError(ErrCodeInvalidInput, "ID "+msgInvalidCharacters)
+		return NewValidationError(fieldError("id", ValidationCharacters, "ID "+msgInvalidCharacters))
 	}
 
 	return nil
 }
 
-func validateUser(u *domain.User) error {
-	var errs []string
+func validateUser(u *domain.User, rules *loginRules, socials *social.Registry, schema *domain.AttributeSchema, partial bool) error {
+	var errs []FieldError
 
 	if u.ID != nil {
 		if len(*u.ID) > 36 {
-			errs = append(errs, "ID must be less than 36 characters")
+			errs = append(errs, fieldError("id", ValidationMaxLength, "ID must be less than 36 characters", "max", 36))
 		}
 		if !regexp.MustCompile(validCharactersPattern).MatchString(*u.ID) {
-			errs = append(errs, "ID "+msgInvalidCharacters)
+			errs = append(errs, fieldError("id", ValidationCharacters, "ID "+msgInvalidCharacters))
 		}
 	}
 
 	if u.Login != nil {
-		login := *u.Login
-		if len(login) < 5 || len(login) > 20 {
-			errs = append(errs, "login must be 5-20 characters")
-		}
-		if !regexp.MustCompile(validCharactersPattern).MatchString(login) {
-			errs = append(errs, "login "+msgInvalidCharacters)
-		}
+		errs = append(errs, loginProblems(*u.Login)...)
+		errs = append(errs, rules.problems("login", *u.Login)...)
 	}
 
 	if u.Username != nil {
 		username := *u.Username
 		if len(username) > 50 {
-			errs = append(errs, "username exceeds 50 character limit")
+			errs = append(errs, fieldError("username", ValidationMaxLength, "username exceeds 50 character limit", "max", 50))
 		}
+		errs = append(errs, rules.problems("username", username)...)
 	}
 
 	if u.Password != nil {
-		pwd := *u.Password
-		if len(pwd) < 8 || len(pwd) > 64 {
-			errs = append(errs, "password must be 8-64 characters")
-		}
-		if !regexp.MustCompile(validCharactersPattern).MatchString(pwd) {
-			errs = append(errs, "password "+msgInvalidCharacters)
+		errs = append(errs, passwordProblems(*u.Password)...)
+	}
+
+	if u.Email != nil {
+		if addr, err := mail.ParseAddress(*u.Email); err != nil || addr.Address != *u.Email || len(*u.Email) > 254 {
+			errs = append(errs, fieldError("email", ValidationFormat, "email is not a valid address"))
 		}
 	}
 
 	if u.Description != nil && len(*u.Description) > 500 {
-		errs = append(errs, "description exceeds 500 character limit")
+		errs = append(errs, fieldError("description", ValidationMaxLength, "description exceeds 500 character limit", "max", 500))
 	}
 
 	if u.Comment != nil && len(*u.Comment) > 300 {
-		errs = append(errs, "comment exceeds 300 character limit")
+		errs = append(errs, fieldError("comment", ValidationMaxLength, "comment exceeds 300 character limit", "max", 300))
 	}
 
 	if u.RegDate != nil && u.RegDate.After(time.Now()) {
-		errs = append(errs, "registration date cannot be in the future")
+		errs = append(errs, fieldError("reg_date", ValidationInFuture, "registration date cannot be in the future"))
 	}
 
 	if u.Location != nil {
 		loc := u.Location
 		if loc.Lat < -90 || loc.Lat > 90 {
-			errs = append(errs, "latitude must be between -90 and 90")
+			errs = append(errs, fieldError("location.lat", ValidationRange, "latitude must be between -90 and 90", "min", -90, "max", 90))
 		}
 		if loc.Lon < -180 || loc.Lon > 180 {
-			errs = append(errs, "longitude must be between -180 and 180")
+			errs = append(errs, fieldError("location.lon", ValidationRange, "longitude must be between -180 and 180", "min", -180, "max", 180))
 		}
 	}
 
-	if u.SocialNet != nil {
-		validSocials := map[string]bool{
-			"facebook":  true,
-			"twitter":   true,
-			"instagram": true,
-			"max":       true,
-			"vk":        true,
-			"telegram":  true,
-		}
-		if !validSocials[strings.ToLower(*u.SocialNet)] {
-			errs = append(errs, "invalid social network specified")
-		}
-	}
+	errs = append(errs, socialProblems(u.SocialProfiles, socials)...)
+	errs = append(errs, attributeProblems(u.Attributes, schema, partial)...)
 
 	if len(errs) != 0 {
-		return NewServiceError(ErrCodeInvalidInput, strings.Join(errs, "; "))
+		return NewValidationError(errs...)
 	}
 	return nil
 }
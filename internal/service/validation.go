@@ -0,0 +1,58 @@
+package service
+
+import "strings"
+
+// Коды ошибок валидации отдельных полей
+const (
+	ValidationRequired    = "required"
+	ValidationLength      = "length"
+	ValidationMinLength   = "min_length"
+	ValidationMaxLength   = "max_length"
+	ValidationCharacters  = "invalid_characters"
+	ValidationFormat      = "invalid_format"
+	ValidationType        = "invalid_type"
+	ValidationRange       = "out_of_range"
+	ValidationMinValue    = "min_value"
+	ValidationMaxValue    = "max_value"
+	ValidationInFuture    = "in_future"
+	ValidationUnsupported = "unsupported_value"
+	ValidationReserved    = "reserved"
+	ValidationBlocked     = "blocked"
+	ValidationTaken       = "taken"
+	ValidationTooMany     = "too_many_items"
+	ValidationDuplicate   = "duplicate"
+	ValidationUnknown     = "unknown_field"
+)
+
+// FieldError описывает проблему с конкретным полем запроса
+type FieldError struct {
+	Field   string         `json:"field" example:"login"`
+	Code    string         `json:"code" example:"length"`
+	Message string         `json:"message" example:"login must be 5-20 characters"`
+	Params  map[string]any `json:"params,omitempty"`
+}
+
+func fieldError(field, code, message string, params ...any) FieldError {
+	fe := FieldError{Field: field, Code: code, Message: message}
+	if len(params) > 0 {
+		fe.Params = make(map[string]any, len(params)/2)
+		for i := 0; i+1 < len(params); i += 2 {
+			fe.Params[params[i].(string)] = params[i+1]
+		}
+	}
+	return fe
+}
+
+// NewValidationError собирает ошибки полей в одну ошибку INVALID_INPUT.
+// Message сохраняет прежний формат со всеми проблемами через "; ".
+func NewValidationError(details ...FieldError) *ServiceError {
+	messages := make([]string, len(details))
+	for i, d := range details {
+		messages[i] = d.Message
+	}
+	return &ServiceError{
+		Code:    ErrCodeInvalidInput,
+		Message: strings.Join(messages, "; "),
+		Details: details,
+	}
+}
@@ -0,0 +1,668 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/satrunjis/user-service/internal/domain"
+	"github.com/satrunjis/user-service/internal/netguard"
+)
+
+const (
+	webhookSecretPrefix = "whsec_"
+	webhookSecretBytes  = 32
+	// Список вебхуков для рассылки перечитывается не реже этого интервала
+	webhookRefreshInterval = 30 * time.Second
+	// Задача возвращается в очередь, если экземпляр не завершил её за время запроса и этот запас
+	webhookLeaseMargin = 30 * time.Second
+
+	defaultDeliveriesSize = 20
+	maxDeliveriesSize     = 100
+)
+
+// Заголовки запроса к получателю
+const (
+	HeaderWebhookID        = "X-Webhook-ID"
+	HeaderWebhookEvent     = "X-Webhook-Event"
+	HeaderWebhookDelivery  = "X-Webhook-Delivery"
+	HeaderWebhookTimestamp = "X-Webhook-Timestamp"
+	HeaderWebhookSignature = "X-Webhook-Signature"
+)
+
+type WebhookOptions struct {
+	// MaxAttempts после стольких неудачных попыток доставка переносится в очередь недоставленных
+	MaxAttempts int
+	// Задержка перед n-й повторной попыткой BackoffBase * 2^(n-1), но не больше BackoffMax
+	BackoffBase  time.Duration
+	BackoffMax   time.Duration
+	Timeout      time.Duration
+	PollInterval time.Duration
+	// Concurrency сколько запросов к получателям выполняется одновременно
+	Concurrency int
+	// AllowPrivateNetworks разрешает адреса получателей в локальной и частных сетях
+	AllowPrivateNetworks bool
+}
+
+// WebhookSender отправляет подписанный запрос получателю
+type WebhookSender interface {
+	Send(ctx context.Context, url string, header http.Header, body []byte) (int, error)
+}
+
+// WebhookUpdate изменяемые поля подписки, nil означает "не менять"
+type WebhookUpdate struct {
+	URL         *string
+	Events      *[]string
+	Description *string
+	Active      *bool
+}
+
+type WebhookService struct {
+	webhooks   domain.WebhookRepository
+	deliveries domain.WebhookDeliveryRepository
+	queue      domain.WebhookQueue
+	sender     WebhookSender
+	opts       WebhookOptions
+	cache      *webhookCache
+	logger     *slog.Logger
+}
+
+// webhookCache активные подписки для рассылки событий
+type webhookCache struct {
+	mu       sync.Mutex
+	webhooks []*domain.Webhook
+	loadedAt time.Time
+}
+
+func NewWebhookService(
+	webhooks domain.WebhookRepository,
+	deliveries domain.WebhookDeliveryRepository,
+	queue domain.WebhookQueue,
+	sender WebhookSender,
+	opts WebhookOptions,
+	logger *slog.Logger,
+) *WebhookService {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 1
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+	return &WebhookService{
+		webhooks:   webhooks,
+		deliveries: deliveries,
+		queue:      queue,
+		sender:     sender,
+		opts:       opts,
+		cache:      &webhookCache{},
+		logger:     logger,
+	}
+}
+
+func (s *WebhookService) CreateWebhook(ctx context.Context, rawURL string, events []string, secret, description *string) (*domain.Webhook, error) {
+	const op = "WebhookService.CreateWebhook"
+
+	target, err := webhookURL(rawURL, s.opts.AllowPrivateNetworks)
+	if err != nil {
+		return nil, err
+	}
+	events, err = webhookEvents(events)
+	if err != nil {
+		return nil, err
+	}
+	if err := webhookDescription(description); err != nil {
+		return nil, err
+	}
+
+	var value string
+	if secret != nil {
+		value = strings.TrimSpace(*secret)
+		if len(value) < 16 || len(value) > 256 {
+			return nil, NewValidationError(fieldError("secret", ValidationLength, "secret must be 16-256 characters", "min", 16, "max", 256))
+		}
+	} else {
+		token, err := generateToken(webhookSecretBytes)
+		if err != nil {
+			return nil, WrapMessageError(ErrCodeInternal, err, MsgWebhookSecretFailed)
+		}
+		value = webhookSecretPrefix + token
+	}
+
+	webhook := &domain.Webhook{
+		ID:          uuid.New().String(),
+		URL:         target,
+		Events:      events,
+		Secret:      value,
+		Description: description,
+		Active:      true,
+		CreatedAt:   time.Now().UTC(),
+		CreatedBy:   domain.PrincipalFrom(ctx).Actor(),
+	}
+	if err := s.webhooks.Create(ctx, webhook); err != nil {
+		return nil, webhookError(err, "create webhook")
+	}
+	s.invalidate()
+
+	s.logger.InfoContext(ctx, "webhook created", "operation", op, "webhook_id", webhook.ID, "events", webhook.Events, "actor", webhook.CreatedBy)
+	return webhook, nil
+}
+
+func (s *WebhookService) ListWebhooks(ctx context.Context) ([]*domain.Webhook, error) {
+	webhooks, err := s.webhooks.List(ctx)
+	if err != nil {
+		return nil, mapRepositoryError(err, "list webhooks")
+	}
+	for _, w := range webhooks {
+		w.Secret = ""
+	}
+	return webhooks, nil
+}
+
+func (s *WebhookService) GetWebhook(ctx context.Context, id string) (*domain.Webhook, error) {
+	webhook, err := s.getWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	webhook.Secret = ""
+	return webhook, nil
+}
+
+func (s *WebhookService) UpdateWebhook(ctx context.Context, id string, update WebhookUpdate) (*domain.Webhook, error) {
+	const op = "WebhookService.UpdateWebhook"
+
+	webhook, err := s.getWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.URL != nil {
+		if webhook.URL, err = webhookURL(*update.URL, s.opts.AllowPrivateNetworks); err != nil {
+			return nil, err
+		}
+	}
+	if update.Events != nil {
+		if webhook.Events, err = webhookEvents(*update.Events); err != nil {
+			return nil, err
+		}
+	}
+	if update.Description != nil {
+		if err := webhookDescription(update.Description); err != nil {
+			return nil, err
+		}
+		webhook.Description = update.Description
+	}
+	if update.Active != nil {
+		webhook.Active = *update.Active
+	}
+	now := time.Now().UTC()
+	webhook.UpdatedAt = &now
+
+	if err := s.webhooks.Update(ctx, webhook); err != nil {
+		return nil, webhookError(err, "update webhook")
+	}
+	s.invalidate()
+
+	s.logger.InfoContext(ctx, "webhook updated", "operation", op, "webhook_id", id, "active", webhook.Active, "actor", domain.PrincipalFrom(ctx).Actor())
+	webhook.Secret = ""
+	return webhook, nil
+}
+
+// DeleteWebhook удаляет подписку. Доставки в очереди при следующей попытке переносятся в недоставленные,
+// история доставок сохраняется.
+func (s *WebhookService) DeleteWebhook(ctx context.Context, id string) error {
+	const op = "WebhookService.DeleteWebhook"
+
+	if err := s.webhooks.Delete(ctx, id); err != nil {
+		return webhookError(err, "delete webhook")
+	}
+	s.invalidate()
+
+	s.logger.InfoContext(ctx, "webhook deleted", "operation", op, "webhook_id", id, "actor", domain.PrincipalFrom(ctx).Actor())
+	return nil
+}
+
+func (s *WebhookService) ListDeliveries(ctx context.Context, webhookID, status string, page, size int) (*domain.WebhookDeliveryPage, error) {
+	if _, err := s.getWebhook(ctx, webhookID); err != nil {
+		return nil, err
+	}
+	if status != "" && !slices.Contains(domain.DeliveryStatuses, status) {
+		return nil, NewValidationError(fieldError("status", ValidationUnsupported, "status must be one of: "+strings.Join(domain.DeliveryStatuses, ", "), "allowed", domain.DeliveryStatuses))
+	}
+	if page <= 0 {
+		page = 1
+	}
+	if size <= 0 || size > maxDeliveriesSize {
+		size = defaultDeliveriesSize
+	}
+	if page*size > maxHistoryWindow {
+		return nil, NewValidationError(fieldError("page", ValidationRange, "page is too deep, at most 10000 entries can be listed", "min", 1, "max", maxHistoryWindow/size))
+	}
+
+	deliveries, err := s.deliveries.List(ctx, webhookID, status, page, size)
+	if err != nil {
+		return nil, mapRepositoryError(err, "list webhook deliveries")
+	}
+	return deliveries, nil
+}
+
+// ReplayDelivery ставит в очередь новую доставку с тем же телом. Исходная доставка
+// убирается из очереди недоставленных, её история не меняется.
+func (s *WebhookService) ReplayDelivery(ctx context.Context, webhookID, deliveryID string) (*domain.WebhookDelivery, error) {
+	const op = "WebhookService.ReplayDelivery"
+	log := s.logger.With("operation", op, "webhook_id", webhookID, "delivery_id", deliveryID)
+
+	webhook, err := s.getWebhook(ctx, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if !webhook.Active {
+		return nil, NewMessageError(ErrCodeConflict, MsgWebhookInactive)
+	}
+
+	original, err := s.deliveries.GetByID(ctx, deliveryID)
+	if err != nil {
+		return nil, deliveryError(err, "webhook delivery")
+	}
+	if original.WebhookID != webhookID {
+		return nil, NewMessageError(ErrCodeNotFound, MsgDeliveryNotFound)
+	}
+	if original.Status == domain.DeliveryPending || original.Status == domain.DeliveryFailed {
+		return nil, NewMessageError(ErrCodeConflict, MsgDeliveryInProgress)
+	}
+
+	now := time.Now().UTC()
+	delivery := &domain.WebhookDelivery{
+		ID:            uuid.New().String(),
+		WebhookID:     webhookID,
+		EventID:       original.EventID,
+		EventType:     original.EventType,
+		UserID:        original.UserID,
+		Payload:       original.Payload,
+		Status:        domain.DeliveryPending,
+		NextAttemptAt: &now,
+		ReplayOf:      &original.ID,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := s.enqueue(ctx, delivery); err != nil {
+		return nil, err
+	}
+	if original.Status == domain.DeliveryDead {
+		if err := s.queue.Complete(ctx, original.ID); err != nil {
+			log.ErrorContext(ctx, "failed to remove delivery from dead letter queue", "error", err)
+		}
+	}
+
+	log.InfoContext(ctx, "webhook delivery replayed", "replay_id", delivery.ID, "actor", domain.PrincipalFrom(ctx).Actor())
+	return delivery, nil
+}
+
+// HandleEvent подписчик шины событий, создаёт доставки для подходящих вебхуков.
+// ID доставки выводится из ID события, поэтому повтор события не создаёт вторую доставку.
+func (s *WebhookService) HandleEvent(ctx context.Context, event domain.UserEvent) error {
+	const op = "WebhookService.HandleEvent"
+	log := s.logger.With("operation", op, "event_id", event.ID, "event_type", event.Type)
+
+	webhooks, err := s.activeWebhooks(ctx)
+	if err != nil {
+		log.ErrorContext(ctx, "failed to load webhooks, event is not delivered", "error", err)
+		return err
+	}
+
+	var errs []error
+
+	var payload []byte
+	for _, webhook := range webhooks {
+		if !webhook.Accepts(event.Type) {
+			continue
+		}
+		if payload == nil {
+			if payload, err = json.Marshal(event); err != nil {
+				log.ErrorContext(ctx, "event encoding failed", "error", err)
+				return WrapError(ErrCodeInternal, err)
+			}
+		}
+
+		now := time.Now().UTC()
+		delivery := &domain.WebhookDelivery{
+			ID:            deliveryID(webhook.ID, event.ID),
+			WebhookID:     webhook.ID,
+			EventID:       event.ID,
+			EventType:     event.Type,
+			UserID:        event.UserID,
+			Payload:       payload,
+			Status:        domain.DeliveryPending,
+			NextAttemptAt: &now,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+		err := s.enqueue(ctx, delivery)
+		if HasCode(err, ErrCodeAlreadyExists) {
+			log.DebugContext(ctx, "event already delivered to webhook", "webhook_id", webhook.ID)
+			err = s.requeue(ctx, delivery.ID)
+		}
+		if err != nil {
+			log.ErrorContext(ctx, "failed to enqueue webhook delivery", "webhook_id", webhook.ID, "error", err)
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// requeue ставит в очередь доставку, созданную при прошлой попытке обработать событие,
+// если тогда её не удалось поставить в очередь
+func (s *WebhookService) requeue(ctx context.Context, id string) error {
+	delivery, err := s.deliveries.GetByID(ctx, id)
+	if err != nil {
+		return deliveryError(err, "webhook delivery")
+	}
+	if delivery.Status != domain.DeliveryPending || delivery.Attempts > 0 || delivery.NextAttemptAt == nil {
+		return nil
+	}
+	if err := s.queue.Enqueue(ctx, delivery.ID, *delivery.NextAttemptAt); err != nil {
+		return StorageError(err)
+	}
+	return nil
+}
+
+// Run доставляет события из очереди, пока не отменён ctx
+func (s *WebhookService) Run(ctx context.Context) {
+	const op = "WebhookService.Run"
+	log := s.logger.With("operation", op)
+
+	if s.opts.PollInterval <= 0 {
+		log.Warn("webhook delivery is disabled")
+		return
+	}
+
+	ticker := time.NewTicker(s.opts.PollInterval)
+	defer ticker.Stop()
+
+	lease := s.opts.Timeout + webhookLeaseMargin
+	batch := s.opts.Concurrency * 4
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// Пока очередь отдаёт полные пачки, следующая берётся без ожидания
+		for ctx.Err() == nil {
+			ids, err := s.queue.Claim(ctx, time.Now(), lease, batch)
+			if err != nil {
+				log.ErrorContext(ctx, "failed to claim webhook deliveries", "error", err)
+				break
+			}
+			s.deliverAll(ctx, ids)
+			if len(ids) < batch {
+				break
+			}
+		}
+	}
+}
+
+func (s *WebhookService) deliverAll(ctx context.Context, ids []string) {
+	sem := make(chan struct{}, s.opts.Concurrency)
+	var wg sync.WaitGroup
+	for _, id := range ids {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			// Начатая попытка завершается и при остановке сервиса, иначе она повторится после lease
+			s.deliver(context.WithoutCancel(ctx), id)
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *WebhookService) deliver(ctx context.Context, deliveryID string) {
+	const op = "WebhookService.deliver"
+	log := s.logger.With("operation", op, "delivery_id", deliveryID)
+
+	delivery, err := s.deliveries.GetByID(ctx, deliveryID)
+	if HasCode(err, ErrCodeNotFound) {
+		log.WarnContext(ctx, "queued webhook delivery not found, dropping")
+		if err := s.queue.Complete(ctx, deliveryID); err != nil {
+			log.ErrorContext(ctx, "failed to complete webhook delivery", "error", err)
+		}
+		return
+	}
+	if err != nil {
+		// Задача останется в очереди и вернётся после lease
+		log.ErrorContext(ctx, "failed to load webhook delivery", "error", err)
+		return
+	}
+	log = log.With("webhook_id", delivery.WebhookID, "event_type", delivery.EventType)
+
+	webhook, err := s.webhooks.GetByID(ctx, delivery.WebhookID)
+	switch {
+	case HasCode(err, ErrCodeNotFound):
+		s.finish(ctx, log, delivery, domain.DeliveryDead, 0, "webhook was deleted")
+		return
+	case err != nil:
+		log.ErrorContext(ctx, "failed to load webhook", "error", err)
+		return
+	case !webhook.Active:
+		s.finish(ctx, log, delivery, domain.DeliveryDead, 0, "webhook is inactive")
+		return
+	}
+
+	now := time.Now().UTC()
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+
+	status, err := s.sender.Send(ctx, webhook.URL, signedHeader(webhook, delivery, now), delivery.Payload)
+	switch {
+	case err != nil:
+		s.retry(ctx, log, delivery, 0, err.Error())
+	case status >= 200 && status < 300:
+		s.finish(ctx, log, delivery, domain.DeliverySucceeded, status, "")
+	default:
+		s.retry(ctx, log, delivery, status, fmt.Sprintf("unexpected response status %d", status))
+	}
+}
+
+// retry назначает следующую попытку или переносит доставку в очередь недоставленных
+func (s *WebhookService) retry(ctx context.Context, log *slog.Logger, delivery *domain.WebhookDelivery, status int, reason string) {
+	if delivery.Attempts >= s.opts.MaxAttempts {
+		s.finish(ctx, log, delivery, domain.DeliveryDead, status, reason)
+		return
+	}
+
+	next := time.Now().UTC().Add(s.backoff(delivery.Attempts))
+	delivery.Status = domain.DeliveryFailed
+	delivery.ResponseStatus = status
+	delivery.Error = reason
+	delivery.NextAttemptAt = &next
+	delivery.UpdatedAt = time.Now().UTC()
+	if err := s.deliveries.Save(ctx, delivery); err != nil {
+		log.ErrorContext(ctx, "failed to save webhook delivery", "error", err)
+	}
+	if err := s.queue.Enqueue(ctx, delivery.ID, next); err != nil {
+		log.ErrorContext(ctx, "failed to reschedule webhook delivery", "error", err)
+	}
+	log.WarnContext(ctx, "webhook delivery failed, will retry", "attempt", delivery.Attempts, "reason", reason, "next_attempt_at", next)
+}
+
+func (s *WebhookService) finish(ctx context.Context, log *slog.Logger, delivery *domain.WebhookDelivery, status string, responseStatus int, reason string) {
+	now := time.Now().UTC()
+	delivery.Status = status
+	delivery.ResponseStatus = responseStatus
+	delivery.Error = reason
+	delivery.NextAttemptAt = nil
+	delivery.UpdatedAt = now
+	if err := s.deliveries.Save(ctx, delivery); err != nil {
+		log.ErrorContext(ctx, "failed to save webhook delivery", "error", err)
+	}
+
+	var err error
+	if status == domain.DeliveryDead {
+		err = s.queue.DeadLetter(ctx, delivery.ID, now)
+		log.WarnContext(ctx, "webhook delivery moved to dead letter queue", "attempts", delivery.Attempts, "reason", reason)
+	} else {
+		err = s.queue.Complete(ctx, delivery.ID)
+		log.InfoContext(ctx, "webhook delivered", "attempts", delivery.Attempts, "status", responseStatus)
+	}
+	if err != nil {
+		log.ErrorContext(ctx, "failed to update webhook queue", "error", err)
+	}
+}
+
+func (s *WebhookService) backoff(attempt int) time.Duration {
+	d := s.opts.BackoffBase
+	for i := 1; i < attempt && d < s.opts.BackoffMax; i++ {
+		d *= 2
+	}
+	return min(d, s.opts.BackoffMax)
+}
+
+// enqueue сохраняет доставку в историю до постановки в очередь, чтобы обработчик её нашёл.
+// Для уже существующей доставки возвращает ALREADY_EXISTS.
+func (s *WebhookService) enqueue(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	if err := s.deliveries.Create(ctx, delivery); err != nil {
+		if HasCode(err, ErrCodeAlreadyExists) {
+			return err
+		}
+		return deliveryError(err, "save webhook delivery")
+	}
+	if err := s.queue.Enqueue(ctx, delivery.ID, *delivery.NextAttemptAt); err != nil {
+		return StorageError(err)
+	}
+	return nil
+}
+
+// deliveryID одинаков для повторной доставки того же события, поэтому вебхук получает событие один раз
+func deliveryID(webhookID, eventID string) string {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(webhookID+":"+eventID)).String()
+}
+
+func (s *WebhookService) getWebhook(ctx context.Context, id string) (*domain.Webhook, error) {
+	webhook, err := s.webhooks.GetByID(ctx, id)
+	if err != nil {
+		return nil, webhookError(err, "webhook")
+	}
+	return webhook, nil
+}
+
+// webhookError и deliveryError сообщают об отсутствующем или существующем вебхуке и доставке,
+// а не о пользователе, как mapRepositoryError
+func webhookError(err error, operation string) error {
+	switch {
+	case HasCode(err, ErrCodeNotFound):
+		return WrapMessageError(ErrCodeNotFound, fmt.Errorf("%s: %w", operation, err), MsgWebhookNotFound)
+	case HasCode(err, ErrCodeAlreadyExists):
+		return WrapMessageError(ErrCodeAlreadyExists, fmt.Errorf("%s: %w", operation, err), MsgWebhookExists)
+	}
+	return mapRepositoryError(err, operation)
+}
+
+func deliveryError(err error, operation string) error {
+	switch {
+	case HasCode(err, ErrCodeNotFound):
+		return WrapMessageError(ErrCodeNotFound, fmt.Errorf("%s: %w", operation, err), MsgDeliveryNotFound)
+	case HasCode(err, ErrCodeAlreadyExists):
+		return WrapMessageError(ErrCodeAlreadyExists, fmt.Errorf("%s: %w", operation, err), MsgDeliveryExists)
+	}
+	return mapRepositoryError(err, operation)
+}
+
+func (s *WebhookService) activeWebhooks(ctx context.Context) ([]*domain.Webhook, error) {
+	s.cache.mu.Lock()
+	defer s.cache.mu.Unlock()
+
+	if s.cache.webhooks != nil && time.Since(s.cache.loadedAt) < webhookRefreshInterval {
+		return s.cache.webhooks, nil
+	}
+	webhooks, err := s.webhooks.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	active := make([]*domain.Webhook, 0, len(webhooks))
+	for _, w := range webhooks {
+		if w.Active {
+			active = append(active, w)
+		}
+	}
+	s.cache.webhooks = active
+	s.cache.loadedAt = time.Now()
+	return active, nil
+}
+
+func (s *WebhookService) invalidate() {
+	s.cache.mu.Lock()
+	s.cache.webhooks = nil
+	s.cache.mu.Unlock()
+}
+
+// signedHeader подписывает "<timestamp>.<тело>" ключом вебхука. Метка времени входит в подпись,
+// чтобы получатель мог отвергать старые перехваченные запросы.
+func signedHeader(webhook *domain.Webhook, delivery *domain.WebhookDelivery, at time.Time) http.Header {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(webhook.Secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(delivery.Payload)
+
+	header := http.Header{}
+	header.Set(HeaderWebhookID, webhook.ID)
+	header.Set(HeaderWebhookEvent, delivery.EventType)
+	header.Set(HeaderWebhookDelivery, delivery.ID)
+	header.Set(HeaderWebhookTimestamp, timestamp)
+	header.Set(HeaderWebhookSignature, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	return header
+}
+
+// webhookURL проверяет адрес получателя. Имя, которое разрешается во внутренний адрес, здесь не
+// отсекается, такие подключения запрещает отправитель.
+func webhookURL(raw string, allowPrivate bool) (string, error) {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(raw) > 2048 {
+		return "", NewValidationError(fieldError("url", ValidationFormat, "url must be an absolute http or https URL up to 2048 characters"))
+	}
+	if u.User != nil {
+		return "", NewValidationError(fieldError("url", ValidationFormat, "url must not contain credentials"))
+	}
+	if !allowPrivate && netguard.CheckHost(u.Hostname()) != nil {
+		return "", NewValidationError(fieldError("url", ValidationUnsupported, "url must point to a public address"))
+	}
+	return raw, nil
+}
+
+func webhookEvents(events []string) ([]string, error) {
+	for _, e := range events {
+		if !slices.Contains(domain.EventTypes, e) {
+			return nil, NewValidationError(fieldError("events", ValidationUnsupported, "unknown event type: "+e, "allowed", domain.EventTypes))
+		}
+	}
+	if events == nil {
+		return []string{}, nil
+	}
+	return slices.Compact(slices.Sorted(slices.Values(events))), nil
+}
+
+func webhookDescription(description *string) error {
+	if description != nil && len(*description) > 500 {
+		return NewValidationError(fieldError("description", ValidationMaxLength, "description exceeds 500 character limit", "max", 500))
+	}
+	return nil
+}
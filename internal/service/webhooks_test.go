@@ -0,0 +1,330 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/satrunjis/user-service/internal/domain"
+	"github.com/satrunjis/user-service/internal/external/webhook"
+)
+
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+type memoryWebhooks struct {
+	mu       sync.Mutex
+	webhooks map[string]*domain.Webhook
+}
+
+func (r *memoryWebhooks) Create(_ context.Context, w *domain.Webhook) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copied := *w
+	r.webhooks[w.ID] = &copied
+	return nil
+}
+
+func (r *memoryWebhooks) GetByID(_ context.Context, id string) (*domain.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	w, ok := r.webhooks[id]
+	if !ok {
+		return nil, NewServiceError(ErrCodeNotFound)
+	}
+	copied := *w
+	return &copied, nil
+}
+
+func (r *memoryWebhooks) List(_ context.Context) ([]*domain.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var list []*domain.Webhook
+	for _, w := range r.webhooks {
+		copied := *w
+		list = append(list, &copied)
+	}
+	return list, nil
+}
+
+func (r *memoryWebhooks) Update(ctx context.Context, w *domain.Webhook) error {
+	return r.Create(ctx, w)
+}
+
+func (r *memoryWebhooks) Delete(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.webhooks, id)
+	return nil
+}
+
+type memoryDeliveries struct {
+	mu         sync.Mutex
+	deliveries map[string]*domain.WebhookDelivery
+}
+
+func (r *memoryDeliveries) Create(ctx context.Context, d *domain.WebhookDelivery) error {
+	r.mu.Lock()
+	_, exists := r.deliveries[d.ID]
+	r.mu.Unlock()
+	if exists {
+		return NewServiceError(ErrCodeAlreadyExists)
+	}
+	return r.Save(ctx, d)
+}
+
+func (r *memoryDeliveries) Save(_ context.Context, d *domain.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copied := *d
+	r.deliveries[d.ID] = &copied
+	return nil
+}
+
+func (r *memoryDeliveries) GetByID(_ context.Context, id string) (*domain.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	d, ok := r.deliveries[id]
+	if !ok {
+		return nil, NewServiceError(ErrCodeNotFound)
+	}
+	copied := *d
+	return &copied, nil
+}
+
+func (r *memoryDeliveries) List(context.Context, string, string, int, int) (*domain.WebhookDeliveryPage, error) {
+	return &domain.WebhookDeliveryPage{}, nil
+}
+
+func (r *memoryDeliveries) ListByUser(context.Context, string, int) ([]*domain.WebhookDelivery, error) {
+	return nil, nil
+}
+
+func (r *memoryDeliveries) DeleteByUser(context.Context, string) (int64, error) {
+	return 0, nil
+}
+
+// memoryQueue запоминает расписание и итог каждой доставки
+type memoryQueue struct {
+	mu        sync.Mutex
+	scheduled map[string]time.Time
+	completed []string
+	dead      []string
+}
+
+func (q *memoryQueue) Enqueue(_ context.Context, id string, at time.Time) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.scheduled[id] = at
+	return nil
+}
+
+func (q *memoryQueue) Claim(context.Context, time.Time, time.Duration, int) ([]string, error) {
+	return nil, nil
+}
+
+func (q *memoryQueue) Complete(_ context.Context, id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.scheduled, id)
+	q.completed = append(q.completed, id)
+	return nil
+}
+
+func (q *memoryQueue) DeadLetter(_ context.Context, id string, _ time.Time) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.scheduled, id)
+	q.dead = append(q.dead, id)
+	return nil
+}
+
+type webhookFixture struct {
+	service    *WebhookService
+	webhooks   *memoryWebhooks
+	deliveries *memoryDeliveries
+	queue      *memoryQueue
+	webhook    *domain.Webhook
+}
+
+func newWebhookFixture(t *testing.T, target string, opts WebhookOptions) *webhookFixture {
+	t.Helper()
+	f := &webhookFixture{
+		webhooks:   &memoryWebhooks{webhooks: map[string]*domain.Webhook{}},
+		deliveries: &memoryDeliveries{deliveries: map[string]*domain.WebhookDelivery{}},
+		queue:      &memoryQueue{scheduled: map[string]time.Time{}},
+	}
+	opts.AllowPrivateNetworks = true
+	sender := webhook.NewHTTPSender(time.Second, true, discardLogger)
+	f.service = NewWebhookService(f.webhooks, f.deliveries, f.queue, sender, opts, discardLogger)
+
+	created, err := f.service.CreateWebhook(context.Background(), target, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("CreateWebhook() error = %v", err)
+	}
+	f.webhook, _ = f.webhooks.GetByID(context.Background(), created.ID)
+	return f
+}
+
+// publish создаёт доставку события и возвращает её ID
+func (f *webhookFixture) publish(t *testing.T) string {
+	t.Helper()
+	event := domain.UserEvent{ID: "event-1", Type: domain.EventUserCreated, UserID: "user-1", OccurredAt: time.Now().UTC()}
+	if err := f.service.HandleEvent(context.Background(), event); err != nil {
+		t.Fatalf("HandleEvent() error = %v", err)
+	}
+	return deliveryID(f.webhook.ID, event.ID)
+}
+
+func (f *webhookFixture) delivery(t *testing.T, id string) *domain.WebhookDelivery {
+	t.Helper()
+	d, err := f.deliveries.GetByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("delivery %s: %v", id, err)
+	}
+	return d
+}
+
+func TestWebhookDeliveryIsSigned(t *testing.T) {
+	var header http.Header
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	f := newWebhookFixture(t, server.URL, WebhookOptions{MaxAttempts: 3, BackoffBase: time.Second, BackoffMax: time.Minute})
+	id := f.publish(t)
+	f.service.deliver(context.Background(), id)
+
+	mac := hmac.New(sha256.New, []byte(f.webhook.Secret))
+	mac.Write([]byte(header.Get(HeaderWebhookTimestamp) + "."))
+	mac.Write(body)
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); header.Get(HeaderWebhookSignature) != want {
+		t.Errorf("signature = %s, want %s", header.Get(HeaderWebhookSignature), want)
+	}
+	if header.Get(HeaderWebhookID) != f.webhook.ID || header.Get(HeaderWebhookDelivery) != id || header.Get(HeaderWebhookEvent) != domain.EventUserCreated {
+		t.Errorf("unexpected webhook headers: %v", header)
+	}
+
+	d := f.delivery(t, id)
+	if d.Status != domain.DeliverySucceeded || d.ResponseStatus != http.StatusNoContent || d.Attempts != 1 {
+		t.Errorf("delivery = %s/%d after %d attempts, want succeeded/204 after 1", d.Status, d.ResponseStatus, d.Attempts)
+	}
+	if len(f.queue.completed) != 1 || f.queue.completed[0] != id {
+		t.Errorf("completed = %v, want [%s]", f.queue.completed, id)
+	}
+}
+
+func TestWebhookDeliveryRetriesAndDeadLetters(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	f := newWebhookFixture(t, server.URL, WebhookOptions{MaxAttempts: 3, BackoffBase: 10 * time.Second, BackoffMax: 15 * time.Second})
+	id := f.publish(t)
+
+	for attempt, backoff := range []time.Duration{10 * time.Second, 15 * time.Second} {
+		before := time.Now()
+		f.service.deliver(context.Background(), id)
+
+		d := f.delivery(t, id)
+		if d.Status != domain.DeliveryFailed || d.Attempts != attempt+1 || d.ResponseStatus != http.StatusServiceUnavailable {
+			t.Fatalf("attempt %d: delivery = %s/%d after %d attempts", attempt+1, d.Status, d.ResponseStatus, d.Attempts)
+		}
+		next, ok := f.queue.scheduled[id]
+		if !ok || next.Before(before.Add(backoff)) || next.After(time.Now().Add(backoff)) {
+			t.Errorf("attempt %d: next attempt at %v, want %v after the attempt", attempt+1, next, backoff)
+		}
+	}
+
+	f.service.deliver(context.Background(), id)
+	d := f.delivery(t, id)
+	if d.Status != domain.DeliveryDead || d.Attempts != 3 || d.NextAttemptAt != nil {
+		t.Errorf("delivery = %s after %d attempts, want dead after 3", d.Status, d.Attempts)
+	}
+	if len(f.queue.dead) != 1 || f.queue.dead[0] != id {
+		t.Errorf("dead letters = %v, want [%s]", f.queue.dead, id)
+	}
+	if requests != 3 {
+		t.Errorf("requests = %d, want 3", requests)
+	}
+}
+
+func TestWebhookReplayDeadDelivery(t *testing.T) {
+	healthy := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	f := newWebhookFixture(t, server.URL, WebhookOptions{MaxAttempts: 1, BackoffBase: time.Second, BackoffMax: time.Second})
+	id := f.publish(t)
+	f.service.deliver(context.Background(), id)
+	if d := f.delivery(t, id); d.Status != domain.DeliveryDead {
+		t.Fatalf("delivery status = %s, want dead", d.Status)
+	}
+
+	healthy = true
+	replay, err := f.service.ReplayDelivery(context.Background(), f.webhook.ID, id)
+	if err != nil {
+		t.Fatalf("ReplayDelivery() error = %v", err)
+	}
+	if replay.ID == id || replay.ReplayOf == nil || *replay.ReplayOf != id {
+		t.Errorf("replay = %s of %v, want a new delivery of %s", replay.ID, replay.ReplayOf, id)
+	}
+	if _, ok := f.queue.scheduled[replay.ID]; !ok {
+		t.Error("replay is not queued")
+	}
+	if len(f.queue.completed) != 1 || f.queue.completed[0] != id {
+		t.Errorf("completed = %v, original should leave the dead letter queue", f.queue.completed)
+	}
+
+	f.service.deliver(context.Background(), replay.ID)
+	if d := f.delivery(t, replay.ID); d.Status != domain.DeliverySucceeded || string(d.Payload) != string(f.delivery(t, id).Payload) {
+		t.Errorf("replay status = %s, want succeeded with the original payload", d.Status)
+	}
+
+	if _, err := f.service.ReplayDelivery(context.Background(), f.webhook.ID, "missing"); !HasCode(err, ErrCodeNotFound) {
+		t.Errorf("ReplayDelivery(missing) error = %v, want NOT_FOUND", err)
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	s := &WebhookService{opts: WebhookOptions{BackoffBase: 10 * time.Second, BackoffMax: time.Minute}}
+	for attempt, want := range map[int]time.Duration{1: 10 * time.Second, 2: 20 * time.Second, 3: 40 * time.Second, 4: time.Minute, 10: time.Minute} {
+		if got := s.backoff(attempt); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+}
+
+func TestWebhookURLRejectsPrivateHosts(t *testing.T) {
+	for _, raw := range []string{"http://localhost:8080/hook", "http://127.0.0.1/hook", "http://[::1]/hook", "http://169.254.169.254/latest", "http://10.0.0.5/hook"} {
+		if _, err := webhookURL(raw, false); !HasCode(err, ErrCodeInvalidInput) {
+			t.Errorf("webhookURL(%s) error = %v, want INVALID_INPUT", raw, err)
+		}
+		if _, err := webhookURL(raw, true); err != nil {
+			t.Errorf("webhookURL(%s) with private networks allowed: %v", raw, err)
+		}
+	}
+	if _, err := webhookURL("https://hooks.example.com/users", false); err != nil {
+		t.Errorf("webhookURL(public) error = %v", err)
+	}
+}
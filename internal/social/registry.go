@@ -0,0 +1,99 @@
+// Package social содержит реестр поддерживаемых социальных сетей.
+package social
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/satrunjis/user-service/internal/domain"
+)
+
+// DefaultNetworks используются, если реестр не задан в конфигурации
+var DefaultNetworks = []domain.SocialNetwork{
+	{ID: "facebook", Name: "Facebook", HandlePattern: `^[a-z0-9.]{5,50}$`, ProfileURL: "https://facebook.com/{handle}"},
+	{ID: "twitter", Name: "X (Twitter)", HandlePattern: `^[a-z0-9_]{1,15}$`, ProfileURL: "https://x.com/{handle}"},
+	{ID: "instagram", Name: "Instagram", HandlePattern: `^[a-z0-9._]{1,30}$`, ProfileURL: "https://instagram.com/{handle}"},
+	{ID: "max", Name: "MAX", HandlePattern: `^[a-z0-9_]{3,32}$`},
+	{ID: "vk", Name: "ВКонтакте", HandlePattern: `^[a-z0-9_.]{2,32}$`, ProfileURL: "https://vk.com/{handle}"},
+	{ID: "telegram", Name: "Telegram", HandlePattern: `^[a-z0-9_]{5,32}$`, ProfileURL: "https://t.me/{handle}"},
+}
+
+type network struct {
+	domain.SocialNetwork
+	handle *regexp.Regexp
+}
+
+type Registry struct {
+	networks []*network
+	byID     map[string]*network
+}
+
+func NewRegistry(networks []domain.SocialNetwork) (*Registry, error) {
+	r := &Registry{byID: make(map[string]*network, len(networks))}
+	for _, n := range networks {
+		id := NormalizeID(n.ID)
+		if id == "" {
+			return nil, fmt.Errorf("social network without id")
+		}
+		if _, ok := r.byID[id]; ok {
+			return nil, fmt.Errorf("duplicate social network %q", id)
+		}
+		pattern := n.HandlePattern
+		if pattern == "" {
+			pattern = `^.{1,100}$`
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("social network %q: invalid handle pattern: %w", id, err)
+		}
+
+		n.ID = id
+		n.HandlePattern = pattern
+		if n.Name == "" {
+			n.Name = id
+		}
+		entry := &network{SocialNetwork: n, handle: re}
+		r.networks = append(r.networks, entry)
+		r.byID[id] = entry
+	}
+	return r, nil
+}
+
+// Networks возвращает сети в порядке из конфигурации
+func (r *Registry) Networks() []domain.SocialNetwork {
+	out := make([]domain.SocialNetwork, len(r.networks))
+	for i, n := range r.networks {
+		out[i] = n.SocialNetwork
+	}
+	return out
+}
+
+func (r *Registry) Has(id string) bool {
+	_, ok := r.byID[NormalizeID(id)]
+	return ok
+}
+
+// ValidHandle проверяет ник по шаблону сети. Сеть должна быть в реестре.
+func (r *Registry) ValidHandle(id, handle string) bool {
+	n, ok := r.byID[NormalizeID(id)]
+	return ok && n.handle.MatchString(handle)
+}
+
+// ProfileURL строит ссылку на профиль, если у сети есть шаблон и указан ник
+func (r *Registry) ProfileURL(p domain.SocialProfile) string {
+	n, ok := r.byID[NormalizeID(p.Network)]
+	if !ok || n.ProfileURL == "" || p.Handle == "" {
+		return ""
+	}
+	return strings.ReplaceAll(n.ProfileURL, "{handle}", p.Handle)
+}
+
+func NormalizeID(id string) string {
+	return strings.ToLower(strings.TrimSpace(id))
+}
+
+// NormalizeHandle убирает @ в начале и приводит ник к нижнему регистру
+func NormalizeHandle(handle string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(handle), "@"))
+}
@@ -0,0 +1,195 @@
+package cdc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Handler обрабатывает изменение. Запись подтверждается, только если Handler вернул nil,
+// иначе она остаётся в группе и обрабатывается повторно через ClaimIdle.
+type Handler func(ctx context.Context, envelope Envelope) error
+
+type ConsumerOptions struct {
+	Stream string
+	Group  string
+	// Consumer имя потребителя в группе, у каждого экземпляра своё
+	Consumer string
+	// Start откуда читает создаваемая группа: "0" - весь поток, "$" (по умолчанию) - только новые записи
+	Start string
+	Batch int
+	// Block сколько ждать новых записей за одно чтение
+	Block time.Duration
+	// ClaimIdle через сколько записи, не подтверждённые другим потребителем, забираются себе
+	ClaimIdle time.Duration
+	Logger    *slog.Logger
+}
+
+// Consumer читает поток изменений группой потребителей: каждая запись обрабатывается одним
+// экземпляром, записи упавшего экземпляра забирают остальные. Доставка не реже одного раза,
+// повторы отбрасываются по Envelope.ID.
+type Consumer struct {
+	client *redis.Client
+	opts   ConsumerOptions
+}
+
+func NewConsumer(client *redis.Client, opts ConsumerOptions) (*Consumer, error) {
+	if opts.Stream == "" || opts.Group == "" || opts.Consumer == "" {
+		return nil, errors.New("cdc: stream, group and consumer are required")
+	}
+	if opts.Start == "" {
+		opts.Start = "$"
+	}
+	if opts.Batch <= 0 {
+		opts.Batch = 100
+	}
+	if opts.Block <= 0 {
+		opts.Block = 5 * time.Second
+	}
+	if opts.ClaimIdle <= 0 {
+		opts.ClaimIdle = time.Minute
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	return &Consumer{client: client, opts: opts}, nil
+}
+
+// Run обрабатывает записи, пока не отменён ctx. Ошибка возвращается, только если не удалось создать группу.
+func (c *Consumer) Run(ctx context.Context, handler Handler) error {
+	log := c.opts.Logger.With("stream", c.opts.Stream, "group", c.opts.Group, "consumer", c.opts.Consumer)
+
+	err := c.client.XGroupCreateMkStream(ctx, c.opts.Stream, c.opts.Group, c.opts.Start).Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("cdc: create group: %w", err)
+	}
+
+	// После перезапуска сначала дочитываются свои неподтверждённые записи
+	c.read(ctx, log, handler, "0")
+
+	var claimedAt time.Time
+	for ctx.Err() == nil {
+		if time.Since(claimedAt) >= c.opts.ClaimIdle {
+			claimedAt = time.Now()
+			c.claim(ctx, log, handler)
+		}
+		c.read(ctx, log, handler, ">")
+	}
+	return nil
+}
+
+// read читает новые записи (">") или историю своих неподтверждённых ("0")
+func (c *Consumer) read(ctx context.Context, log *slog.Logger, handler Handler, start string) {
+	for ctx.Err() == nil {
+		args := &redis.XReadGroupArgs{
+			Group:    c.opts.Group,
+			Consumer: c.opts.Consumer,
+			Streams:  []string{c.opts.Stream, start},
+			Count:    int64(c.opts.Batch),
+			Block:    c.opts.Block,
+		}
+		if start != ">" {
+			args.Block = -1
+		}
+		streams, err := c.client.XReadGroup(ctx, args).Result()
+		if errors.Is(err, redis.Nil) {
+			return
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Error("failed to read stream", "error", err)
+				sleep(ctx, time.Second)
+			}
+			return
+		}
+		if len(streams) == 0 || len(streams[0].Messages) == 0 {
+			return
+		}
+
+		messages := streams[0].Messages
+		c.process(ctx, log, handler, messages)
+		if start == ">" {
+			return
+		}
+		if len(messages) < c.opts.Batch {
+			return
+		}
+		// История своих записей читается дальше с последней просмотренной
+		start = messages[len(messages)-1].ID
+	}
+}
+
+// claim забирает записи, которые другие потребители не подтвердили за ClaimIdle
+func (c *Consumer) claim(ctx context.Context, log *slog.Logger, handler Handler) {
+	start := "0-0"
+	for ctx.Err() == nil {
+		messages, next, err := c.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   c.opts.Stream,
+			Group:    c.opts.Group,
+			Consumer: c.opts.Consumer,
+			MinIdle:  c.opts.ClaimIdle,
+			Start:    start,
+			Count:    int64(c.opts.Batch),
+		}).Result()
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Error("failed to claim pending records", "error", err)
+			}
+			return
+		}
+		if len(messages) > 0 {
+			log.Info("pending records claimed", "count", len(messages))
+			c.process(ctx, log, handler, messages)
+		}
+		if next == "0-0" || next == "" {
+			return
+		}
+		start = next
+	}
+}
+
+func (c *Consumer) process(ctx context.Context, log *slog.Logger, handler Handler, messages []redis.XMessage) {
+	for _, message := range messages {
+		// Удалённые из потока записи XAUTOCLAIM и XREADGROUP возвращают без полей
+		if len(message.Values) == 0 {
+			c.ack(ctx, log, message.ID)
+			continue
+		}
+
+		envelope, err := Decode(message.Values)
+		if errors.Is(err, ErrUnsupportedVersion) {
+			// Запись остаётся в группе до обновления потребителя
+			log.Warn("record skipped, envelope version is not supported", "record_id", message.ID, "error", err)
+			continue
+		}
+		if err != nil {
+			log.Error("malformed record acknowledged without processing", "record_id", message.ID, "error", err)
+			c.ack(ctx, log, message.ID)
+			continue
+		}
+
+		if err := handler(ctx, envelope); err != nil {
+			log.Error("record processing failed, will be retried", "record_id", message.ID, "event_id", envelope.ID, "error", err)
+			continue
+		}
+		c.ack(ctx, log, message.ID)
+	}
+}
+
+func (c *Consumer) ack(ctx context.Context, log *slog.Logger, id string) {
+	if err := c.client.XAck(ctx, c.opts.Stream, c.opts.Group, id).Err(); err != nil {
+		log.Error("failed to acknowledge record", "record_id", id, "error", err)
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
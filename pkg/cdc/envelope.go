@@ -0,0 +1,68 @@
+// Package cdc описывает формат изменений пользователей, которые user-service публикует в Redis Stream,
+// и помогает читать их группой потребителей.
+package cdc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Version текущая версия конверта. Несовместимые изменения формата увеличивают версию,
+// новые поля добавляются без её смены.
+const Version = 1
+
+// Поля записи в Redis Stream
+const (
+	FieldVersion  = "version"
+	FieldType     = "type"
+	FieldEnvelope = "envelope"
+)
+
+// TypeSnapshot текущее состояние пользователя, публикуется командой replay
+const TypeSnapshot = "user.snapshot"
+
+// Envelope одно изменение пользователя. ID общий для повторных публикаций одного события,
+// по нему потребитель отбрасывает дубликаты. Payload - событие user-service в JSON:
+// изменённые поля (changes) и состояние пользователя после изменения (user).
+type Envelope struct {
+	Version    int             `json:"version"`
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	UserID     string          `json:"user_id"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+// ErrUnsupportedVersion конверт записан более новой версией формата
+var ErrUnsupportedVersion = errors.New("cdc: unsupported envelope version")
+
+// Values поля записи для XADD
+func (e Envelope) Values() (map[string]any, error) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return nil, fmt.Errorf("cdc: encode envelope %s: %w", e.ID, err)
+	}
+	return map[string]any{
+		FieldVersion:  e.Version,
+		FieldType:     e.Type,
+		FieldEnvelope: string(data),
+	}, nil
+}
+
+// Decode разбирает запись потока. Конверт более новой версии, чем Version, не разбирается.
+func Decode(values map[string]any) (Envelope, error) {
+	var envelope Envelope
+	data, ok := values[FieldEnvelope].(string)
+	if !ok {
+		return envelope, fmt.Errorf("cdc: record has no %s field", FieldEnvelope)
+	}
+	if err := json.Unmarshal([]byte(data), &envelope); err != nil {
+		return envelope, fmt.Errorf("cdc: decode envelope: %w", err)
+	}
+	if envelope.Version > Version {
+		return envelope, fmt.Errorf("%w: %d, supported %d", ErrUnsupportedVersion, envelope.Version, Version)
+	}
+	return envelope, nil
+}